@@ -4,28 +4,60 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/services"
 	"github.com/markaya/meinappf/internal/validator"
 )
 
 type accountCreateForm struct {
 	AccountName string
-	Currency    int
+	Currency    string
+	Type        string
+	CreditLimit string
+	validator.Validator
+}
+
+// accountTypeForm changes the type and limit of an existing account.
+// accountTypeForm backs the forms of the account page.
+type accountTypeForm struct {
+	Name        string
+	Type        string
+	CreditLimit string
 	validator.Validator
 }
 
 type rebalanceAccountForm struct {
 	accountId  int
-	newBalance float64
+	newBalance models.Money
 	validator.Validator
 }
 
 func (app *application) accountCreate(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting account view")
+		app.serverError(w, err)
+		return
+	}
+
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
+	data.Currencies = currencies
 
-	data.Form = accountCreateForm{Currency: 0}
+	form := accountCreateForm{Type: string(models.Checking)}
+	if len(currencies) > 0 {
+		form.Currency = currencies[0].Code.String()
+	}
+	data.Form = form
 	app.render(w, http.StatusOK, "account_create.html", data)
 }
 
@@ -44,40 +76,52 @@ func (app *application) accountCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	currency, err := strconv.Atoi(r.PostForm.Get("currency"))
+	currencies, err := app.currencies.GetEnabled(userId)
 	if err != nil {
-		app.errorLog.Printf("could not parse form currency")
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
+	enabled := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		enabled = append(enabled, c.Code.String())
+	}
+
 	form := accountCreateForm{
 		AccountName: r.PostForm.Get("name"),
-		Currency:    currency,
+		Currency:    r.PostForm.Get("currency"),
+		Type:        r.PostForm.Get("type"),
+		CreditLimit: r.PostForm.Get("limit"),
+	}
+	if form.Type == "" {
+		form.Type = string(models.Checking)
 	}
 
 	form.CheckField(validator.NotBlank(form.AccountName), "name", "This field cannot be blank")
 	form.CheckField(validator.MaxChars(form.AccountName, 20), "name", "This field cannto be more than 20 chars long.")
-	form.CheckField(validator.PermittedInt(form.Currency, 0, 1), "currency", "This field must equal 0(RSD) or 1(EUR)")
+	form.CheckField(validator.PermittedValue(form.Currency, enabled...), "currency", "This field must be one of your enabled currencies")
+	creditLimit := checkAccountType(&form.Validator, form.Type, form.CreditLimit, models.Currency(form.Currency))
 
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
+		data.Currencies = currencies
 		app.render(w, http.StatusUnprocessableEntity, "account_create.html", data)
 		return
 	}
 
-	id, err := app.accounts.Insert(userId, form.AccountName, models.Currency(form.Currency))
+	id, err := app.accounts.Insert(userId, form.AccountName, models.Currency(form.Currency), models.AccountType(form.Type), creditLimit)
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateAccountName) {
 			form.AddFieldError("name", "Account name already in use.")
 			data := app.newTemplateData(r)
 			data.Form = form
+			data.Currencies = currencies
 			app.render(w, http.StatusUnprocessableEntity, "account_create.html", data)
 		} else {
 			app.serverError(w, err)
-			return
 		}
+		return
 	}
 
 	app.sessionManager.Put(r.Context(), "flash", "Account successfully created!")
@@ -99,15 +143,38 @@ func (app *application) accountsView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accounts, err := app.accounts.GetAll(userId)
+	all, err := app.accounts.GetAll(userId)
 	if err != nil {
 		app.errorLog.Println("error while getting all accounts for user")
 		app.serverError(w, err)
 		return
 	}
 
+	accounts := []*models.Account{}
+	archived := []*models.Account{}
+	for _, a := range all {
+		if a.Active() {
+			accounts = append(accounts, a)
+		} else {
+			archived = append(archived, a)
+		}
+	}
+
+	inconsistent, err := app.accounts.GetInconsistent(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	for _, a := range inconsistent {
+		app.errorLog.Printf("account %d: stored balance %s, ledger %s", a.ID, a.StoredBalance, a.Balance)
+	}
+
 	data := app.newTemplateData(r)
 	data.Accounts = accounts
+	data.ArchivedAccounts = archived
+	// NOTE: Archived accounts still hold money, closed ones hold none.
+	data.NetWorth = services.GetNetWorth(ownAccounts(all))
+	data.InconsistentAccounts = inconsistent
 	data.User = user
 	app.render(w, http.StatusOK, "accounts.html", data)
 }
@@ -146,9 +213,243 @@ func (app *application) accountView(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.User = user
 	data.Account = account
+	data.Form = accountTypeForm{
+		Name:        account.AccountName,
+		Type:        string(account.Type),
+		CreditLimit: account.CreditLimit.Decimal(),
+	}
 	app.render(w, http.StatusOK, "account.html", data)
 }
 
+func (app *application) accountTypePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user changing account type")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	account, err := app.accounts.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := accountTypeForm{
+		Name:        account.AccountName,
+		Type:        r.PostForm.Get("type"),
+		CreditLimit: r.PostForm.Get("limit"),
+	}
+	creditLimit := checkAccountType(&form.Validator, form.Type, form.CreditLimit, account.Currency)
+
+	if form.Valid() {
+		err = app.accounts.SetType(userId, id, models.AccountType(form.Type), creditLimit)
+		switch {
+		case errors.Is(err, models.ErrCreditLimit):
+			form.AddFieldError("limit", fmt.Sprintf("The balance of %s is already below this limit.", account.Balance))
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Account = account
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "account.html", data)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Account type saved!")
+	http.Redirect(w, r, fmt.Sprintf("/account/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) accountRenamePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user renaming account")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	account, err := app.accounts.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := accountTypeForm{
+		Name:        strings.TrimSpace(r.PostForm.Get("name")),
+		Type:        string(account.Type),
+		CreditLimit: account.CreditLimit.Decimal(),
+	}
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 20), "name", "This field cannto be more than 20 chars long.")
+
+	if form.Valid() {
+		err = app.accounts.Rename(userId, id, form.Name)
+		switch {
+		case errors.Is(err, models.ErrDuplicateAccountName):
+			form.AddFieldError("name", "Account name already in use.")
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Account = account
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "account.html", data)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Account renamed!")
+	http.Redirect(w, r, fmt.Sprintf("/account/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) accountArchivePost(w http.ResponseWriter, r *http.Request) {
+	app.setAccountStatus(w, r, "archive")
+}
+
+func (app *application) accountUnarchivePost(w http.ResponseWriter, r *http.Request) {
+	app.setAccountStatus(w, r, "unarchive")
+}
+
+func (app *application) accountClosePost(w http.ResponseWriter, r *http.Request) {
+	app.setAccountStatus(w, r, "close")
+}
+
+func (app *application) accountReopenPost(w http.ResponseWriter, r *http.Request) {
+	app.setAccountStatus(w, r, "reopen")
+}
+
+func (app *application) setAccountStatus(w http.ResponseWriter, r *http.Request, action string) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user changing account status")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	var flash string
+	switch action {
+	case "archive":
+		err = app.accounts.SetArchived(userId, id, true)
+		flash = "Account archived, it is kept in history and reports."
+	case "unarchive":
+		err = app.accounts.SetArchived(userId, id, false)
+		flash = "Account is back in use."
+	case "close":
+		err = app.accounts.Close(userId, id)
+		flash = "Account closed."
+	case "reopen":
+		err = app.accounts.Reopen(userId, id)
+		flash = "Account reopened."
+	}
+
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrAccountNotEmpty):
+		flash = "Only accounts with a zero balance can be closed, move the money out or rebalance first."
+	case err != nil:
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, fmt.Sprintf("/account/view/%d", id), http.StatusSeeOther)
+}
+
+// formAccounts returns the accounts offered in forms: the ones in use and
+// the current ones of the record being edited, archived or not. Accounts the
+// user only views are left out.
+func (app *application) formAccounts(userId int, current ...int) ([]*models.Account, error) {
+	accounts, err := app.accounts.GetAll(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(accounts, func(a *models.Account) bool {
+		return !a.Role.Allows(models.RoleEditor) || !a.Active() && !slices.Contains(current, a.ID)
+	}), nil
+}
+
+// ownAccounts leaves out the accounts other household members shared with
+// the user, their money is not the user's net worth.
+func ownAccounts(accounts []*models.Account) []*models.Account {
+	return slices.DeleteFunc(slices.Clone(accounts), func(a *models.Account) bool {
+		return a.Role != models.RoleOwner
+	})
+}
+
+// checkAccountType validates the type and credit limit fields and returns
+// the parsed limit, a blank limit is zero.
+func checkAccountType(v *validator.Validator, accountType, rawLimit string, currency models.Currency) models.Money {
+	t := models.AccountType(accountType)
+	v.CheckField(t.Known(), "type", "This field must be one of the account types.")
+
+	creditLimit := models.NewMoney(0, currency)
+	if rawLimit != "" {
+		limit, err := models.ParseMoney(rawLimit, currency)
+		v.CheckField(err == nil && !limit.IsNegative(), "limit", "This field must be zero or a positive amount.")
+		if err == nil {
+			creditLimit = limit
+		}
+	}
+	v.CheckField(t.HasLimit() || creditLimit.IsZero(), "limit", "Only checking, credit card and loan accounts can go below zero.")
+
+	return creditLimit
+}
+
 func (app *application) accountRebalanceView(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	accIdRaw := r.PathValue("id")
@@ -166,6 +467,12 @@ func (app *application) accountRebalanceView(w http.ResponseWriter, r *http.Requ
 		app.errorLog.Printf("could not find account for user %d, with id %d", userId, accountId)
 		return
 	}
+	// NOTE: Rebalancing corrects the books of the account, it is left to the
+	// owner and not to household members.
+	if account.Role != models.RoleOwner {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
 	data.Account = account
 	form := rebalanceAccountForm{}
 	data.Form = form
@@ -188,16 +495,20 @@ func (app *application) accountRebalancePost(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	newBalance, err := strconv.ParseFloat(r.PostForm.Get("new-balance"), 64)
+	acc, err := app.accounts.Get(userId, accId)
 	if err != nil {
-		app.errorLog.Printf("error parsing new-balance %s", r.PostForm.Get("new-balance"))
+		app.errorLog.Printf("could not find account with id %d, for user %d", accId, userId)
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
+	if acc.Role != models.RoleOwner {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
 
-	acc, err := app.accounts.Get(userId, accId)
+	newBalance, err := models.ParseMoney(r.PostForm.Get("new-balance"), acc.Currency)
 	if err != nil {
-		app.errorLog.Printf("could not find account with id %d, for user %d", accId, userId)
+		app.errorLog.Printf("error parsing new-balance %s", r.PostForm.Get("new-balance"))
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
@@ -207,10 +518,10 @@ func (app *application) accountRebalancePost(w http.ResponseWriter, r *http.Requ
 		newBalance: newBalance,
 	}
 
-	form.CheckField(validator.GreaterThanZero(form.newBalance), "balance", "This field must be greater than zero.")
+	form.CheckField(form.newBalance.Minor >= -acc.CreditLimit.Minor, "balance", "This field must not go past the credit limit of the account.")
 
-	balanceDiff := acc.Balance - newBalance
-	if balanceDiff == 0 {
+	balanceDiff := acc.Balance.Sub(newBalance)
+	if balanceDiff.IsZero() {
 		form.AddFieldError("balance", "New balance can not be same as current balance")
 	}
 
@@ -220,10 +531,20 @@ func (app *application) accountRebalancePost(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	transactionCreateForm := models.NewRebalance(*acc, balanceDiff)
-	_, err = app.transactions.Insert(transactionCreateForm, newBalance)
-	if err != nil {
-		app.errorLog.Printf("could not insert transaction create form %v", transactionCreateForm)
+	_, err = app.transactions.Rebalance(*acc, newBalance)
+	if errors.Is(err, models.ErrConcurrentUpdate) {
+		acc, err = app.accounts.Get(userId, accId)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		form.AddFieldError("balance", "Account was changed in the meantime, please check the balance and try again.")
+		data.Form = form
+		data.Account = acc
+		app.render(w, http.StatusConflict, "rebalance.html", data)
+		return
+	} else if err != nil {
+		app.errorLog.Printf("could not rebalance account %d to %s", acc.ID, newBalance)
 		app.serverError(w, err)
 		return
 	}
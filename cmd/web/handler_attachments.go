@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func (app *application) attachmentCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user attaching file")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	transaction, err := app.transactions.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	editable, err := app.canEdit(userId, transaction.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if !editable {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	form := newTransactionEditForm(userId, transaction)
+
+	// NOTE: The limit leaves room for the rest of the multipart body, the
+	// file itself is checked by the model.
+	r.Body = http.MaxBytesReader(w, r.Body, models.MaxAttachmentSize+1<<20)
+	data, filename, err := readUpload(r, "file")
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		form.AddFieldError("file", "The file must be at most 5 MB.")
+	case errors.Is(err, http.ErrMissingFile):
+		form.AddFieldError("file", "Pick a file to attach.")
+	case err != nil:
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if form.Valid() {
+		_, err = app.attachments.Insert(userId, transaction.ID, filename, data)
+		switch {
+		case errors.Is(err, models.ErrAttachmentSize):
+			form.AddFieldError("file", "The file must not be empty and at most 5 MB.")
+		case errors.Is(err, models.ErrAttachmentType):
+			form.AddFieldError("file", "Only JPEG, PNG and GIF images and PDF documents can be attached.")
+		case errors.Is(err, models.ErrNotEditable):
+			form.AddFieldError("file", "Only incomes and expenses can have attachments.")
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderTransactionEdit(w, r, http.StatusUnprocessableEntity, userId, transaction, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "File attached!")
+	http.Redirect(w, r, fmt.Sprintf("/transaction/edit/%d", transaction.ID), http.StatusSeeOther)
+}
+
+func (app *application) attachmentView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting attachment")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	attachment, err := app.attachments.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// NOTE: The content type was sniffed on upload, together with nosniff the
+	// browser never runs an attachment as a page.
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("Content-Length", strconv.Itoa(len(attachment.Data)))
+	w.Write(attachment.Data)
+}
+
+func (app *application) attachmentThumbnailView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting attachment thumbnail")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	thumbnail, err := app.attachments.GetThumbnail(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("Content-Length", strconv.Itoa(len(thumbnail)))
+	w.Write(thumbnail)
+}
+
+func (app *application) attachmentDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting attachment")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	attachment, err := app.attachments.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.attachments.Delete(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			// NOTE: The attachment is there but its account is view only.
+			app.clientError(w, http.StatusForbidden)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%s removed!", attachment.Filename))
+	http.Redirect(w, r, fmt.Sprintf("/transaction/edit/%d", attachment.TransactionID), http.StatusSeeOther)
+}
+
+// readUpload reads the file of a multipart form field, with its base name.
+func readUpload(r *http.Request, field string) ([]byte, string, error) {
+	err := r.ParseMultipartForm(models.MaxAttachmentSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, models.MaxAttachmentSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, header.Filename, nil
+}
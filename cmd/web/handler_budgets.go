@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/services"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+// budgetRolloverMonths is how far back a rollover chain is followed.
+const budgetRolloverMonths = 12
+
+type budgetForm struct {
+	CategoryID int
+	Currency   string
+	Amount     string
+	Rollover   bool
+	validator.Validator
+}
+
+func (app *application) budgetsView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting budgets view")
+		app.serverError(w, err)
+		return
+	}
+
+	month := models.MonthOf(time.Now())
+	if s := r.URL.Query().Get("month"); s != "" {
+		m, err := models.ParseMonth(s)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		month = m
+	}
+
+	app.renderBudgets(w, r, http.StatusOK, userId, month, budgetForm{})
+}
+
+func (app *application) budgetSetPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user setting budget")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	month, err := models.ParseMonth(r.PostForm.Get("month"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	categoryId, err := strconv.Atoi(r.PostForm.Get("category"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := budgetForm{
+		CategoryID: categoryId,
+		Currency:   r.PostForm.Get("currency"),
+		Amount:     r.PostForm.Get("amount"),
+		Rollover:   r.PostForm.Get("rollover") == "on",
+	}
+
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	currency := models.Currency(form.Currency)
+	enabled := slices.ContainsFunc(currencies, func(c *models.CurrencyInfo) bool { return c.Code == currency })
+	form.CheckField(enabled, "currency", "This field must be one of your currencies.")
+
+	amount, err := models.ParseMoney(form.Amount, currency)
+	form.CheckField(err == nil, "amount", "This field must be a valid amount.")
+	form.CheckField(!amount.IsNegative(), "amount", "This field cannot be negative.")
+
+	if form.Valid() {
+		_, err = app.budgets.Set(models.Budget{
+			UserID:     userId,
+			CategoryID: form.CategoryID,
+			Month:      month,
+			Amount:     amount,
+			Rollover:   form.Rollover,
+		})
+		switch {
+		case errors.Is(err, models.ErrBudgetCategory):
+			form.AddFieldError("category", "This field must be one of your expense categories.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderBudgets(w, r, http.StatusUnprocessableEntity, userId, month, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Budget saved!")
+	http.Redirect(w, r, budgetsUrl(month), http.StatusSeeOther)
+}
+
+func (app *application) budgetDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting budget")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	month, err := models.ParseMonth(r.PostForm.Get("month"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.budgets.Delete(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Budget removed.")
+	http.Redirect(w, r, budgetsUrl(month), http.StatusSeeOther)
+}
+
+func (app *application) budgetsCopyPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user copying budgets")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	month, err := models.ParseMonth(r.PostForm.Get("month"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	n, err := app.budgets.CopyMonth(userId, month.AddDate(0, -1, 0), month)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Copied %d budgets from the previous month.", n))
+	http.Redirect(w, r, budgetsUrl(month), http.StatusSeeOther)
+}
+
+func (app *application) renderBudgets(w http.ResponseWriter, r *http.Request, status, userId int, month time.Time, form budgetForm) {
+	progress, err := app.budgetProgress(userId, month)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	categories, err := app.categories.GetActive(userId, models.Expense)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Budgets = progress
+	data.Categories = categories
+	data.Currencies = currencies
+	data.Month = month
+	data.Form = form
+	app.render(w, status, "budgets.html", data)
+}
+
+// budgetProgress compares the budgets of month with the expenses booked on
+// their categories, sub-categories included.
+func (app *application) budgetProgress(userId int, month time.Time) ([]*services.BudgetProgress, error) {
+	month = models.MonthOf(month)
+	budgets, err := app.budgets.GetRange(userId, month.AddDate(0, -budgetRolloverMonths, 0), month)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	categories, err := app.categories.GetAll(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	spending := map[time.Time]services.Spending{}
+	for _, b := range budgets {
+		if _, ok := spending[b.Month]; ok {
+			continue
+		}
+		end := b.Month.AddDate(0, 1, 0).Add(-time.Nanosecond)
+		groupings, err := app.transactions.GetGroupingByDate(userId, b.Month, end)
+		if err != nil {
+			return nil, err
+		}
+		spending[b.Month] = services.SpendingOf(services.RollUpGroupings(categories, groupings))
+	}
+
+	return services.GetBudgetProgress(budgets, categories, month, spending), nil
+}
+
+func budgetsUrl(month time.Time) string {
+	return "/budgets/?month=" + month.Format("2006-01")
+}
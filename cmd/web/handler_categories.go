@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type categoryCreateForm struct {
+	Name            string
+	TransactionType int
+	ParentID        int
+	validator.Validator
+}
+
+func (app *application) categoriesView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting categories view")
+		app.serverError(w, err)
+		return
+	}
+
+	app.renderCategories(w, r, http.StatusOK, userId, categoryCreateForm{TransactionType: int(models.Expense)})
+}
+
+func (app *application) categoryCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating category")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	txType, err := strconv.Atoi(r.PostForm.Get("txtype"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	parentId, err := parseParentId(r.PostForm.Get("parent"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := categoryCreateForm{
+		Name:            strings.TrimSpace(r.PostForm.Get("name")),
+		TransactionType: txType,
+		ParentID:        parentId,
+	}
+
+	checkCategoryName(&form.Validator, form.Name)
+	form.CheckField(validator.PermittedInt(form.TransactionType, 0, 1), "txtype", "This field must equal 0(INCOME) or 1(EXPENSE)")
+
+	if form.Valid() {
+		_, err = app.categories.Insert(userId, models.TransactionType(form.TransactionType), form.Name, form.ParentID)
+		switch {
+		case errors.Is(err, models.ErrDuplicateCategory):
+			form.AddFieldError("name", "Category with this name already exists.")
+		case errors.Is(err, models.ErrCategoryParent), errors.Is(err, models.ErrNoRecord):
+			form.AddFieldError("parent", "Parent must be one of your categories of the same type.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderCategories(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Category %q created!", form.Name))
+	http.Redirect(w, r, "/categories/", http.StatusSeeOther)
+}
+
+func (app *application) categoryRenamePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user renaming category")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.PostForm.Get("name"))
+	v := validator.Validator{}
+	checkCategoryName(&v, name)
+	if !v.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "Category name can not be blank or longer than 25 chars.")
+		http.Redirect(w, r, "/categories/", http.StatusSeeOther)
+		return
+	}
+
+	err = app.categories.Rename(userId, id, name)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrDuplicateCategory):
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Category %q already exists, merge into it instead.", name))
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Category renamed to %q!", name))
+	}
+
+	http.Redirect(w, r, "/categories/", http.StatusSeeOther)
+}
+
+func (app *application) categoryMergePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user merging category")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	intoId, err := strconv.Atoi(r.PostForm.Get("into"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.categories.Merge(userId, id, intoId)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrCategoryMergeMismatch):
+		app.sessionManager.Put(r.Context(), "flash", "Only an income category can be merged into an income category, and an expense into an expense.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Categories merged!")
+	}
+
+	http.Redirect(w, r, "/categories/", http.StatusSeeOther)
+}
+
+func (app *application) categoryMovePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user moving category")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	parentId, err := parseParentId(r.PostForm.Get("parent"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.categories.SetParent(userId, id, parentId)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrCategoryParent):
+		app.sessionManager.Put(r.Context(), "flash", "A category can only be moved under a category of the same type that is not one of its own sub-categories.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Category moved!")
+	}
+
+	http.Redirect(w, r, "/categories/", http.StatusSeeOther)
+}
+
+func (app *application) categoryArchivePost(w http.ResponseWriter, r *http.Request) {
+	app.setCategoryArchived(w, r, true)
+}
+
+func (app *application) categoryUnarchivePost(w http.ResponseWriter, r *http.Request) {
+	app.setCategoryArchived(w, r, false)
+}
+
+func (app *application) setCategoryArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user archiving category")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.categories.SetArchived(userId, id, archived)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if archived {
+		app.sessionManager.Put(r.Context(), "flash", "Category archived.")
+	} else {
+		app.sessionManager.Put(r.Context(), "flash", "Category restored!")
+	}
+	http.Redirect(w, r, "/categories/", http.StatusSeeOther)
+}
+
+func (app *application) renderCategories(w http.ResponseWriter, r *http.Request, status, userId int, form categoryCreateForm) {
+	categories, err := app.categories.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Categories = categories
+	data.Form = form
+	app.render(w, status, "categories.html", data)
+}
+
+// withCategories sets the categories that can be picked for a transaction of
+// type tt. current are kept selectable when editing a transaction whose
+// category, or the category of one of its split lines, has since been archived.
+func (app *application) withCategories(data *templateData, userId int, tt models.TransactionType, current ...string) error {
+	categories, err := app.categories.GetAll(userId)
+	if err != nil {
+		return err
+	}
+
+	categories = slices.DeleteFunc(categories, func(c *models.Category) bool {
+		return c.TransactionType != tt || (c.Archived && !slices.Contains(current, c.Name))
+	})
+
+	for _, name := range current {
+		if name != "" && !slices.ContainsFunc(categories, func(c *models.Category) bool { return c.Name == name }) {
+			categories = append(categories, &models.Category{Name: name, Path: name, TransactionType: tt, Archived: true})
+		}
+	}
+
+	data.Categories = categories
+	return nil
+}
+
+// categoryPermitted reports whether name can be used for a transaction of type
+// tt, current being the category the transaction already has, if any.
+func (app *application) categoryPermitted(userId int, tt models.TransactionType, name, current string) (bool, error) {
+	if name == current {
+		return true, nil
+	}
+
+	categories, err := app.categories.GetActive(userId, tt)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(categories, func(c *models.Category) bool { return c.Name == name }), nil
+}
+
+// parseParentId reads a parent category id, blank and zero both mean top
+// level.
+func parseParentId(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func checkCategoryName(v *validator.Validator, name string) {
+	v.CheckField(validator.NotBlank(name), "name", "This field cannot be blank")
+	v.CheckField(validator.MaxChars(name, 25), "name", "This field cannot be more than 25 chars long.")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func (app *application) currenciesView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting currencies view")
+		app.serverError(w, err)
+		return
+	}
+
+	currencies, err := app.currencies.GetAll(userId)
+	if err != nil {
+		app.errorLog.Println("error while getting currencies for user")
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Currencies = currencies
+	app.render(w, http.StatusOK, "currencies.html", data)
+}
+
+func (app *application) currencyEnablePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user enabling currency")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	code, ok := models.GetCurrencyFromString(r.PostForm.Get("code"))
+	if !ok {
+		app.errorLog.Printf("unknown currency code %s", r.PostForm.Get("code"))
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.currencies.Enable(userId, code)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", code.String()+" enabled!")
+	http.Redirect(w, r, "/currencies/", http.StatusSeeOther)
+}
+
+func (app *application) currencyDisablePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user disabling currency")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	code, ok := models.GetCurrencyFromString(r.PostForm.Get("code"))
+	if !ok {
+		app.errorLog.Printf("unknown currency code %s", r.PostForm.Get("code"))
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.currencies.Disable(userId, code)
+	if err != nil {
+		if errors.Is(err, models.ErrCurrencyInUse) {
+			app.sessionManager.Put(r.Context(), "flash", code.String()+" is used by one of your accounts.")
+			http.Redirect(w, r, "/currencies/", http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", code.String()+" disabled!")
+	http.Redirect(w, r, "/currencies/", http.StatusSeeOther)
+}
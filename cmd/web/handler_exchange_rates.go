@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/services"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type exchangeRateCreateForm struct {
+	From string
+	To   string
+	Date time.Time
+	Rate string
+	validator.Validator
+}
+
+func (app *application) exchangeRatesView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting exchange rates view")
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	err := app.withExchangeRates(data, userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	form := exchangeRateCreateForm{Date: time.Now()}
+	if len(data.Currencies) > 1 {
+		form.From = data.Currencies[0].Code.String()
+		form.To = data.Currencies[1].Code.String()
+	}
+	data.Form = form
+	app.render(w, http.StatusOK, "exchange_rates.html", data)
+}
+
+func (app *application) exchangeRateCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating exchange rate")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", r.PostForm.Get("date"))
+	if err != nil {
+		app.errorLog.Println("error while parsing date")
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := exchangeRateCreateForm{
+		From: r.PostForm.Get("from"),
+		To:   r.PostForm.Get("to"),
+		Date: date,
+		Rate: r.PostForm.Get("rate"),
+	}
+
+	from, ok := models.GetCurrencyFromString(form.From)
+	form.CheckField(ok, "from", "This field must be a supported currency")
+	to, ok := models.GetCurrencyFromString(form.To)
+	form.CheckField(ok, "to", "This field must be a supported currency")
+	form.CheckField(from != to, "to", "Currencies must be different")
+
+	rate, err := strconv.ParseFloat(form.Rate, 64)
+	form.CheckField(err == nil && rate > 0, "rate", "This field must be a positive number.")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		err := app.withExchangeRates(data, userId)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "exchange_rates.html", data)
+		return
+	}
+
+	_, err = app.exchangeRates.Insert(userId, from, to, date, rate, models.RateSourceManual)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Exchange rate saved!")
+	http.Redirect(w, r, "/exchange-rates/", http.StatusSeeOther)
+}
+
+// NOTE: The full ECB history is around 7MB uncompressed.
+const maxRateImportSize = 32 << 20
+
+func (app *application) exchangeRateImportPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user importing exchange rates")
+		app.serverError(w, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRateImportSize)
+	err := r.ParseMultipartForm(maxRateImportSize)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := exchangeRateCreateForm{Date: time.Now()}
+
+	format := r.PostForm.Get("format")
+	if format == "" {
+		format = services.RateFormatAuto
+	}
+	form.CheckField(validator.PermittedValue(format, services.RateFormats...), "file", "Unsupported file format")
+
+	var rates []models.ExchangeRate
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		form.AddFieldError("file", "Choose a file to import")
+	} else {
+		defer file.Close()
+		if form.Valid() {
+			rates, err = services.ParseRates(format, file)
+			form.CheckField(err == nil, "file", fmt.Sprintf("Could not read file: %v", err))
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		err := app.withExchangeRates(data, userId)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "exchange_rates.html", data)
+		return
+	}
+
+	report, err := app.exchangeRates.Import(userId, rates)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Import done: "+report.String())
+	http.Redirect(w, r, "/exchange-rates/", http.StatusSeeOther)
+}
+
+func (app *application) withExchangeRates(data *templateData, userId int) error {
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		return err
+	}
+
+	rates, err := app.exchangeRates.GetRecent(userId, 50)
+	if err != nil {
+		return err
+	}
+
+	data.Currencies = currencies
+	data.ExchangeRates = rates
+	return nil
+}
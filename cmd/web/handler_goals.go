@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/services"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+// goalPaceMonths is how many recent months set the pace a goal is projected
+// with.
+const goalPaceMonths = 3
+
+type goalForm struct {
+	ID         int
+	Name       string
+	Target     string
+	Currency   string
+	Deadline   time.Time
+	Tag        string
+	AccountIDs []int
+	validator.Validator
+}
+
+// Funded reports whether the account is one of the goal's, for the template.
+func (form goalForm) Funded(accountId int) bool {
+	return slices.Contains(form.AccountIDs, accountId)
+}
+
+func (app *application) goalsView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting goals view")
+		app.serverError(w, err)
+		return
+	}
+
+	app.renderGoals(w, r, http.StatusOK, userId, goalForm{})
+}
+
+func (app *application) goalCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating goal")
+		app.serverError(w, err)
+		return
+	}
+
+	form, goal, ok := app.parseGoalForm(w, r, userId)
+	if !ok {
+		return
+	}
+
+	if form.Valid() {
+		_, err := app.goals.Insert(goal)
+		if !app.checkGoalError(w, &form, err) {
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderGoals(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Goal created!")
+	http.Redirect(w, r, "/goals/", http.StatusSeeOther)
+}
+
+func (app *application) goalEdit(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting goal edit")
+		app.serverError(w, err)
+		return
+	}
+
+	goal, ok := app.getGoal(w, r, userId)
+	if !ok {
+		return
+	}
+
+	form := goalForm{
+		ID:         goal.ID,
+		Name:       goal.Name,
+		Target:     goal.Target.Decimal(),
+		Currency:   goal.Target.Currency.String(),
+		Deadline:   goal.Deadline,
+		Tag:        goal.Tag,
+		AccountIDs: goal.AccountIDs,
+	}
+
+	app.renderGoalEdit(w, r, http.StatusOK, userId, form)
+}
+
+func (app *application) goalEditPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user editing goal")
+		app.serverError(w, err)
+		return
+	}
+
+	existing, ok := app.getGoal(w, r, userId)
+	if !ok {
+		return
+	}
+
+	form, goal, ok := app.parseGoalForm(w, r, userId)
+	if !ok {
+		return
+	}
+	form.ID = existing.ID
+	goal.ID = existing.ID
+
+	if form.Valid() {
+		err := app.goals.Update(goal)
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+			return
+		}
+		if !app.checkGoalError(w, &form, err) {
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderGoalEdit(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Goal updated!")
+	http.Redirect(w, r, "/goals/", http.StatusSeeOther)
+}
+
+func (app *application) goalDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting goal")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.goals.Delete(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Goal deleted, its accounts and transfers are kept.")
+	http.Redirect(w, r, "/goals/", http.StatusSeeOther)
+}
+
+func (app *application) getGoal(w http.ResponseWriter, r *http.Request, userId int) (*models.Goal, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	goal, err := app.goals.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return goal, true
+}
+
+// parseGoalForm reads and validates the goal form, ok is false when a
+// response was already written.
+func (app *application) parseGoalForm(w http.ResponseWriter, r *http.Request, userId int) (goalForm, models.Goal, bool) {
+	form := goalForm{}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return form, models.Goal{}, false
+	}
+
+	for _, s := range r.PostForm["accounts"] {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return form, models.Goal{}, false
+		}
+		form.AccountIDs = append(form.AccountIDs, id)
+	}
+
+	form.Name = strings.TrimSpace(r.PostForm.Get("name"))
+	form.Target = r.PostForm.Get("target")
+	form.Currency = r.PostForm.Get("currency")
+	// NOTE: Tags are stored lower cased, see models.ParseTags.
+	form.Tag = strings.ToLower(strings.TrimSpace(r.PostForm.Get("tag")))
+
+	if s := r.PostForm.Get("deadline"); s != "" {
+		deadline, err := time.Parse("2006-01-02", s)
+		form.Deadline = deadline
+		form.CheckField(err == nil, "deadline", "This field must be a valid date.")
+	}
+
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return form, models.Goal{}, false
+	}
+	currency := models.Currency(form.Currency)
+	enabled := slices.ContainsFunc(currencies, func(c *models.CurrencyInfo) bool { return c.Code == currency })
+	form.CheckField(enabled, "currency", "This field must be one of your currencies.")
+
+	target, err := models.ParseMoney(form.Target, currency)
+	form.CheckField(err == nil && target.Minor > 0, "target", "This field must be an amount greater than zero.")
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 50), "name", "This field cannot be more than 50 chars long.")
+	form.CheckField(validator.MaxChars(form.Tag, 25), "tag", "A tag cannot be more than 25 chars long.")
+	form.CheckField(!strings.Contains(form.Tag, ","), "tag", "This field must be a single tag.")
+	form.CheckField(form.Tag != "" || len(form.AccountIDs) > 0, "accounts", "Pick the accounts or the tag that fund the goal.")
+
+	goal := models.Goal{
+		UserID:     userId,
+		Name:       form.Name,
+		Target:     target,
+		Deadline:   form.Deadline,
+		Tag:        form.Tag,
+		AccountIDs: form.AccountIDs,
+	}
+
+	return form, goal, true
+}
+
+// checkGoalError turns the errors of saving a goal into field errors, false
+// means a response was already written.
+func (app *application) checkGoalError(w http.ResponseWriter, form *goalForm, err error) bool {
+	switch {
+	case errors.Is(err, models.ErrDuplicateGoal):
+		form.AddFieldError("name", "A goal with this name already exists.")
+	case errors.Is(err, models.ErrGoalCurrency):
+		form.AddFieldError("accounts", "The accounts must hold the currency of the goal.")
+	case errors.Is(err, models.ErrAccountDoesNotExist):
+		form.AddFieldError("accounts", "Account does not exist.")
+	case err != nil:
+		app.serverError(w, err)
+		return false
+	}
+	return true
+}
+
+// goalProgress returns the goals of the user and how they are coming along.
+func (app *application) goalProgress(userId int, now time.Time) ([]*services.GoalProgress, error) {
+	goals, err := app.goals.GetAll(userId, now.AddDate(0, -goalPaceMonths, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return services.GetGoalProgress(goals, now, goalPaceMonths), nil
+}
+
+func (app *application) renderGoals(w http.ResponseWriter, r *http.Request, status, userId int, form goalForm) {
+	progress, err := app.goalProgress(userId, time.Now())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data, err := app.goalFormData(r, userId, form)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Goals = progress
+	data.Form = form
+	app.render(w, status, "goals.html", data)
+}
+
+func (app *application) renderGoalEdit(w http.ResponseWriter, r *http.Request, status, userId int, form goalForm) {
+	data, err := app.goalFormData(r, userId, form)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Form = form
+	app.render(w, status, "goal_edit.html", data)
+}
+
+func (app *application) goalFormData(r *http.Request, userId int, form goalForm) (*templateData, error) {
+	accounts, err := app.formAccounts(userId, form.AccountIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := app.transactions.GetTags(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := app.newTemplateData(r)
+	data.Accounts = accounts
+	data.Currencies = currencies
+	data.Tags = tags
+	return data, nil
+}
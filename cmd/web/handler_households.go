@@ -0,0 +1,472 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type householdForm struct {
+	Name string
+	validator.Validator
+}
+
+type inviteForm struct {
+	Email string
+	Role  string
+	validator.Validator
+}
+
+type shareForm struct {
+	AccountID int
+	validator.Validator
+}
+
+// householdPage is the form data of the household page, which has both the
+// invite and the share form.
+type householdPage struct {
+	Invite inviteForm
+	Share  shareForm
+}
+
+func (app *application) householdsView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting households view")
+		app.serverError(w, err)
+		return
+	}
+
+	app.renderHouseholds(w, r, http.StatusOK, userId, householdForm{})
+}
+
+func (app *application) householdCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating household")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := householdForm{Name: strings.TrimSpace(r.PostForm.Get("name"))}
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank.")
+	form.CheckField(validator.MaxChars(form.Name, 50), "name", "This field cannot be more than 50 chars long.")
+
+	if !form.Valid() {
+		app.renderHouseholds(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	id, err := app.households.Insert(userId, form.Name)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Household created, invite the others!")
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) householdView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting household view")
+		app.serverError(w, err)
+		return
+	}
+
+	household, ok := app.getHousehold(w, r, userId)
+	if !ok {
+		return
+	}
+
+	page := householdPage{Invite: inviteForm{Role: string(models.RoleEditor)}}
+	app.renderHousehold(w, r, http.StatusOK, userId, household, page)
+}
+
+func (app *application) householdInvitePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user inviting household member")
+		app.serverError(w, err)
+		return
+	}
+
+	household, ok := app.getHousehold(w, r, userId)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := inviteForm{
+		Email: strings.TrimSpace(r.PostForm.Get("email")),
+		Role:  r.PostForm.Get("role"),
+	}
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be valid email adress")
+	form.CheckField(models.Role(form.Role).Known(), "role", "This field must be one of the roles.")
+
+	if form.Valid() {
+		err = app.households.Invite(userId, household.ID, form.Email, models.Role(form.Role))
+		switch {
+		case errors.Is(err, models.ErrHouseholdRole):
+			app.clientError(w, http.StatusForbidden)
+			return
+		case errors.Is(err, models.ErrUnknownMember):
+			form.AddFieldError("email", "Nobody signed up with this email.")
+		case errors.Is(err, models.ErrDuplicateMember):
+			form.AddFieldError("email", "This user is already a member or invited.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderHousehold(w, r, http.StatusUnprocessableEntity, userId, household, householdPage{Invite: form})
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Invitation sent, it shows up on their households page.")
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", household.ID), http.StatusSeeOther)
+}
+
+func (app *application) householdAcceptPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user accepting household invitation")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.households.Accept(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Welcome to the household!")
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", id), http.StatusSeeOther)
+}
+
+// householdLeavePost ends a membership, for a pending invitation it is the
+// decline.
+func (app *application) householdLeavePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user leaving household")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.households.Leave(userId, id)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrLastOwner):
+		app.sessionManager.Put(r.Context(), "flash", "Make another member an owner or delete the household first.")
+		http.Redirect(w, r, fmt.Sprintf("/household/view/%d", id), http.StatusSeeOther)
+		return
+	case err != nil:
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "You left the household, your accounts are no longer shared with it.")
+	http.Redirect(w, r, "/households/", http.StatusSeeOther)
+}
+
+func (app *application) householdRolePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user changing household role")
+		app.serverError(w, err)
+		return
+	}
+
+	id, memberId, ok := app.householdMemberPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	role := models.Role(r.PostForm.Get("role"))
+	if !role.Known() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	flash := "Role changed!"
+	err = app.households.SetRole(userId, id, memberId, role)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrHouseholdRole):
+		app.clientError(w, http.StatusForbidden)
+		return
+	case errors.Is(err, models.ErrLastOwner):
+		flash = "A household needs an owner, make another member an owner first."
+	case err != nil:
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) householdRemovePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user removing household member")
+		app.serverError(w, err)
+		return
+	}
+
+	id, memberId, ok := app.householdMemberPath(w, r)
+	if !ok {
+		return
+	}
+
+	flash := "Member removed, the accounts they shared are private again."
+	err := app.households.RemoveMember(userId, id, memberId)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrHouseholdRole):
+		app.clientError(w, http.StatusForbidden)
+		return
+	case errors.Is(err, models.ErrLastOwner):
+		flash = "A household needs an owner, make another member an owner first."
+	case err != nil:
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) householdSharePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user sharing account")
+		app.serverError(w, err)
+		return
+	}
+
+	household, ok := app.getHousehold(w, r, userId)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := shareForm{}
+	form.AccountID, err = strconv.Atoi(r.PostForm.Get("account"))
+	form.CheckField(err == nil && form.AccountID > 0, "account", "Pick one of your accounts.")
+
+	if form.Valid() {
+		err = app.households.ShareAccount(userId, household.ID, form.AccountID)
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			form.AddFieldError("account", "Pick one of your accounts.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		page := householdPage{Invite: inviteForm{Role: string(models.RoleEditor)}, Share: form}
+		app.renderHousehold(w, r, http.StatusUnprocessableEntity, userId, household, page)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Account shared with the household!")
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", household.ID), http.StatusSeeOther)
+}
+
+func (app *application) householdUnsharePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user unsharing account")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	accountId, err := strconv.Atoi(r.PathValue("account"))
+	if err != nil || accountId < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.households.UnshareAccount(userId, accountId)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Account is private again.")
+	http.Redirect(w, r, fmt.Sprintf("/household/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) householdDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting household")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.households.Delete(userId, id)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrHouseholdRole):
+		app.clientError(w, http.StatusForbidden)
+		return
+	case err != nil:
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Household deleted, every account is private again.")
+	http.Redirect(w, r, "/households/", http.StatusSeeOther)
+}
+
+func (app *application) getHousehold(w http.ResponseWriter, r *http.Request, userId int) (*models.Household, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	household, err := app.households.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return household, true
+}
+
+func (app *application) householdMemberPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return 0, 0, false
+	}
+
+	memberId, err := strconv.Atoi(r.PathValue("member"))
+	if err != nil || memberId < 1 {
+		app.notFound(w)
+		return 0, 0, false
+	}
+
+	return id, memberId, true
+}
+
+func (app *application) renderHouseholds(w http.ResponseWriter, r *http.Request, status, userId int, form householdForm) {
+	households, err := app.households.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Households = households
+	data.Form = form
+	app.render(w, status, "households.html", data)
+}
+
+// renderHousehold offers the active accounts of the user not shared with
+// the household yet in the share form.
+func (app *application) renderHousehold(w http.ResponseWriter, r *http.Request, status, userId int, household *models.Household, page householdPage) {
+	accounts, err := app.accounts.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	user, ok := r.Context().Value(authenticatedUser).(*models.User)
+	if !ok {
+		err := errors.New("could not find user in context for household view")
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Household = household
+	data.Accounts = slices.DeleteFunc(accounts, func(a *models.Account) bool {
+		return a.Role != models.RoleOwner || !a.Active() || a.HouseholdID == household.ID
+	})
+	data.Form = page
+	app.render(w, status, "household.html", data)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type loanTermsForm struct {
+	Principal        string
+	Rate             string
+	Months           string
+	FirstPayment     time.Time
+	InterestCategory string
+	validator.Validator
+}
+
+type installmentPayForm struct {
+	Number    int
+	AccountID int
+	Date      time.Time
+	validator.Validator
+}
+
+// loanSchedulePage is the form data of the schedule page, which has both the
+// terms and the payment form.
+type loanSchedulePage struct {
+	Terms loanTermsForm
+	Pay   installmentPayForm
+}
+
+func (app *application) loanScheduleView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting loan schedule")
+		app.serverError(w, err)
+		return
+	}
+
+	account, ok := app.getLoanAccount(w, r, userId)
+	if !ok {
+		return
+	}
+
+	terms, err := app.loanTerms.Get(userId, account.ID)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+
+	page := loanSchedulePage{
+		Terms: loanTermsForm{
+			FirstPayment:     time.Now().AddDate(0, 1, 0),
+			InterestCategory: "interest",
+		},
+		Pay: installmentPayForm{Date: time.Now()},
+	}
+	// NOTE: A loan account opened by rebalancing already holds the debt.
+	if account.Balance.IsNegative() {
+		page.Terms.Principal = account.Balance.Abs().Decimal()
+	}
+	if terms != nil {
+		page.Terms = newLoanTermsForm(terms)
+		if next, ok := terms.NextInstallment(); ok {
+			page.Pay.Number = next.Number
+			page.Pay.Date = next.Date
+		}
+	}
+
+	app.renderLoanSchedule(w, r, http.StatusOK, userId, account, terms, page)
+}
+
+func (app *application) loanSchedulePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user setting loan terms")
+		app.serverError(w, err)
+		return
+	}
+
+	account, ok := app.getLoanAccount(w, r, userId)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := loanTermsForm{
+		Principal:        r.PostForm.Get("principal"),
+		Rate:             r.PostForm.Get("rate"),
+		Months:           r.PostForm.Get("months"),
+		InterestCategory: r.PostForm.Get("category"),
+	}
+
+	principal, err := models.ParseMoney(form.Principal, account.Currency)
+	form.CheckField(err == nil && principal.Minor > 0, "principal", "This field must be an amount greater than zero.")
+	rate, err := strconv.ParseFloat(form.Rate, 64)
+	form.CheckField(err == nil && rate >= 0 && rate < 100, "rate", "This field must be a yearly rate in percent.")
+	months, err := strconv.Atoi(form.Months)
+	form.CheckField(err == nil && months > 0 && months <= 600, "months", "This field must be a number of months up to 600.")
+	form.FirstPayment, err = time.Parse("2006-01-02", r.PostForm.Get("first"))
+	form.CheckField(err == nil, "first", "This field must be a valid date.")
+
+	permitted, err := app.categoryPermitted(userId, models.Expense, form.InterestCategory, "")
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	form.CheckField(permitted, "category", "This field must be one of your expense categories.")
+
+	if form.Valid() {
+		err = app.loanTerms.Set(models.LoanTerms{
+			AccountID:        account.ID,
+			UserID:           userId,
+			Principal:        principal,
+			Rate:             rate,
+			Months:           months,
+			FirstPayment:     form.FirstPayment,
+			InterestCategory: form.InterestCategory,
+		})
+		switch {
+		case errors.Is(err, models.ErrInstallmentsPaid):
+			form.AddFieldError("principal", "The terms can not change once an installment is paid.")
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		terms, err := app.loanTerms.Get(userId, account.ID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		page := loanSchedulePage{Terms: form, Pay: installmentPayForm{Date: time.Now()}}
+		app.renderLoanSchedule(w, r, http.StatusUnprocessableEntity, userId, account, terms, page)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Loan terms saved!")
+	http.Redirect(w, r, fmt.Sprintf("/account/schedule/%d", account.ID), http.StatusSeeOther)
+}
+
+func (app *application) loanSchedulePayPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user paying loan installment")
+		app.serverError(w, err)
+		return
+	}
+
+	account, ok := app.getLoanAccount(w, r, userId)
+	if !ok {
+		return
+	}
+
+	terms, err := app.loanTerms.Get(userId, account.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := installmentPayForm{}
+	form.Number, err = strconv.Atoi(r.PostForm.Get("number"))
+	form.CheckField(err == nil && form.Number > 0 && form.Number <= terms.Months, "number", "Pick an installment of the schedule.")
+	form.AccountID, err = strconv.Atoi(r.PostForm.Get("account"))
+	form.CheckField(err == nil && form.AccountID != account.ID, "account", "Pick the account paying the installment.")
+	form.Date, err = time.Parse("2006-01-02", r.PostForm.Get("date"))
+	form.CheckField(err == nil, "date", "This field must be a valid date.")
+
+	var from *models.Account
+	if form.Valid() {
+		from, err = app.accounts.Get(userId, form.AccountID)
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			form.AddFieldError("account", "Account does not exist.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		case !from.Role.Allows(models.RoleEditor):
+			form.AddFieldError("account", "You can only view this shared account.")
+		}
+	}
+
+	if form.Valid() {
+		err = app.loanTerms.Pay(userId, account.ID, form.Number, *from, form.Date)
+		switch {
+		case errors.Is(err, models.ErrInstallmentsPaid):
+			form.AddFieldError("number", "This installment is already paid.")
+		case errors.Is(err, models.ErrLoanCurrency):
+			form.AddFieldError("account", "The account must hold the currency of the loan.")
+		case errors.Is(err, models.ErrInsufficientFunds):
+			form.AddFieldError("account", "Account does not have suficient funds.")
+		case errors.Is(err, models.ErrAccountClosed):
+			form.AddFieldError("account", "Account is closed.")
+		case errors.Is(err, models.ErrConcurrentUpdate):
+			form.AddFieldError("account", "Account is being changed by another request, please try again.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		page := loanSchedulePage{Terms: newLoanTermsForm(terms), Pay: form}
+		app.renderLoanSchedule(w, r, http.StatusUnprocessableEntity, userId, account, terms, page)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Installment %d paid!", form.Number))
+	http.Redirect(w, r, fmt.Sprintf("/account/schedule/%d", account.ID), http.StatusSeeOther)
+}
+
+func (app *application) loanScheduleDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting loan terms")
+		app.serverError(w, err)
+		return
+	}
+
+	account, ok := app.getLoanAccount(w, r, userId)
+	if !ok {
+		return
+	}
+
+	err := app.loanTerms.Delete(userId, account.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Loan terms removed, the payments made are kept.")
+	http.Redirect(w, r, fmt.Sprintf("/account/view/%d", account.ID), http.StatusSeeOther)
+}
+
+func newLoanTermsForm(terms *models.LoanTerms) loanTermsForm {
+	return loanTermsForm{
+		Principal:        terms.Principal.Decimal(),
+		Rate:             strconv.FormatFloat(terms.Rate, 'f', -1, 64),
+		Months:           strconv.Itoa(terms.Months),
+		FirstPayment:     terms.FirstPayment,
+		InterestCategory: terms.InterestCategory,
+	}
+}
+
+// getLoanAccount returns the loan account of the path, other accounts have no
+// schedule.
+func (app *application) getLoanAccount(w http.ResponseWriter, r *http.Request, userId int) (*models.Account, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	account, err := app.accounts.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	// NOTE: The schedule is kept by the owner of the account, not by the
+	// household members it is shared with.
+	if account.Type != models.Loan || account.Role != models.RoleOwner {
+		app.notFound(w)
+		return nil, false
+	}
+
+	return account, true
+}
+
+func (app *application) renderLoanSchedule(w http.ResponseWriter, r *http.Request, status, userId int, account *models.Account, terms *models.LoanTerms, page loanSchedulePage) {
+	accounts, err := app.formAccounts(userId, page.Pay.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	err = app.withCategories(data, userId, models.Expense, page.Terms.InterestCategory)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Account = account
+	data.LoanTerms = terms
+	data.Accounts = accounts
+	data.Form = page
+	app.render(w, status, "loan_schedule.html", data)
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/services"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type counterpartyForm struct {
+	Name string
+	Note string
+	validator.Validator
+}
+
+type loanForm struct {
+	CounterpartyID int
+	Direction      string
+	AccountID      int
+	Amount         string
+	Date           time.Time
+	DueDate        time.Time
+	Description    string
+	validator.Validator
+}
+
+type repayForm struct {
+	AccountID int
+	Amount    string
+	Date      time.Time
+	validator.Validator
+}
+
+func (app *application) loansView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting loans view")
+		app.serverError(w, err)
+		return
+	}
+
+	form := loanForm{Direction: string(models.Lent), Date: time.Now()}
+	app.renderLoans(w, r, http.StatusOK, userId, form)
+}
+
+func (app *application) loanCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating loan")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := loanForm{
+		Direction:   r.PostForm.Get("direction"),
+		Amount:      r.PostForm.Get("amount"),
+		Description: strings.TrimSpace(r.PostForm.Get("description")),
+	}
+
+	form.CounterpartyID, err = strconv.Atoi(r.PostForm.Get("counterparty"))
+	form.CheckField(err == nil && form.CounterpartyID > 0, "counterparty", "Pick the person of the loan.")
+	form.CheckField(models.LoanDirection(form.Direction).Known(), "direction", "This field must be lent or borrowed.")
+	form.CheckField(validator.MaxChars(form.Description, 100), "description", "This field cannot be more than 100 chars long.")
+
+	form.Date, err = time.Parse("2006-01-02", r.PostForm.Get("date"))
+	form.CheckField(err == nil, "date", "This field must be a valid date.")
+	if s := r.PostForm.Get("due"); s != "" {
+		form.DueDate, err = time.Parse("2006-01-02", s)
+		form.CheckField(err == nil, "due", "This field must be a valid date.")
+		form.CheckField(err != nil || !form.DueDate.Before(form.Date), "due", "The due date cannot be before the loan date.")
+	}
+
+	form.AccountID, err = strconv.Atoi(r.PostForm.Get("account"))
+	form.CheckField(err == nil && form.AccountID > 0, "account", "Pick the account of the money.")
+
+	account, amount, ok := app.loanAccount(w, userId, form.AccountID, form.Amount, &form.Validator)
+	if !ok {
+		return
+	}
+
+	if form.Valid() {
+		loan := models.PersonalLoan{
+			UserID:         userId,
+			CounterpartyID: form.CounterpartyID,
+			Direction:      models.LoanDirection(form.Direction),
+			Principal:      amount,
+			Date:           form.Date,
+			DueDate:        form.DueDate,
+			Description:    form.Description,
+		}
+
+		_, err = app.loans.Insert(loan, *account)
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			form.AddFieldError("counterparty", "Pick the person of the loan.")
+		case !app.checkLoanError(w, &form.Validator, err):
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderLoans(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Loan recorded!")
+	http.Redirect(w, r, fmt.Sprintf("/counterparty/view/%d", form.CounterpartyID), http.StatusSeeOther)
+}
+
+func (app *application) loanView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting loan view")
+		app.serverError(w, err)
+		return
+	}
+
+	loan, ok := app.getLoan(w, r, userId)
+	if !ok {
+		return
+	}
+
+	form := repayForm{AccountID: loan.AccountID, Amount: loan.Outstanding().Decimal(), Date: time.Now()}
+	app.renderLoan(w, r, http.StatusOK, userId, loan, form)
+}
+
+func (app *application) loanRepayPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user repaying loan")
+		app.serverError(w, err)
+		return
+	}
+
+	loan, ok := app.getLoan(w, r, userId)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := repayForm{Amount: r.PostForm.Get("amount")}
+	form.Date, err = time.Parse("2006-01-02", r.PostForm.Get("date"))
+	form.CheckField(err == nil, "date", "This field must be a valid date.")
+
+	form.AccountID, err = strconv.Atoi(r.PostForm.Get("account"))
+	form.CheckField(err == nil && form.AccountID > 0, "account", "Pick the account of the money.")
+
+	account, amount, ok := app.loanAccount(w, userId, form.AccountID, form.Amount, &form.Validator)
+	if !ok {
+		return
+	}
+
+	if form.Valid() {
+		_, err = app.loans.Repay(userId, loan.ID, *account, amount, form.Date)
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case errors.Is(err, models.ErrLoanOverpaid):
+			form.AddFieldError("amount", fmt.Sprintf("Only %s is outstanding.", loan.Outstanding()))
+		case !app.checkLoanError(w, &form.Validator, err):
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderLoan(w, r, http.StatusUnprocessableEntity, userId, loan, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Repayment recorded!")
+	http.Redirect(w, r, fmt.Sprintf("/loan/view/%d", loan.ID), http.StatusSeeOther)
+}
+
+func (app *application) loanPaymentDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting loan payment")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	paymentId, err := strconv.Atoi(r.PathValue("payment"))
+	if err != nil || paymentId < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.loans.DeletePayment(userId, id, paymentId)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrInsufficientFunds):
+		app.sessionManager.Put(r.Context(), "flash", "Repayment can not be removed, the account would go below zero.")
+	case errors.Is(err, models.ErrAccountClosed):
+		app.sessionManager.Put(r.Context(), "flash", "Repayment can not be removed, the account is closed.")
+	case errors.Is(err, models.ErrConcurrentUpdate):
+		app.sessionManager.Put(r.Context(), "flash", "Account is being changed by another request, please try again.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Repayment removed.")
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/loan/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) loanDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting loan")
+		app.serverError(w, err)
+		return
+	}
+
+	loan, ok := app.getLoan(w, r, userId)
+	if !ok {
+		return
+	}
+
+	err := app.loans.Delete(userId, loan.ID)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrInsufficientFunds):
+		app.sessionManager.Put(r.Context(), "flash", "Loan can not be deleted, an account would go below zero.")
+	case errors.Is(err, models.ErrAccountClosed):
+		app.sessionManager.Put(r.Context(), "flash", "Loan can not be deleted, one of its accounts is closed.")
+	case errors.Is(err, models.ErrConcurrentUpdate):
+		app.sessionManager.Put(r.Context(), "flash", "Account is being changed by another request, please try again.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Loan deleted, its transactions were taken back from the accounts.")
+		http.Redirect(w, r, fmt.Sprintf("/counterparty/view/%d", loan.CounterpartyID), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/loan/view/%d", loan.ID), http.StatusSeeOther)
+}
+
+func (app *application) counterpartiesView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting counterparties view")
+		app.serverError(w, err)
+		return
+	}
+
+	app.renderCounterparties(w, r, http.StatusOK, userId, counterpartyForm{})
+}
+
+func (app *application) counterpartyCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating counterparty")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := counterpartyForm{
+		Name: strings.TrimSpace(r.PostForm.Get("name")),
+		Note: strings.TrimSpace(r.PostForm.Get("note")),
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 50), "name", "This field cannot be more than 50 chars long.")
+	form.CheckField(validator.MaxChars(form.Note, 200), "note", "This field cannot be more than 200 chars long.")
+
+	if form.Valid() {
+		_, err = app.loans.InsertCounterparty(userId, form.Name, form.Note)
+		switch {
+		case errors.Is(err, models.ErrDuplicateCounterparty):
+			form.AddFieldError("name", "A person with this name already exists.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderCounterparties(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%s added!", form.Name))
+	http.Redirect(w, r, "/counterparties/", http.StatusSeeOther)
+}
+
+func (app *application) counterpartyView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting counterparty view")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	counterparty, err := app.loans.GetCounterparty(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	loans, err := app.loans.GetByCounterparty(userId, id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Today = time.Now()
+	data.Counterparty = counterparty
+	data.Loans = loans
+	data.LoanBalances = services.GetLoanBalances(loans, data.Today)
+	app.render(w, http.StatusOK, "counterparty.html", data)
+}
+
+func (app *application) counterpartyDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting counterparty")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.loans.DeleteCounterparty(userId, id)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrCounterpartyInUse):
+		app.sessionManager.Put(r.Context(), "flash", "Only people without loans can be removed, delete their loans first.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Person removed.")
+	}
+
+	http.Redirect(w, r, "/counterparties/", http.StatusSeeOther)
+}
+
+func (app *application) getLoan(w http.ResponseWriter, r *http.Request, userId int) (*models.PersonalLoan, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	loan, err := app.loans.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return loan, true
+}
+
+// loanAccount looks up the account of the loan forms and parses the amount in
+// its currency, ok is false when a response was already written.
+func (app *application) loanAccount(w http.ResponseWriter, userId, accountId int, rawAmount string, v *validator.Validator) (*models.Account, models.Money, bool) {
+	if accountId < 1 {
+		return nil, models.Money{}, true
+	}
+
+	account, err := app.accounts.Get(userId, accountId)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			v.AddFieldError("account", "Account does not exist.")
+			return nil, models.Money{}, true
+		}
+		app.serverError(w, err)
+		return nil, models.Money{}, false
+	}
+
+	amount, err := models.ParseMoney(rawAmount, account.Currency)
+	v.CheckField(err == nil && amount.Minor > 0, "amount", "This field must be an amount greater than zero.")
+	v.CheckField(account.Role.Allows(models.RoleEditor), "account", "You can only view this shared account.")
+
+	return account, amount, true
+}
+
+// checkLoanError turns the errors of moving loan money into field errors,
+// false means a response was already written.
+func (app *application) checkLoanError(w http.ResponseWriter, v *validator.Validator, err error) bool {
+	switch {
+	case errors.Is(err, models.ErrLoanCurrency):
+		v.AddFieldError("account", "The account must hold the currency of the loan.")
+	case errors.Is(err, models.ErrInsufficientFunds):
+		v.AddFieldError("amount", "Account does not have suficient funds.")
+	case errors.Is(err, models.ErrAccountClosed):
+		v.AddFieldError("account", "Account is closed.")
+	case errors.Is(err, models.ErrConcurrentUpdate):
+		v.AddFieldError("amount", "Account is being changed by another request, please try again.")
+	case err != nil:
+		app.serverError(w, err)
+		return false
+	}
+	return true
+}
+
+func (app *application) renderLoans(w http.ResponseWriter, r *http.Request, status, userId int, form loanForm) {
+	loans, err := app.loans.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	counterparties, err := app.loans.GetCounterparties(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	accounts, err := app.formAccounts(userId, form.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Today = time.Now()
+	data.Loans = loans
+	data.LoanBalances = services.GetLoanBalances(loans, data.Today)
+	data.Counterparties = counterparties
+	data.Accounts = accounts
+	data.Form = form
+	app.render(w, status, "loans.html", data)
+}
+
+func (app *application) renderLoan(w http.ResponseWriter, r *http.Request, status, userId int, loan *models.PersonalLoan, form repayForm) {
+	accounts, err := app.formAccounts(userId, form.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Today = time.Now()
+	data.Loan = loan
+	data.Accounts = accounts
+	data.Form = form
+	app.render(w, status, "loan.html", data)
+}
+
+func (app *application) renderCounterparties(w http.ResponseWriter, r *http.Request, status, userId int, form counterpartyForm) {
+	counterparties, err := app.loans.GetCounterparties(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Counterparties = counterparties
+	data.Form = form
+	app.render(w, status, "counterparties.html", data)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type payeeCreateForm struct {
+	Name            string
+	DefaultCategory string
+	validator.Validator
+}
+
+func (app *application) payeesView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting payees view")
+		app.serverError(w, err)
+		return
+	}
+
+	app.renderPayees(w, r, http.StatusOK, userId, payeeCreateForm{})
+}
+
+func (app *application) payeeCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating payee")
+		app.serverError(w, err)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := payeeCreateForm{
+		Name:            strings.TrimSpace(r.PostForm.Get("name")),
+		DefaultCategory: r.PostForm.Get("category"),
+	}
+
+	checkPayeeName(&form.Validator, "name", form.Name)
+
+	if form.Valid() && form.DefaultCategory != "" {
+		permitted, err := app.categoryPermitted(userId, models.Expense, form.DefaultCategory, "")
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(permitted, "category", "This field must be one of your expense categories.")
+	}
+
+	if form.Valid() {
+		_, err = app.payees.Insert(userId, form.Name, form.DefaultCategory)
+		switch {
+		case errors.Is(err, models.ErrDuplicatePayee), errors.Is(err, models.ErrDuplicateAlias):
+			form.AddFieldError("name", "Payee with this name already exists.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderPayees(w, r, http.StatusUnprocessableEntity, userId, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Payee %q created!", form.Name))
+	http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+}
+
+func (app *application) payeeCategoryPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user changing payee category")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	category := r.PostForm.Get("category")
+	if category != "" {
+		permitted, err := app.categoryPermitted(userId, models.Expense, category, "")
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		if !permitted {
+			app.sessionManager.Put(r.Context(), "flash", "Default category must be one of your expense categories.")
+			http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+			return
+		}
+	}
+
+	err = app.payees.SetDefaultCategory(userId, id, category)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Default category saved!")
+	http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+}
+
+func (app *application) payeeDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting payee")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.payees.Delete(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Payee deleted, its transactions are kept without a payee.")
+	http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+}
+
+func (app *application) payeeAliasPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user adding payee alias")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	pattern := r.PostForm.Get("pattern")
+	v := validator.Validator{}
+	checkPayeeName(&v, "pattern", pattern)
+	if !v.Valid() {
+		app.sessionManager.Put(r.Context(), "flash", "Alias can not be blank or longer than 50 chars.")
+		http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+		return
+	}
+
+	n, err := app.payees.AddAlias(userId, id, pattern)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrDuplicateAlias):
+		app.sessionManager.Put(r.Context(), "flash", "Payee already has this alias.")
+	case errors.Is(err, models.ErrInvalidAlias):
+		app.sessionManager.Put(r.Context(), "flash", "Alias can not be blank.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Alias added, %d existing transactions matched.", n))
+	}
+
+	http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+}
+
+func (app *application) payeeAliasDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user removing payee alias")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.payees.RemoveAlias(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Alias removed.")
+	http.Redirect(w, r, "/payees/", http.StatusSeeOther)
+}
+
+func (app *application) renderPayees(w http.ResponseWriter, r *http.Request, status, userId int, form payeeCreateForm) {
+	payees, err := app.payees.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	categories, err := app.categories.GetActive(userId, models.Expense)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Payees = payees
+	data.Categories = categories
+	data.Form = form
+	app.render(w, status, "payees.html", data)
+}
+
+// findPayee looks up the payee typed into a transaction form by name or alias.
+// When no payee was typed the description is matched against the aliases
+// instead, so "MAXI 123 BGD" ends up with the payee Maxi. An expense without a
+// category gets the default category of the payee.
+func (app *application) findPayee(userId int, form *models.TransactionCreateForm) error {
+	form.Payee = strings.TrimSpace(form.Payee)
+
+	var payee *models.Payee
+	var err error
+	if form.Payee != "" {
+		payee, err = app.payees.GetByName(userId, form.Payee)
+		if errors.Is(err, models.ErrNoRecord) {
+			payee, err = app.payees.Match(userId, form.Payee)
+		}
+	} else {
+		payee, err = app.payees.Match(userId, form.Description)
+	}
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	form.PayeeID = payee.ID
+	form.Payee = payee.Name
+	if form.Category == "" && models.TransactionType(form.TransactionType) == models.Expense && len(form.Splits) == 0 {
+		form.Category = payee.DefaultCategory
+	}
+
+	return nil
+}
+
+// createPayee adds the payee typed into a valid transaction form when it is not
+// one of the user's payees yet.
+func (app *application) createPayee(userId int, form *models.TransactionCreateForm) error {
+	if form.Payee == "" || form.PayeeID != 0 {
+		return nil
+	}
+
+	id, err := app.payees.Insert(userId, form.Payee, "")
+	if err != nil {
+		return err
+	}
+
+	form.PayeeID = id
+	return nil
+}
+
+func checkPayeeName(v *validator.Validator, key, name string) {
+	v.CheckField(validator.NotBlank(name), key, "This field cannot be blank")
+	v.CheckField(validator.MaxChars(name, 50), key, "This field cannot be more than 50 chars long.")
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type recurringForm struct {
+	ID              int
+	AccountID       int
+	TransactionType int
+	Amount          models.Money
+	Category        string
+	Description     string
+	Frequency       string
+	Interval        int
+	Day             int
+	StartDate       time.Time
+	EndDate         time.Time
+	validator.Validator
+}
+
+func (app *application) recurringView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting recurring view")
+		app.serverError(w, err)
+		return
+	}
+
+	rules, err := app.recurring.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.RecurringRules = rules
+	app.render(w, http.StatusOK, "recurring.html", data)
+}
+
+func (app *application) recurringCreate(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting recurring create")
+		app.serverError(w, err)
+		return
+	}
+
+	var tt models.TransactionType
+	switch r.PathValue("ttype") {
+	case "income":
+		tt = models.Income
+	case "expense":
+		tt = models.Expense
+	default:
+		app.notFound(w)
+		return
+	}
+
+	form := recurringForm{
+		TransactionType: int(tt),
+		Frequency:       string(models.Monthly),
+		Interval:        1,
+		Day:             time.Now().Day(),
+		StartDate:       time.Now(),
+	}
+
+	app.renderRecurringForm(w, r, http.StatusOK, userId, form, "")
+}
+
+func (app *application) recurringCreatePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user creating recurring rule")
+		app.serverError(w, err)
+		return
+	}
+
+	form, ok := app.parseRecurringForm(w, r, userId)
+	if !ok {
+		return
+	}
+
+	if form.Valid() {
+		permitted, err := app.categoryPermitted(userId, models.TransactionType(form.TransactionType), form.Category, "")
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(permitted, "category", "This field must be one of your categories.")
+	}
+
+	if form.Valid() {
+		_, err := app.recurring.Insert(form.rule(userId))
+		switch {
+		case errors.Is(err, models.ErrAccountDoesNotExist):
+			form.AddFieldError("account", "Account does not exist.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderRecurringForm(w, r, http.StatusUnprocessableEntity, userId, form, "")
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Recurring transaction created, due occurrences are posted within the hour.")
+	http.Redirect(w, r, "/recurring/", http.StatusSeeOther)
+}
+
+func (app *application) recurringEdit(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting recurring edit")
+		app.serverError(w, err)
+		return
+	}
+
+	rule, ok := app.getRecurringRule(w, r, userId)
+	if !ok {
+		return
+	}
+
+	form := recurringForm{
+		ID:              rule.ID,
+		AccountID:       rule.AccountID,
+		TransactionType: int(rule.TransactionType),
+		Amount:          rule.Amount,
+		Category:        rule.Category,
+		Description:     rule.Description,
+		Frequency:       string(rule.Frequency),
+		Interval:        rule.Interval,
+		Day:             rule.Day,
+		StartDate:       rule.StartDate,
+		EndDate:         rule.EndDate,
+	}
+
+	app.renderRecurringForm(w, r, http.StatusOK, userId, form, rule.Category)
+}
+
+func (app *application) recurringEditPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user editing recurring rule")
+		app.serverError(w, err)
+		return
+	}
+
+	rule, ok := app.getRecurringRule(w, r, userId)
+	if !ok {
+		return
+	}
+
+	form, ok := app.parseRecurringForm(w, r, userId)
+	if !ok {
+		return
+	}
+	form.ID = rule.ID
+	// NOTE: The type stays, the categories of the other type would not fit.
+	form.TransactionType = int(rule.TransactionType)
+
+	if form.Valid() {
+		permitted, err := app.categoryPermitted(userId, rule.TransactionType, form.Category, rule.Category)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(permitted, "category", "This field must be one of your categories.")
+	}
+
+	if form.Valid() {
+		err := app.recurring.Update(form.rule(userId))
+		switch {
+		case errors.Is(err, models.ErrAccountDoesNotExist):
+			form.AddFieldError("account", "Account does not exist.")
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderRecurringForm(w, r, http.StatusUnprocessableEntity, userId, form, rule.Category)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Recurring transaction updated!")
+	http.Redirect(w, r, "/recurring/", http.StatusSeeOther)
+}
+
+func (app *application) recurringDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting recurring rule")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.recurring.Delete(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Recurring transaction deleted, transactions it posted are kept.")
+	http.Redirect(w, r, "/recurring/", http.StatusSeeOther)
+}
+
+func (app *application) recurringPausePost(w http.ResponseWriter, r *http.Request) {
+	app.setRecurringPaused(w, r, true)
+}
+
+func (app *application) recurringResumePost(w http.ResponseWriter, r *http.Request) {
+	app.setRecurringPaused(w, r, false)
+}
+
+func (app *application) setRecurringPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user pausing recurring rule")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.recurring.SetPaused(userId, id, paused)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if paused {
+		app.sessionManager.Put(r.Context(), "flash", "Recurring transaction paused.")
+	} else {
+		app.sessionManager.Put(r.Context(), "flash", "Recurring transaction resumed, missed occurrences will be posted.")
+	}
+	http.Redirect(w, r, "/recurring/", http.StatusSeeOther)
+}
+
+func (app *application) getRecurringRule(w http.ResponseWriter, r *http.Request, userId int) (*models.RecurringRule, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	rule, err := app.recurring.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return rule, true
+}
+
+// parseRecurringForm reads and validates the rule form, ok is false when a
+// response was already written.
+func (app *application) parseRecurringForm(w http.ResponseWriter, r *http.Request, userId int) (recurringForm, bool) {
+	form := recurringForm{}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return form, false
+	}
+
+	accId, err := strconv.Atoi(r.PostForm.Get("account"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return form, false
+	}
+
+	account, err := app.accounts.Get(userId, accId)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return form, false
+	}
+	if !account.Role.Allows(models.RoleEditor) {
+		app.clientError(w, http.StatusForbidden)
+		return form, false
+	}
+
+	txType, err := strconv.Atoi(r.PostForm.Get("txtype"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return form, false
+	}
+
+	form.AccountID = account.ID
+	form.TransactionType = txType
+	form.Category = r.PostForm.Get("category")
+	form.Description = strings.TrimSpace(r.PostForm.Get("description"))
+	form.Frequency = r.PostForm.Get("frequency")
+
+	amount, err := models.ParseMoney(r.PostForm.Get("amount"), account.Currency)
+	form.Amount = amount
+	form.CheckField(err == nil, "amount", "This field must be a valid amount.")
+	form.CheckField(amount.Minor > 0, "amount", "This field must be greater than zero.")
+
+	interval, err := strconv.Atoi(r.PostForm.Get("interval"))
+	form.Interval = interval
+	form.CheckField(err == nil && interval >= 1 && interval <= 12, "interval", "This field must be a number from 1 to 12.")
+
+	day, err := strconv.Atoi(r.PostForm.Get("day"))
+	form.Day = day
+	form.CheckField(err == nil && day >= 1 && day <= 31, "day", "This field must be a day of the month from 1 to 31.")
+
+	startDate, err := time.Parse("2006-01-02", r.PostForm.Get("start-date"))
+	form.StartDate = startDate
+	form.CheckField(err == nil, "start-date", "This field must be a valid date.")
+
+	if s := r.PostForm.Get("end-date"); s != "" {
+		endDate, err := time.Parse("2006-01-02", s)
+		form.EndDate = endDate
+		form.CheckField(err == nil, "end-date", "This field must be a valid date.")
+		form.CheckField(!endDate.Before(startDate), "end-date", "This field cannot be before the start date.")
+	}
+
+	form.CheckField(validator.PermittedInt(form.TransactionType, 0, 1), "txtype", "This field must equal 0(INCOME) or 1(EXPENSE)")
+	form.CheckField(models.Frequency(form.Frequency).Known(), "frequency", "This field must be weekly, monthly or yearly.")
+	form.CheckField(validator.NotBlank(form.Category), "category", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Description, 100), "description", "This field cannot be more than 100 chars long.")
+
+	return form, true
+}
+
+func (form recurringForm) rule(userId int) models.RecurringRule {
+	return models.RecurringRule{
+		ID:              form.ID,
+		UserID:          userId,
+		AccountID:       form.AccountID,
+		TransactionType: models.TransactionType(form.TransactionType),
+		Amount:          form.Amount,
+		Category:        form.Category,
+		Description:     form.Description,
+		Frequency:       models.Frequency(form.Frequency),
+		Interval:        form.Interval,
+		Day:             form.Day,
+		StartDate:       form.StartDate,
+		EndDate:         form.EndDate,
+	}
+}
+
+func (app *application) renderRecurringForm(w http.ResponseWriter, r *http.Request, status, userId int, form recurringForm, current string) {
+	accounts, err := app.formAccounts(userId, form.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	err = app.withCategories(data, userId, models.TransactionType(form.TransactionType), current)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Accounts = accounts
+	data.Form = form
+	app.render(w, status, "recurring_edit.html", data)
+}
+
+// describeSchedule describes the schedule of a rule, e.g. "every 2 months on day 15".
+func describeSchedule(r *models.RecurringRule) string {
+	var unit, on string
+	switch r.Frequency {
+	case models.Weekly:
+		unit, on = "week", "on "+r.StartDate.Weekday().String()
+	case models.Monthly:
+		unit, on = "month", fmt.Sprintf("on day %d", r.Day)
+	case models.Yearly:
+		unit, on = "year", "on "+r.StartDate.Format("2 Jan")
+	}
+
+	if r.Interval > 1 {
+		return fmt.Sprintf("every %d %ss %s", r.Interval, unit, on)
+	}
+	return fmt.Sprintf("every %s %s", unit, on)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+type searchForm struct {
+	Query     string
+	AccountID int
+	StartDate time.Time
+	EndDate   time.Time
+	Min       string
+	Max       string
+	// Searched is false until there is text or a filter to search with.
+	Searched bool
+	validator.Validator
+}
+
+func (app *application) searchView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user searching transactions")
+		app.serverError(w, err)
+		return
+	}
+
+	params := r.URL.Query()
+	form := searchForm{
+		Query: strings.TrimSpace(params.Get("q")),
+		Min:   strings.TrimSpace(params.Get("min")),
+		Max:   strings.TrimSpace(params.Get("max")),
+	}
+	form.CheckField(validator.MaxChars(form.Query, 200), "q", "This field cannot be more than 200 chars long.")
+
+	var err error
+	if account := params.Get("account"); account != "" {
+		form.AccountID, err = strconv.Atoi(account)
+		form.CheckField(err == nil && form.AccountID > 0, "account", "Pick one of your accounts.")
+	}
+	if start := params.Get("start-date"); start != "" {
+		form.StartDate, err = time.Parse("2006-01-02", start)
+		form.CheckField(err == nil, "start-date", "This field must be a valid date.")
+	}
+	if end := params.Get("end-date"); end != "" {
+		form.EndDate, err = time.Parse("2006-01-02", end)
+		form.CheckField(err == nil, "end-date", "This field must be a valid date.")
+	}
+	form.CheckField(form.StartDate.IsZero() || form.EndDate.IsZero() || !form.EndDate.Before(form.StartDate),
+		"end-date", "This field must not be before the start date.")
+
+	q := models.SearchQuery{
+		Text:      form.Query,
+		AccountID: form.AccountID,
+		StartDate: form.StartDate,
+		EndDate:   form.EndDate,
+	}
+	if form.Min != "" {
+		q.MinAmount, err = strconv.ParseFloat(form.Min, 64)
+		form.CheckField(err == nil && q.MinAmount >= 0, "min", "This field must be an amount.")
+	}
+	if form.Max != "" {
+		q.MaxAmount, err = strconv.ParseFloat(form.Max, 64)
+		form.CheckField(err == nil && q.MaxAmount >= 0, "max", "This field must be an amount.")
+	}
+	form.CheckField(q.MaxAmount == 0 || q.MinAmount <= q.MaxAmount, "max", "This field must not be less than the minimum.")
+
+	form.Searched = len(models.SearchTerms(form.Query)) > 0 || form.AccountID > 0 ||
+		!form.StartDate.IsZero() || !form.EndDate.IsZero() || q.MinAmount > 0 || q.MaxAmount > 0
+
+	data := app.newTemplateData(r)
+	data.Accounts, err = app.accounts.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !form.Valid() {
+		status = http.StatusUnprocessableEntity
+		form.Searched = false
+	}
+
+	if form.Searched {
+		data.SearchResults, err = app.search.Search(userId, q)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		data.Members, err = app.households.GetMemberNames(userId)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	data.SearchText = form.Query
+	data.Form = form
+	app.render(w, status, "search.html", data)
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/markaya/meinappf/internal/models"
@@ -20,10 +22,8 @@ func (app *application) transactionCreate(w http.ResponseWriter, r *http.Request
 	switch path {
 	case "income":
 		transactionType = models.Income
-		data.DefaultIncomeCategories()
 	case "expense":
 		transactionType = models.Expense
-		data.DefaultExpenseCategories()
 	default:
 		err := fmt.Errorf("path %s does not exist", path)
 		app.serverError(w, err)
@@ -37,15 +37,27 @@ func (app *application) transactionCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	accounts, err := app.accounts.GetAll(id)
+	accounts, err := app.formAccounts(id)
 	if err != nil {
 		app.errorLog.Println("error while getting all accounts for user")
 		app.serverError(w, err)
 		return
 	}
 
+	err = app.withCategories(data, id, transactionType, "")
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Payees, err = app.payees.GetAll(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	data.Accounts = accounts
-	data.Form = models.TransactionCreateForm{Currency: 0, TransactionType: int(transactionType)}
+	data.Form = models.TransactionCreateForm{TransactionType: int(transactionType)}
 	data.DateStringNow = time.Now().Format("2006-01-02")
 
 	app.render(w, http.StatusOK, "transaction_create.html", data)
@@ -79,6 +91,11 @@ func (app *application) transactionCreatePost(w http.ResponseWriter, r *http.Req
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
+	// NOTE: Household viewers can see shared accounts but not add to them.
+	if !account.Role.Allows(models.RoleEditor) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
 
 	currency := account.Currency
 
@@ -89,7 +106,7 @@ func (app *application) transactionCreatePost(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	amount, err := strconv.ParseFloat(r.PostForm.Get("amount"), 64)
+	amount, err := models.ParseMoney(r.PostForm.Get("amount"), currency)
 	if err != nil {
 		app.infoLog.Println("error while parsing amount type")
 		app.clientError(w, http.StatusBadRequest)
@@ -110,31 +127,84 @@ func (app *application) transactionCreatePost(w http.ResponseWriter, r *http.Req
 		Amount:          amount,
 		Category:        r.PostForm.Get("category"),
 		Description:     r.PostForm.Get("description"),
-		Currency:        int(currency),
+		Currency:        currency,
 		TransactionType: txType,
+		Tags:            models.ParseTags(r.PostForm.Get("tags")),
+		Payee:           r.PostForm.Get("payee"),
+	}
+
+	splits, ok := parseSplits(r, currency)
+	form.Splits = splits
+	form.CheckField(ok, "splits", "Every split line needs a category and a valid amount.")
+	checkSplits(&form)
+
+	err = app.findPayee(userId, &form)
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
+	form.CheckField(validator.MaxChars(form.Payee, 50), "payee", "This field cannot be more than 50 chars long.")
 
 	form.CheckField(validator.NotBlank(form.Category), "category", "This field cannot be blank")
 	form.CheckField(validator.MaxChars(form.Category, 25), "category", "This field cannto be more than 25 chars long.")
 	form.CheckField(validator.MaxChars(form.Description, 100), "descritpion", "This field cannto be more than 100 chars long.")
-	form.CheckField(validator.PermittedInt(form.Currency, 0, 1), "currency", "This field must equal 0(RSD) or 1(EUR)")
+	form.CheckField(form.Currency.Known(), "currency", "This field must be a supported currency")
 	form.CheckField(validator.PermittedInt(form.TransactionType, 0, 1), "txtype", "This field must equal 0(INCOME) or 1(EXPENSE)")
-	form.CheckField(validator.GreaterThanZero(form.Amount), "amount", "This field must be greater than zero.")
+	form.CheckField(validator.GreaterThanZero(form.Amount.Minor), "amount", "This field must be greater than zero.")
+	checkTags(&form.Validator, form.Tags)
 
-	txAmountSigned := amount
 	var transactionType = models.TransactionType(txType)
 
-	if transactionType == models.Expense {
-		txAmountSigned = -amount
+	if form.Valid() {
+		// NOTE: A split transaction takes its category from the first line,
+		// so only the lines need checking.
+		if len(form.Splits) == 0 {
+			permitted, err := app.categoryPermitted(userId, transactionType, form.Category, "")
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			form.CheckField(permitted, "category", "This field must be one of your categories.")
+		} else {
+			permitted, err := app.splitsPermitted(userId, transactionType, form.Splits, nil)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			form.CheckField(permitted, "splits", "Every split line must use one of your categories.")
+		}
 	}
 
-	newBalance := account.Balance + txAmountSigned
-	if newBalance < 0 {
-		form.AddFieldError("amount", "Account does not have suficient funds.")
+	if form.Valid() {
+		err = app.createPayee(userId, &form)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if form.Valid() {
+		_, err = app.transactions.Insert(form)
+		switch {
+		case errors.Is(err, models.ErrInsufficientFunds):
+			form.AddFieldError("amount", "Account does not have suficient funds.")
+		case errors.Is(err, models.ErrAccountClosed):
+			form.AddFieldError("amount", "Account is closed.")
+		case errors.Is(err, models.ErrSplitTotal):
+			form.AddFieldError("splits", "Split lines must add up to the amount.")
+		case errors.Is(err, models.ErrAccountDoesNotExist):
+			form.AddFieldError("account", "Account does not exist.")
+		case errors.Is(err, models.ErrConcurrentUpdate):
+			form.AddFieldError("amount", "Account is being changed by another request, please try again.")
+		case err != nil:
+			app.infoLog.Println("server error when inserting!")
+			app.serverError(w, err)
+			return
+		}
 	}
 
 	if !form.Valid() {
-		accounts, err := app.accounts.GetAll(userId)
+		accounts, err := app.formAccounts(userId)
 		if err != nil {
 			app.errorLog.Println("error while getting all accounts for user")
 			app.serverError(w, err)
@@ -142,15 +212,16 @@ func (app *application) transactionCreatePost(w http.ResponseWriter, r *http.Req
 		}
 		data := app.newTemplateData(r)
 
-		switch transactionType {
-		case models.Expense:
-			data.DefaultExpenseCategories()
-		case models.Income:
-			data.DefaultIncomeCategories()
-		default:
-			app.errorLog.Println("unsuported transaction type for user creation of transaction")
-			panic("unsupported tx type")
+		err = app.withCategories(data, userId, transactionType, "")
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
 
+		data.Payees, err = app.payees.GetAll(userId)
+		if err != nil {
+			app.serverError(w, err)
+			return
 		}
 
 		data.Accounts = accounts
@@ -159,22 +230,6 @@ func (app *application) transactionCreatePost(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// FIXME: sending account like this is prime call for race conditions.
-	// It is fine for now as there is no concurrent writes.
-	_, err = app.transactions.Insert(form, newBalance)
-	if err != nil {
-		if errors.Is(err, models.ErrAccountDoesNotExist) {
-			form.AddFieldError("account", "Account does not exist.")
-			data := app.newTemplateData(r)
-			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "account_create.html", data)
-		} else {
-			app.infoLog.Println("server error when inserting!")
-			app.serverError(w, err)
-			return
-		}
-	}
-
 	var redirectUrl string
 	switch transactionType {
 	case models.Expense:
@@ -190,6 +245,324 @@ func (app *application) transactionCreatePost(w http.ResponseWriter, r *http.Req
 	http.Redirect(w, r, redirectUrl, http.StatusSeeOther)
 }
 
+func (app *application) transactionEdit(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting transaction edit")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	transaction, err := app.transactions.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	editable, err := app.canEdit(userId, transaction.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if !editable {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	app.renderTransactionEdit(w, r, http.StatusOK, userId, transaction, newTransactionEditForm(userId, transaction))
+}
+
+// newTransactionEditForm fills the edit form with the saved transaction.
+func newTransactionEditForm(userId int, transaction *models.Transaction) models.TransactionCreateForm {
+	return models.TransactionCreateForm{
+		UserId:          userId,
+		AccountId:       transaction.AccountID,
+		Date:            transaction.Date,
+		Amount:          transaction.Amount,
+		Category:        transaction.Category,
+		Description:     transaction.Description,
+		Currency:        transaction.Currency,
+		TransactionType: int(transaction.TransactionType),
+		Tags:            transaction.Tags,
+		Splits:          transaction.Splits,
+		PayeeID:         transaction.PayeeID,
+		Payee:           transaction.Payee,
+	}
+}
+
+func (app *application) transactionEditPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user editing transaction")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	transaction, err := app.transactions.Get(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	accId, err := strconv.Atoi(r.PostForm.Get("account"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	account, err := app.accounts.Get(userId, accId)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if !account.Role.Allows(models.RoleEditor) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", r.PostForm.Get("date"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := models.TransactionCreateForm{
+		UserId:          userId,
+		AccountId:       account.ID,
+		Date:            date,
+		Category:        r.PostForm.Get("category"),
+		Description:     r.PostForm.Get("description"),
+		Currency:        account.Currency,
+		TransactionType: int(transaction.TransactionType),
+		Tags:            models.ParseTags(r.PostForm.Get("tags")),
+		Payee:           r.PostForm.Get("payee"),
+	}
+
+	amount, err := models.ParseMoney(r.PostForm.Get("amount"), account.Currency)
+	form.Amount = amount
+	form.CheckField(err == nil, "amount", "This field must be a valid amount.")
+
+	splits, ok := parseSplits(r, account.Currency)
+	form.Splits = splits
+	form.CheckField(ok, "splits", "Every split line needs a category and a valid amount.")
+	checkSplits(&form)
+
+	err = app.findPayee(userId, &form)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	form.CheckField(validator.MaxChars(form.Payee, 50), "payee", "This field cannot be more than 50 chars long.")
+
+	form.CheckField(validator.NotBlank(form.Category), "category", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Category, 25), "category", "This field cannto be more than 25 chars long.")
+	form.CheckField(validator.MaxChars(form.Description, 100), "descritpion", "This field cannto be more than 100 chars long.")
+	form.CheckField(amount.Minor > 0, "amount", "This field must be greater than zero.")
+	checkTags(&form.Validator, form.Tags)
+
+	if form.Valid() {
+		// NOTE: A split transaction takes its category from the first line,
+		// so only the lines need checking.
+		if len(form.Splits) == 0 {
+			permitted, err := app.categoryPermitted(userId, transaction.TransactionType, form.Category, transaction.Category)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			form.CheckField(permitted, "category", "This field must be one of your categories.")
+		} else {
+			permitted, err := app.splitsPermitted(userId, transaction.TransactionType, form.Splits, transaction.Lines())
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			form.CheckField(permitted, "splits", "Every split line must use one of your categories.")
+		}
+	}
+
+	if form.Valid() {
+		err = app.createPayee(userId, &form)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if form.Valid() {
+		err = app.transactions.Update(id, form)
+		switch {
+		case errors.Is(err, models.ErrInsufficientFunds):
+			form.AddFieldError("amount", "Account does not have suficient funds for this change.")
+		case errors.Is(err, models.ErrAccountClosed):
+			form.AddFieldError("amount", "Account is closed.")
+		case errors.Is(err, models.ErrSplitTotal):
+			form.AddFieldError("splits", "Split lines must add up to the amount.")
+		case errors.Is(err, models.ErrConcurrentUpdate):
+			form.AddFieldError("amount", "Account is being changed by another request, please try again.")
+		case errors.Is(err, models.ErrNotEditable):
+			form.AddFieldError("account", "Only incomes and expenses can be edited.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderTransactionEdit(w, r, http.StatusUnprocessableEntity, userId, transaction, form)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Transaction successfully updated!")
+	http.Redirect(w, r, "/transactions/", http.StatusSeeOther)
+}
+
+func (app *application) transactionDeletePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting transaction")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.transactions.Delete(userId, id)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrInsufficientFunds):
+		app.sessionManager.Put(r.Context(), "flash", "Transaction can not be deleted, the account would go below zero.")
+	case errors.Is(err, models.ErrAccountClosed):
+		app.sessionManager.Put(r.Context(), "flash", "Transaction can not be deleted, the account is closed.")
+	case errors.Is(err, models.ErrNotEditable):
+		app.sessionManager.Put(r.Context(), "flash", "Only incomes and expenses can be deleted.")
+	case errors.Is(err, models.ErrConcurrentUpdate):
+		app.sessionManager.Put(r.Context(), "flash", "Account is being changed by another request, please try again.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Transaction moved to trash.")
+	}
+
+	http.Redirect(w, r, "/transactions/", http.StatusSeeOther)
+}
+
+func (app *application) transactionRestorePost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user restoring transaction")
+		app.serverError(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.transactions.Restore(userId, id)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrInsufficientFunds):
+		app.sessionManager.Put(r.Context(), "flash", "Transaction can not be restored, the account does not have suficient funds.")
+	case errors.Is(err, models.ErrAccountClosed):
+		app.sessionManager.Put(r.Context(), "flash", "Transaction can not be restored, the account is closed.")
+	case errors.Is(err, models.ErrConcurrentUpdate):
+		app.sessionManager.Put(r.Context(), "flash", "Account is being changed by another request, please try again.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Transaction restored!")
+	}
+
+	http.Redirect(w, r, "/transactions/trash/", http.StatusSeeOther)
+}
+
+func (app *application) transactionsTrashView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting trash view")
+		app.serverError(w, err)
+		return
+	}
+
+	deleted, err := app.transactions.GetDeleted(userId, time.Now().Add(-models.TrashRetention))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Transactions = deleted
+	app.render(w, http.StatusOK, "trash.html", data)
+}
+
+func (app *application) renderTransactionEdit(w http.ResponseWriter, r *http.Request, status, userId int, transaction *models.Transaction, form models.TransactionCreateForm) {
+	accounts, err := app.formAccounts(userId, transaction.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	current := []string{}
+	for _, line := range transaction.Lines() {
+		current = append(current, line.Category)
+	}
+	err = app.withCategories(data, userId, transaction.TransactionType, current...)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Payees, err = app.payees.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Accounts = accounts
+	data.Transaction = transaction
+	data.Form = form
+	app.render(w, status, "transaction_edit.html", data)
+}
+
 func (app *application) transactionsView(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.WithDefaultDateFilter()
@@ -232,9 +605,13 @@ func (app *application) transactionsView(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// NOTE: Tags are stored trimmed and in lower case, see models.ParseTags.
+	data.TagFilter = strings.ToLower(strings.TrimSpace(r.Form.Get("tag")))
+
 	incomeTransactions, err := app.transactions.GetByDateAndType(
 		userId,
 		models.Income,
+		data.TagFilter,
 		data.DateFilter["startDate"],
 		data.DateFilter["endDate"],
 	)
@@ -246,6 +623,7 @@ func (app *application) transactionsView(w http.ResponseWriter, r *http.Request)
 	expenseTransactions, err := app.transactions.GetByDateAndType(
 		userId,
 		models.Expense,
+		data.TagFilter,
 		data.DateFilter["startDate"],
 		data.DateFilter["endDate"],
 	)
@@ -254,13 +632,28 @@ func (app *application) transactionsView(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	tags, err := app.transactions.GetTags(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Tags = tags
+
 	report := services.GetTotalReport(
 		append(incomeTransactions, expenseTransactions...),
 		data.DateFilter["startDate"],
 		data.DateFilter["endDate"],
 	)
 
+	members, err := app.households.GetMemberNames(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	data.UserTotalReport = report
+	data.Members = members
 	data.IncomeTransactions = incomeTransactions
 	data.ExpenseTransactions = expenseTransactions
 
@@ -321,7 +714,114 @@ func (app *application) groupingsView(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, err)
 		return
 	}
-	data.GroupingReports = groupings
+	categories, err := app.categories.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.CategoryRollups = services.RollUpGroupings(categories, groupings)
+
+	tagReports, err := app.transactions.GetTagReport(
+		userId,
+		data.DateFilter["startDate"],
+		data.DateFilter["endDate"],
+	)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data.TagReports = tagReports
+
+	payeeReports, err := app.payees.GetReport(
+		userId,
+		data.DateFilter["startDate"],
+		data.DateFilter["endDate"],
+	)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data.PayeeReports = payeeReports
 
 	app.render(w, http.StatusOK, "groupings.html", data)
 }
+
+func checkTags(v *validator.Validator, tags []string) {
+	v.CheckField(len(tags) <= models.MaxTags, "tags", fmt.Sprintf("This field cannot have more than %d tags.", models.MaxTags))
+	for _, tag := range tags {
+		v.CheckField(validator.MaxChars(tag, 25), "tags", "A tag cannot be more than 25 chars long.")
+	}
+}
+
+// parseSplits reads the optional split lines of a transaction form, rows left
+// completely blank are skipped. ok is false when a row misses its category or
+// has an amount that can not be parsed.
+func parseSplits(r *http.Request, currency models.Currency) (splits []models.Split, ok bool) {
+	categories := r.PostForm["split-category"]
+	amounts := r.PostForm["split-amount"]
+	descriptions := r.PostForm["split-description"]
+
+	ok = true
+	for i, category := range categories {
+		var rawAmount, description string
+		if i < len(amounts) {
+			rawAmount = strings.TrimSpace(amounts[i])
+		}
+		if i < len(descriptions) {
+			description = strings.TrimSpace(descriptions[i])
+		}
+		if category == "" && rawAmount == "" && description == "" {
+			continue
+		}
+
+		amount, err := models.ParseMoney(rawAmount, currency)
+		if category == "" || err != nil {
+			ok = false
+			continue
+		}
+		splits = append(splits, models.Split{Category: category, Amount: amount, Description: description})
+	}
+
+	return splits, ok
+}
+
+// checkSplits validates the split lines of form and, when there are any, makes
+// the category of the first line the category of the transaction.
+func checkSplits(form *models.TransactionCreateForm) {
+	if len(form.Splits) == 0 {
+		return
+	}
+
+	form.Category = form.Splits[0].Category
+	form.CheckField(len(form.Splits) >= 2, "splits", "A split needs at least two lines.")
+	form.CheckField(len(form.Splits) <= models.MaxSplits, "splits", fmt.Sprintf("A split cannot have more than %d lines.", models.MaxSplits))
+	for _, s := range form.Splits {
+		form.CheckField(validator.GreaterThanZero(s.Amount.Minor), "splits", "Every split amount must be greater than zero.")
+		form.CheckField(validator.MaxChars(s.Description, 100), "splits", "A split description cannot be more than 100 chars long.")
+	}
+	form.CheckField(models.SplitsTotal(form.Splits) == form.Amount.Minor, "splits", "Split lines must add up to the amount.")
+}
+
+// splitsPermitted reports whether every split line uses a category that can be
+// picked for type tt, current being the lines the transaction already has.
+func (app *application) splitsPermitted(userId int, tt models.TransactionType, splits, current []models.Split) (bool, error) {
+	if len(splits) == 0 {
+		return true, nil
+	}
+
+	categories, err := app.categories.GetActive(userId, tt)
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range splits {
+		used := slices.ContainsFunc(current, func(c models.Split) bool { return c.Category == s.Category })
+		active := slices.ContainsFunc(categories, func(c *models.Category) bool { return c.Name == s.Category })
+		if !used && !active {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
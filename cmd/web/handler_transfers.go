@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -52,13 +53,17 @@ func (app *application) transfersView(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	transactions, err := app.transactions.GetTransfers(
+	transfers, err := app.transactions.GetTransfers(
 		userId,
 		data.DateFilter["startDate"],
 		data.DateFilter["endDate"],
 	)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
-	data.IncomeTransactions = transactions
+	data.Transfers = transfers
 
 	app.render(w, http.StatusOK, "transfers.html", data)
 }
@@ -72,7 +77,7 @@ func (app *application) transferCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accounts, err := app.accounts.GetAll(id)
+	accounts, err := app.formAccounts(id)
 	if err != nil {
 		app.errorLog.Println("error while getting all accounts for user")
 		app.serverError(w, err)
@@ -93,117 +98,383 @@ func (app *application) transferCreatePost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err := r.ParseForm()
+	form, err := app.parseTransferForm(r, userId)
 	if err != nil {
-		app.errorLog.Printf("error parsing form")
+		app.errorLog.Print(err)
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	fromAccId, err := strconv.Atoi(r.PostForm.Get("from"))
+	err = app.setTransferRate(&form, r.PostForm.Get("rate"))
 	if err != nil {
-		app.errorLog.Printf("error parsing from acc")
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	toAccId, err := strconv.Atoi(r.PostForm.Get("to"))
-	if err != nil {
-		app.errorLog.Printf("error parsing to acc")
-		app.clientError(w, http.StatusBadRequest)
+	data := app.newTemplateData(r)
+
+	if form.FromAcc.Available().Minor < form.FromAmount.Add(form.Fee).Minor {
+		form.AddFieldError("amount", "Account does not have suficient funds.")
+	}
+
+	data.Form = form
+	if !form.Valid() {
+		accounts, err := app.formAccounts(userId)
+		if err != nil {
+			app.errorLog.Println("error while getting all accounts for user")
+			app.serverError(w, err)
+			return
+		}
+
+		data.Accounts = accounts
+		app.renderForm(w, http.StatusOK, "transfer_create_form.html", "transfer-create-form", data)
 		return
 	}
 
-	fromAmount, err := strconv.ParseFloat(r.PostForm.Get("amount"), 64)
+	confirmed, err := strconv.ParseBool(r.PostForm.Get("confirm"))
 	if err != nil {
-		app.errorLog.Printf("error parsing from amount acc")
+		app.errorLog.Printf("Error parsing to  acc")
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", r.PostForm.Get("date"))
-	if err != nil {
-		app.errorLog.Printf("error parsing date acc")
-		app.clientError(w, http.StatusBadRequest)
+	if confirmed {
+		_, err = app.transactions.InsertTransfer(form)
+		switch {
+		case errors.Is(err, models.ErrInsufficientFunds):
+			form.AddFieldError("amount", "Account does not have suficient funds.")
+		case errors.Is(err, models.ErrAccountClosed):
+			form.AddFieldError("amount", "One of the accounts is closed.")
+		case errors.Is(err, models.ErrConcurrentUpdate):
+			form.AddFieldError("amount", "Account is being changed by another request, please try again.")
+		case err != nil:
+			app.serverError(w, err)
+			return
+		}
+
+		if !form.Valid() {
+			accounts, err := app.formAccounts(userId)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
+			data.Form = form
+			data.Accounts = accounts
+			app.renderForm(w, http.StatusOK, "transfer_create_form.html", "transfer-create-form", data)
+			return
+		}
+
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(http.StatusOK)
+
+	} else {
+		app.renderForm(w, http.StatusOK, "transfer_confirm.html", "transfer-confirm", data)
+	}
+}
+
+func (app *application) transferView(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting transfer view")
+		app.serverError(w, err)
 		return
 	}
 
-	fromAcc, err := app.accounts.Get(userId, fromAccId)
+	transfer, ok := app.getTransfer(w, r, userId)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Transfer = transfer
+	app.render(w, http.StatusOK, "transfer.html", data)
+}
+
+func (app *application) transferEdit(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user requesting transfer edit")
+		app.serverError(w, err)
+		return
+	}
+
+	transfer, ok := app.getTransfer(w, r, userId)
+	if !ok {
+		return
+	}
+
+	fromAcc, err := app.accounts.Get(userId, transfer.From.AccountID)
 	if err != nil {
-		app.errorLog.Printf("error parsing from  acc")
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
-	toAcc, err := app.accounts.Get(userId, toAccId)
+	toAcc, err := app.accounts.Get(userId, transfer.To.AccountID)
 	if err != nil {
-		app.errorLog.Printf("error parsing to  acc")
-		app.clientError(w, http.StatusBadRequest)
+		app.serverError(w, err)
 		return
 	}
 
-	toAmount := 0.0
-	if fromAcc.Currency == toAcc.Currency {
-		toAmount = fromAmount
-	} else {
-		if fromAcc.Currency == models.Euro {
-			toAmount = fromAmount * 117
-		} else {
-			toAmount = fromAmount / 117
-		}
+	form := models.TransferCreateForm{
+		UserId:       userId,
+		FromAcc:      *fromAcc,
+		FromAmount:   transfer.From.Amount,
+		ToAcc:        *toAcc,
+		ToAmount:     transfer.To.Amount,
+		Fee:          models.NewMoney(0, fromAcc.Currency),
+		Date:         transfer.Date,
+		Rate:         transfer.Rate,
+		RateDate:     transfer.RateDate,
+		RateOverride: transfer.CrossCurrency(),
+		Tags:         transfer.Tags,
+	}
+	if transfer.Fee != nil {
+		form.Fee = transfer.Fee.Amount
 	}
 
-	form := models.TransferCreateForm{
-		FromAcc:    *fromAcc,
-		FromAmount: fromAmount,
-		ToAcc:      *toAcc,
-		ToAmount:   toAmount,
-		Date:       date,
+	app.renderTransferEdit(w, r, http.StatusOK, userId, transfer, form)
+}
+
+func (app *application) transferEditPost(w http.ResponseWriter, r *http.Request) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user editing transfer")
+		app.serverError(w, err)
+		return
 	}
 
-	data := app.newTemplateData(r)
-	form.CheckField(validator.GreaterThanZero(form.FromAmount), "amount", "This field must be greater than zero.")
+	transfer, ok := app.getTransfer(w, r, userId)
+	if !ok {
+		return
+	}
 
-	if fromAcc.Balance < fromAmount {
-		form.AddFieldError("amount", "Account does not have suficient funds.")
+	form, err := app.parseTransferForm(r, userId)
+	if err != nil {
+		app.errorLog.Print(err)
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 
-	if fromAccId == toAccId {
-		form.AddFieldError("from", "Trying to transfer funds from one account to itself.")
-		form.AddFieldError("to", "Trying to transfer funds from one account to itself.")
+	err = app.setTransferRate(&form, r.PostForm.Get("rate"))
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
 
-	data.Form = form
-	if !form.Valid() {
-		accounts, err := app.accounts.GetAll(userId)
-		if err != nil {
-			app.errorLog.Println("error while getting all accounts for user")
+	if form.Valid() {
+		err = app.transactions.UpdateTransfer(transfer.ID, form)
+		switch {
+		case errors.Is(err, models.ErrInsufficientFunds):
+			form.AddFieldError("amount", "Account does not have suficient funds for this change.")
+		case errors.Is(err, models.ErrAccountClosed):
+			form.AddFieldError("amount", "One of the accounts is closed.")
+		case errors.Is(err, models.ErrConcurrentUpdate):
+			form.AddFieldError("amount", "Account is being changed by another request, please try again.")
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+			return
+		case err != nil:
 			app.serverError(w, err)
 			return
 		}
+	}
 
-		data.Accounts = accounts
-		app.renderForm(w, http.StatusOK, "transfer_create_form.html", "transfer-create-form", data)
+	if !form.Valid() {
+		app.renderTransferEdit(w, r, http.StatusUnprocessableEntity, userId, transfer, form)
 		return
 	}
 
-	confirmed, err := strconv.ParseBool(r.PostForm.Get("confirm"))
-	if err != nil {
-		app.errorLog.Printf("Error parsing to  acc")
-		app.clientError(w, http.StatusBadRequest)
+	app.sessionManager.Put(r.Context(), "flash", "Transfer successfully updated!")
+	http.Redirect(w, r, fmt.Sprintf("/transfer/view/%d", transfer.ID), http.StatusSeeOther)
+}
+
+func (app *application) transferDeletePost(w http.ResponseWriter, r *http.Request) {
+	app.setTransferDeleted(w, r, true)
+}
+
+func (app *application) transferRestorePost(w http.ResponseWriter, r *http.Request) {
+	app.setTransferDeleted(w, r, false)
+}
+
+func (app *application) setTransferDeleted(w http.ResponseWriter, r *http.Request, deleted bool) {
+	userId := app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+	if userId == 0 {
+		err := errors.New("unauthorized user deleting transfer")
+		app.serverError(w, err)
 		return
 	}
 
-	if confirmed {
-		err = app.transactions.InsertTransfer(form)
-		if err != nil {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	redirectUrl := "/transfers/"
+	if deleted {
+		err = app.transactions.DeleteTransfer(userId, id)
+	} else {
+		err = app.transactions.RestoreTransfer(userId, id)
+		redirectUrl = "/transactions/trash/"
+	}
+
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		app.notFound(w)
+		return
+	case errors.Is(err, models.ErrInsufficientFunds):
+		app.sessionManager.Put(r.Context(), "flash", "Transfer can not be changed, an account would go below zero.")
+	case errors.Is(err, models.ErrAccountClosed):
+		app.sessionManager.Put(r.Context(), "flash", "Transfer can not be changed, one of the accounts is closed.")
+	case errors.Is(err, models.ErrConcurrentUpdate):
+		app.sessionManager.Put(r.Context(), "flash", "Account is being changed by another request, please try again.")
+	case err != nil:
+		app.serverError(w, err)
+		return
+	case deleted:
+		app.sessionManager.Put(r.Context(), "flash", "Transfer moved to trash.")
+	default:
+		app.sessionManager.Put(r.Context(), "flash", "Transfer restored!")
+	}
+
+	http.Redirect(w, r, redirectUrl, http.StatusSeeOther)
+}
+
+// getTransfer loads the transfer named by the {id} path value, writing a not
+// found or server error response when it can not.
+func (app *application) getTransfer(w http.ResponseWriter, r *http.Request, userId int) (*models.Transfer, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	transfer, err := app.transactions.GetTransfer(userId, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
 			app.serverError(w, err)
-			return
 		}
+		return nil, false
+	}
 
-		w.Header().Set("HX-Redirect", "/")
-		w.WriteHeader(http.StatusOK)
+	return transfer, true
+}
+
+// parseTransferForm reads the fields shared by the create and edit forms. An
+// error means a malformed request, anything the user can correct is added to
+// the form as a field error.
+func (app *application) parseTransferForm(r *http.Request, userId int) (models.TransferCreateForm, error) {
+	form := models.TransferCreateForm{}
 
+	err := r.ParseForm()
+	if err != nil {
+		return form, err
+	}
+
+	fromAccId, err := strconv.Atoi(r.PostForm.Get("from"))
+	if err != nil {
+		return form, fmt.Errorf("error parsing from acc: %w", err)
+	}
+
+	toAccId, err := strconv.Atoi(r.PostForm.Get("to"))
+	if err != nil {
+		return form, fmt.Errorf("error parsing to acc: %w", err)
+	}
+
+	date, err := time.Parse("2006-01-02", r.PostForm.Get("date"))
+	if err != nil {
+		return form, fmt.Errorf("error parsing date: %w", err)
+	}
+
+	fromAcc, err := app.accounts.Get(userId, fromAccId)
+	if err != nil {
+		return form, fmt.Errorf("error getting from acc: %w", err)
+	}
+	toAcc, err := app.accounts.Get(userId, toAccId)
+	if err != nil {
+		return form, fmt.Errorf("error getting to acc: %w", err)
+	}
+
+	fromAmount, err := models.ParseMoney(r.PostForm.Get("amount"), fromAcc.Currency)
+	if err != nil {
+		return form, fmt.Errorf("error parsing amount: %w", err)
+	}
+
+	fee := models.NewMoney(0, fromAcc.Currency)
+	if rawFee := r.PostForm.Get("fee"); rawFee != "" {
+		fee, err = models.ParseMoney(rawFee, fromAcc.Currency)
+		form.CheckField(err == nil && !fee.IsNegative(), "fee", "This field must be zero or a positive amount.")
+	}
+
+	form.UserId = userId
+	form.FromAcc = *fromAcc
+	form.FromAmount = fromAmount
+	form.ToAcc = *toAcc
+	form.ToAmount = fromAmount
+	form.Fee = fee
+	form.Date = date
+	form.Rate = 1
+	form.Tags = models.ParseTags(r.PostForm.Get("tags"))
+
+	form.CheckField(validator.GreaterThanZero(form.FromAmount.Minor), "amount", "This field must be greater than zero.")
+	form.CheckField(fromAcc.Role.Allows(models.RoleEditor), "from", "You can only view this shared account.")
+	form.CheckField(toAcc.Role.Allows(models.RoleEditor), "to", "You can only view this shared account.")
+	checkTags(&form.Validator, form.Tags)
+
+	if fromAccId == toAccId {
+		form.AddFieldError("from", "Trying to transfer funds from one account to itself.")
+		form.AddFieldError("to", "Trying to transfer funds from one account to itself.")
+	}
+
+	return form, nil
+}
+
+// setTransferRate fills in the rate and the converted amount for transfers
+// between currencies. A rate typed by the user wins over the stored one for
+// the date.
+func (app *application) setTransferRate(form *models.TransferCreateForm, rawRate string) error {
+	if form.FromAcc.Currency == form.ToAcc.Currency {
+		return nil
+	}
+
+	if rawRate != "" {
+		rate, err := strconv.ParseFloat(rawRate, 64)
+		if err != nil || rate <= 0 {
+			form.AddFieldError("rate", "This field must be a positive number.")
+		} else {
+			form.Rate = rate
+			form.RateDate = form.Date
+			form.RateOverride = true
+		}
 	} else {
-		app.renderForm(w, http.StatusOK, "transfer_confirm.html", "transfer-confirm", data)
+		rate, err := app.exchangeRates.Get(form.UserId, form.FromAcc.Currency, form.ToAcc.Currency, form.Date)
+		if err != nil {
+			if !errors.Is(err, models.ErrNoRecord) {
+				return err
+			}
+			form.AddFieldError("rate", fmt.Sprintf("No %s/%s exchange rate on or before this date, please enter one.", form.FromAcc.Currency, form.ToAcc.Currency))
+		} else {
+			form.Rate = rate.Rate
+			form.RateDate = rate.Date
+		}
+	}
+
+	form.ToAmount = form.FromAmount.Convert(form.ToAcc.Currency, form.Rate)
+	return nil
+}
+
+func (app *application) renderTransferEdit(w http.ResponseWriter, r *http.Request, status, userId int, transfer *models.Transfer, form models.TransferCreateForm) {
+	accounts, err := app.formAccounts(userId, transfer.From.AccountID, transfer.To.AccountID)
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
+
+	data := app.newTemplateData(r)
+	data.Accounts = accounts
+	data.Transfer = transfer
+	data.Form = form
+	app.render(w, status, "transfer_edit.html", data)
 }
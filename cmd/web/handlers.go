@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/markaya/meinappf/internal/models"
 	"github.com/markaya/meinappf/internal/services"
@@ -54,9 +55,50 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	currencies, err := app.currencies.GetEnabled(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	enabled := map[models.Currency]bool{}
+	for _, c := range currencies {
+		enabled[c.Code] = true
+	}
+
+	rates, err := app.exchangeRates.GetLatestPerPair(userId)
+	if err != nil {
+		app.errorLog.Println("error while getting exchange rates")
+		app.serverError(w, err)
+		return
+	}
+	for _, rate := range rates {
+		if enabled[rate.From] && enabled[rate.To] {
+			data.ExchangeRates = append(data.ExchangeRates, rate)
+		}
+	}
+
 	data.IncomeTransactions = incomeTransactions
 	data.ExpenseTransactions = expenseTransactions
 	data.UserTotalReport = report
 
+	data.Budgets, err = app.budgetProgress(userId, time.Now())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Goals, err = app.goalProgress(userId, time.Now())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	accounts, err := app.accounts.GetAll(userId)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data.NetWorth = services.GetNetWorth(ownAccounts(accounts))
+
 	app.render(w, http.StatusOK, "home.html", data)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime/debug"
@@ -30,6 +31,20 @@ func (app *application) notFound(w http.ResponseWriter) {
 	app.clientError(w, http.StatusNotFound)
 }
 
+// canEdit reports whether the user may change the records of an account,
+// household viewers only see the accounts shared with them.
+func (app *application) canEdit(userId, accountId int) (bool, error) {
+	account, err := app.accounts.Get(userId, accountId)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return account.Role.Allows(models.RoleEditor), nil
+}
+
 func (app *application) renderTableRow(w http.ResponseWriter, status int, transaction models.Transaction) {
 	page := "table_row.html"
 	ts, ok := app.templateCache[page]
@@ -6,13 +6,16 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
+	"github.com/markaya/meinappf/internal/migrations"
 	"github.com/markaya/meinappf/internal/models"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -24,8 +27,6 @@ import (
 	b. Group by accouut + Currency
 	c. total spent
 0a. Transfers and rebalances tables
-1. Fix race conditions
-2. Separate transaction from ledger
 3. Add Apartments/Bills
 4. Add Books
 	a. MD Viewer
@@ -37,10 +38,14 @@ import (
 */
 
 type config struct {
-	addr      string
-	debugMode bool
-	dsn       string
-	tlsPath   string
+	addr        string
+	debugMode   bool
+	dsn         string
+	tlsPath     string
+	autoMigrate bool
+	// attachmentDir keeps attached files on disk, they go in the database
+	// when it is empty.
+	attachmentDir string
 }
 
 type application struct {
@@ -49,12 +54,43 @@ type application struct {
 	users          models.UserModelInterface
 	accounts       models.AccountModelInterface
 	transactions   models.TransactionsModelInterface
+	categories     models.CategoryModelInterface
+	payees         models.PayeeModelInterface
+	recurring      models.RecurringModelInterface
+	budgets        models.BudgetModelInterface
+	goals          models.GoalModelInterface
+	loans          models.LoanModelInterface
+	loanTerms      models.LoanTermsModelInterface
+	households     models.HouseholdModelInterface
+	attachments    models.AttachmentModelInterface
+	search         models.SearchModelInterface
+	currencies     models.CurrencyModelInterface
+	exchangeRates  models.ExchangeRateModelInterface
 	templateCache  map[string]*template.Template
 	sessionManager *scs.SessionManager
 	debugMode      bool
 }
 
 func main() {
+	// NOTE: Subcommands, everything else starts the web server
+	if len(os.Args) > 1 {
+		var run func([]string, io.Writer) error
+		switch os.Args[1] {
+		case "migrate":
+			run = runMigrate
+		case "rates":
+			run = runRates
+		}
+
+		if run != nil {
+			err := run(os.Args[2:], os.Stdout)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	cfg := config{}
 	flag.StringVar(&cfg.addr, "address", ":4000", "HTTP network addr")
 	// NOTE: Not needed because I use FS embed
@@ -62,6 +98,8 @@ func main() {
 	flag.BoolVar(&cfg.debugMode, "debug", false, "Turn debug mode on.")
 	flag.StringVar(&cfg.dsn, "dsn", "", "Sqlite db string")
 	flag.StringVar(&cfg.tlsPath, "tls", "./tls", "Tls folder")
+	flag.BoolVar(&cfg.autoMigrate, "migrate", true, "Apply pending database migrations on startup.")
+	flag.StringVar(&cfg.attachmentDir, "attachment-dir", "", "Folder for attached files, empty keeps them in the database.")
 
 	flag.Parse()
 	flag.Usage()
@@ -82,6 +120,40 @@ func main() {
 	}
 	defer db.Close()
 
+	// NOTE: Migrations
+	if cfg.autoMigrate {
+		applied, err := (&migrations.Migrator{DB: db}).Up()
+		for _, m := range applied {
+			infoLog.Printf("applied migration %s", m)
+		}
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+	}
+
+	// NOTE: Currency registry
+	currencies := &models.CurrencyModel{DB: db}
+	err = currencies.Load()
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	// NOTE: Search index, built with -tags sqlite_fts5 for full-text search.
+	search := &models.SearchModel{DB: db}
+	err = search.Init()
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	// NOTE: Attachments
+	if cfg.attachmentDir != "" {
+		err = os.MkdirAll(cfg.attachmentDir, 0o700)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+	}
+	attachments := &models.AttachmentModel{DB: db, Dir: cfg.attachmentDir}
+
 	// NOTE: Template cahce
 	templateCache, err := newTemplateCache()
 	if err != nil {
@@ -102,11 +174,26 @@ func main() {
 		users:          &models.UserModel{DB: db},
 		accounts:       &models.AccountModel{DB: db},
 		transactions:   &models.TransactionModel{DB: db},
+		categories:     &models.CategoryModel{DB: db},
+		payees:         &models.PayeeModel{DB: db},
+		recurring:      &models.RecurringModel{DB: db},
+		budgets:        &models.BudgetModel{DB: db},
+		goals:          &models.GoalModel{DB: db},
+		loans:          &models.LoanModel{DB: db},
+		loanTerms:      &models.LoanTermsModel{DB: db},
+		households:     &models.HouseholdModel{DB: db},
+		attachments:    attachments,
+		search:         search,
+		currencies:     currencies,
+		exchangeRates:  &models.ExchangeRateModel{DB: db},
 		templateCache:  templateCache,
 		sessionManager: sessionManager,
 		debugMode:      cfg.debugMode,
 	}
 
+	// NOTE: Recurring transactions and the trash bin
+	go app.runScheduler(recurringInterval)
+
 	// NOTE: TLS
 	tlsConfig := &tls.Config{
 		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
@@ -146,11 +233,7 @@ func openDB(dsn string) (*sql.DB, error) {
 		return nil, fmt.Errorf("invalid DSN: file path not found")
 	}
 
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("database file does not exist: %s", filePath)
-	}
-
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := sql.Open("sqlite3", withDSNDefaults(dsn))
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +245,28 @@ func openDB(dsn string) (*sql.DB, error) {
 	return db, err
 }
 
+// dsnDefaults are added to every DSN that does not set them itself. SQLite
+// leaves foreign keys off unless asked, and the schema relies on their
+// cascades. Immediate transactions take the write lock when they begin, so
+// two transactions that read a balance before changing it wait for each other
+// instead of failing with SQLITE_BUSY.
+var dsnDefaults = []string{"_foreign_keys=on", "_txlock=immediate"}
+
+func withDSNDefaults(dsn string) string {
+	for _, param := range dsnDefaults {
+		key, _, _ := strings.Cut(param, "=")
+		if strings.Contains(dsn, key+"=") {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + param
+		} else {
+			dsn += "?" + param
+		}
+	}
+	return dsn
+}
+
 func extractFilePath(dsn string) string {
 	if len(dsn) >= 5 && dsn[:5] == "file:" {
 		dsn = dsn[5:]
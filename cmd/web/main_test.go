@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/migrations"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestWithDSNDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{name: "Path only", dsn: "db/app.db", want: "db/app.db?_foreign_keys=on&_txlock=immediate"},
+		{name: "Other params", dsn: "db/app.db?_busy_timeout=5000", want: "db/app.db?_busy_timeout=5000&_foreign_keys=on&_txlock=immediate"},
+		{name: "Set already", dsn: "db/app.db?_txlock=deferred&_foreign_keys=off", want: "db/app.db?_txlock=deferred&_foreign_keys=off"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, withDSNDefaults(tt.dsn), tt.want)
+		})
+	}
+}
+
+// TestOpenDB checks the database the server runs on, deleting a household
+// must take the access of its members away.
+func TestOpenDB(t *testing.T) {
+	db, err := openDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var foreignKeys bool
+	err = db.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, foreignKeys, true)
+
+	_, err = (&migrations.Migrator{DB: db}).Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &models.UserModel{DB: db}
+	accounts := &models.AccountModel{DB: db}
+	households := &models.HouseholdModel{DB: db}
+
+	userIds := []int{}
+	for i := range 2 {
+		email := fmt.Sprintf("member%d@example.com", i)
+		err = users.Insert("Member", email, "pa$$word")
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := users.Authenticate(email, "pa$$word")
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIds = append(userIds, id)
+	}
+	owner, member := userIds[0], userIds[1]
+
+	accountId, err := accounts.Insert(owner, "Shared", models.Euro, models.Checking, models.NewMoney(0, models.Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := households.Insert(owner, "Home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.Invite(owner, id, "member1@example.com", models.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.Accept(member, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.ShareAccount(owner, id, accountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = accounts.Get(member, accountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = households.Delete(owner, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = accounts.Get(member, accountId)
+	assert.Equal(t, errors.Is(err, models.ErrNoRecord), true)
+	all, err := households.GetAll(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 0)
+}
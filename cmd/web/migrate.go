@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/markaya/meinappf/internal/migrations"
+)
+
+// runMigrate implements `mgo migrate [-dsn=...] [-steps=N] up|down|status`.
+func runMigrate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := fs.String("dsn", "", "Sqlite db string")
+	steps := fs.Int("steps", 1, "Number of migrations to roll back with down")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mgo migrate [-dsn=...] [-steps=N] up|down|status")
+		fs.PrintDefaults()
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *dsn == "" {
+		*dsn = os.Getenv("MGO_DATABASE_URL")
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one of up, down or status")
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := &migrations.Migrator{DB: db}
+
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %s\n", m)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "database is up to date")
+		}
+	case "down":
+		rolledBack, err := migrator.Down(*steps)
+		for _, m := range rolledBack {
+			fmt.Fprintf(out, "rolled back %s\n", m)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Fprintf(out, "%-40s applied %s\n", s.Migration, humanDate(s.AppliedAt))
+			} else {
+				fmt.Fprintf(out, "%-40s pending\n", s.Migration)
+			}
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", fs.Arg(0))
+	}
+
+	return nil
+}
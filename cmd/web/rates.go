@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/markaya/meinappf/internal/models"
+	"github.com/markaya/meinappf/internal/services"
+)
+
+// runRates implements `mgo rates import [-dsn=...] [-format=auto] FILE...`.
+func runRates(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("rates", flag.ExitOnError)
+	dsn := fs.String("dsn", "", "Sqlite db string")
+	format := fs.String("format", services.RateFormatAuto, "One of "+strings.Join(services.RateFormats, ", "))
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mgo rates import [-dsn=...] [-format=auto] FILE...")
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "import" {
+		fs.Usage()
+		return fmt.Errorf("expected import command")
+	}
+
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if *dsn == "" {
+		*dsn = os.Getenv("MGO_DATABASE_URL")
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one file")
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// NOTE: Import skips currencies the registry does not know about.
+	currencies := &models.CurrencyModel{DB: db}
+	err = currencies.Load()
+	if err != nil {
+		return err
+	}
+
+	exchangeRates := &models.ExchangeRateModel{DB: db}
+
+	for _, path := range fs.Args() {
+		report, err := importRatesFile(exchangeRates, *format, path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(out, "%s: %s\n", path, report)
+	}
+
+	return nil
+}
+
+func importRatesFile(exchangeRates models.ExchangeRateModelInterface, format, path string) (*models.RateImportReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rates, err := services.ParseRates(format, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Rates imported from the command line are shared by every user.
+	return exchangeRates.Import(0, rates)
+}
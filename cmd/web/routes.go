@@ -53,13 +53,112 @@ func (app *application) routes() http.Handler {
 	mux.Handle("GET /account/view/{id}", protected(dynamic(http.HandlerFunc(app.accountView))))
 	mux.Handle("GET /account/create", protected(dynamic(http.HandlerFunc(app.accountCreate))))
 	mux.Handle("POST /account/create", protected(dynamic(http.HandlerFunc(app.accountCreatePost))))
+	mux.Handle("POST /account/type/{id}", protected(dynamic(http.HandlerFunc(app.accountTypePost))))
+	mux.Handle("POST /account/rename/{id}", protected(dynamic(http.HandlerFunc(app.accountRenamePost))))
+	mux.Handle("POST /account/archive/{id}", protected(dynamic(http.HandlerFunc(app.accountArchivePost))))
+	mux.Handle("POST /account/unarchive/{id}", protected(dynamic(http.HandlerFunc(app.accountUnarchivePost))))
+	mux.Handle("POST /account/close/{id}", protected(dynamic(http.HandlerFunc(app.accountClosePost))))
+	mux.Handle("POST /account/reopen/{id}", protected(dynamic(http.HandlerFunc(app.accountReopenPost))))
+	mux.Handle("GET /account/schedule/{id}", protected(dynamic(http.HandlerFunc(app.loanScheduleView))))
+	mux.Handle("POST /account/schedule/{id}", protected(dynamic(http.HandlerFunc(app.loanSchedulePost))))
+	mux.Handle("POST /account/schedule/pay/{id}", protected(dynamic(http.HandlerFunc(app.loanSchedulePayPost))))
+	mux.Handle("POST /account/schedule/delete/{id}", protected(dynamic(http.HandlerFunc(app.loanScheduleDeletePost))))
 	mux.Handle("GET /account/rebalance/{id}", protected(dynamic(http.HandlerFunc(app.accountRebalanceView))))
 	mux.Handle("POST /account/rebalance/", protected(dynamic(http.HandlerFunc(app.accountRebalancePost))))
 
+	// NOTE: Currencies
+	mux.Handle("GET /currencies/", protected(dynamic(http.HandlerFunc(app.currenciesView))))
+	mux.Handle("POST /currency/enable", protected(dynamic(http.HandlerFunc(app.currencyEnablePost))))
+	mux.Handle("POST /currency/disable", protected(dynamic(http.HandlerFunc(app.currencyDisablePost))))
+
+	// NOTE: Exchange rates
+	mux.Handle("GET /exchange-rates/", protected(dynamic(http.HandlerFunc(app.exchangeRatesView))))
+	mux.Handle("POST /exchange-rate/create", protected(dynamic(http.HandlerFunc(app.exchangeRateCreatePost))))
+	mux.Handle("POST /exchange-rates/import", protected(dynamic(http.HandlerFunc(app.exchangeRateImportPost))))
+
 	// NOTE: Transactions
 	mux.Handle("GET /transactions/", protected(dynamic(http.HandlerFunc(app.transactionsView))))
 	mux.Handle("GET /transaction/create/{ttype}", protected(dynamic(http.HandlerFunc(app.transactionCreate))))
 	mux.Handle("POST /transaction/create/{$}", protected(dynamic(http.HandlerFunc(app.transactionCreatePost))))
+	mux.Handle("GET /transaction/edit/{id}", protected(dynamic(http.HandlerFunc(app.transactionEdit))))
+	mux.Handle("POST /transaction/edit/{id}", protected(dynamic(http.HandlerFunc(app.transactionEditPost))))
+	mux.Handle("POST /transaction/delete/{id}", protected(dynamic(http.HandlerFunc(app.transactionDeletePost))))
+	mux.Handle("GET /transactions/trash/", protected(dynamic(http.HandlerFunc(app.transactionsTrashView))))
+	mux.Handle("POST /transaction/restore/{id}", protected(dynamic(http.HandlerFunc(app.transactionRestorePost))))
+
+	// NOTE: Search
+	mux.Handle("GET /search/", protected(dynamic(http.HandlerFunc(app.searchView))))
+
+	// NOTE: Attachments
+	mux.Handle("POST /transaction/attach/{id}", protected(dynamic(http.HandlerFunc(app.attachmentCreatePost))))
+	mux.Handle("GET /attachment/view/{id}", protected(dynamic(http.HandlerFunc(app.attachmentView))))
+	mux.Handle("GET /attachment/thumbnail/{id}", protected(dynamic(http.HandlerFunc(app.attachmentThumbnailView))))
+	mux.Handle("POST /attachment/delete/{id}", protected(dynamic(http.HandlerFunc(app.attachmentDeletePost))))
+
+	// NOTE: Categories
+	mux.Handle("GET /categories/", protected(dynamic(http.HandlerFunc(app.categoriesView))))
+	mux.Handle("POST /category/create", protected(dynamic(http.HandlerFunc(app.categoryCreatePost))))
+	mux.Handle("POST /category/rename/{id}", protected(dynamic(http.HandlerFunc(app.categoryRenamePost))))
+	mux.Handle("POST /category/merge/{id}", protected(dynamic(http.HandlerFunc(app.categoryMergePost))))
+	mux.Handle("POST /category/move/{id}", protected(dynamic(http.HandlerFunc(app.categoryMovePost))))
+	mux.Handle("POST /category/archive/{id}", protected(dynamic(http.HandlerFunc(app.categoryArchivePost))))
+	mux.Handle("POST /category/unarchive/{id}", protected(dynamic(http.HandlerFunc(app.categoryUnarchivePost))))
+
+	// NOTE: Payees
+	mux.Handle("GET /payees/", protected(dynamic(http.HandlerFunc(app.payeesView))))
+	mux.Handle("POST /payee/create", protected(dynamic(http.HandlerFunc(app.payeeCreatePost))))
+	mux.Handle("POST /payee/category/{id}", protected(dynamic(http.HandlerFunc(app.payeeCategoryPost))))
+	mux.Handle("POST /payee/delete/{id}", protected(dynamic(http.HandlerFunc(app.payeeDeletePost))))
+	mux.Handle("POST /payee/alias/{id}", protected(dynamic(http.HandlerFunc(app.payeeAliasPost))))
+	mux.Handle("POST /payee/alias/delete/{id}", protected(dynamic(http.HandlerFunc(app.payeeAliasDeletePost))))
+
+	// NOTE: Recurring transactions
+	mux.Handle("GET /recurring/", protected(dynamic(http.HandlerFunc(app.recurringView))))
+	mux.Handle("GET /recurring/create/{ttype}", protected(dynamic(http.HandlerFunc(app.recurringCreate))))
+	mux.Handle("POST /recurring/create", protected(dynamic(http.HandlerFunc(app.recurringCreatePost))))
+	mux.Handle("GET /recurring/edit/{id}", protected(dynamic(http.HandlerFunc(app.recurringEdit))))
+	mux.Handle("POST /recurring/edit/{id}", protected(dynamic(http.HandlerFunc(app.recurringEditPost))))
+	mux.Handle("POST /recurring/delete/{id}", protected(dynamic(http.HandlerFunc(app.recurringDeletePost))))
+	mux.Handle("POST /recurring/pause/{id}", protected(dynamic(http.HandlerFunc(app.recurringPausePost))))
+	mux.Handle("POST /recurring/resume/{id}", protected(dynamic(http.HandlerFunc(app.recurringResumePost))))
+
+	// NOTE: Budgets
+	mux.Handle("GET /budgets/", protected(dynamic(http.HandlerFunc(app.budgetsView))))
+	mux.Handle("POST /budget/set", protected(dynamic(http.HandlerFunc(app.budgetSetPost))))
+	mux.Handle("POST /budget/delete/{id}", protected(dynamic(http.HandlerFunc(app.budgetDeletePost))))
+	mux.Handle("POST /budgets/copy", protected(dynamic(http.HandlerFunc(app.budgetsCopyPost))))
+
+	// NOTE: Goals
+	mux.Handle("GET /goals/", protected(dynamic(http.HandlerFunc(app.goalsView))))
+	mux.Handle("POST /goal/create", protected(dynamic(http.HandlerFunc(app.goalCreatePost))))
+	mux.Handle("GET /goal/edit/{id}", protected(dynamic(http.HandlerFunc(app.goalEdit))))
+	mux.Handle("POST /goal/edit/{id}", protected(dynamic(http.HandlerFunc(app.goalEditPost))))
+	mux.Handle("POST /goal/delete/{id}", protected(dynamic(http.HandlerFunc(app.goalDeletePost))))
+
+	// NOTE: Loans
+	mux.Handle("GET /loans/", protected(dynamic(http.HandlerFunc(app.loansView))))
+	mux.Handle("POST /loan/create", protected(dynamic(http.HandlerFunc(app.loanCreatePost))))
+	mux.Handle("GET /loan/view/{id}", protected(dynamic(http.HandlerFunc(app.loanView))))
+	mux.Handle("POST /loan/repay/{id}", protected(dynamic(http.HandlerFunc(app.loanRepayPost))))
+	mux.Handle("POST /loan/payment/delete/{id}/{payment}", protected(dynamic(http.HandlerFunc(app.loanPaymentDeletePost))))
+	mux.Handle("POST /loan/delete/{id}", protected(dynamic(http.HandlerFunc(app.loanDeletePost))))
+	mux.Handle("GET /counterparties/", protected(dynamic(http.HandlerFunc(app.counterpartiesView))))
+	mux.Handle("POST /counterparty/create", protected(dynamic(http.HandlerFunc(app.counterpartyCreatePost))))
+	mux.Handle("GET /counterparty/view/{id}", protected(dynamic(http.HandlerFunc(app.counterpartyView))))
+	mux.Handle("POST /counterparty/delete/{id}", protected(dynamic(http.HandlerFunc(app.counterpartyDeletePost))))
+
+	// NOTE: Households
+	mux.Handle("GET /households/", protected(dynamic(http.HandlerFunc(app.householdsView))))
+	mux.Handle("POST /household/create", protected(dynamic(http.HandlerFunc(app.householdCreatePost))))
+	mux.Handle("GET /household/view/{id}", protected(dynamic(http.HandlerFunc(app.householdView))))
+	mux.Handle("POST /household/invite/{id}", protected(dynamic(http.HandlerFunc(app.householdInvitePost))))
+	mux.Handle("POST /household/accept/{id}", protected(dynamic(http.HandlerFunc(app.householdAcceptPost))))
+	mux.Handle("POST /household/leave/{id}", protected(dynamic(http.HandlerFunc(app.householdLeavePost))))
+	mux.Handle("POST /household/role/{id}/{member}", protected(dynamic(http.HandlerFunc(app.householdRolePost))))
+	mux.Handle("POST /household/remove/{id}/{member}", protected(dynamic(http.HandlerFunc(app.householdRemovePost))))
+	mux.Handle("POST /household/share/{id}", protected(dynamic(http.HandlerFunc(app.householdSharePost))))
+	mux.Handle("POST /household/unshare/{id}/{account}", protected(dynamic(http.HandlerFunc(app.householdUnsharePost))))
+	mux.Handle("POST /household/delete/{id}", protected(dynamic(http.HandlerFunc(app.householdDeletePost))))
 
 	// NOTE: Groupings
 	mux.Handle("GET /groupings/", protected(dynamic(http.HandlerFunc(app.groupingsView))))
@@ -68,6 +167,11 @@ func (app *application) routes() http.Handler {
 	mux.Handle("GET /transfers/", protected(dynamic(http.HandlerFunc(app.transfersView))))
 	mux.Handle("GET /transfer/create/", protected(dynamic(http.HandlerFunc(app.transferCreate))))
 	mux.Handle("POST /transfer/create/", protected(dynamic(http.HandlerFunc(app.transferCreatePost))))
+	mux.Handle("GET /transfer/view/{id}", protected(dynamic(http.HandlerFunc(app.transferView))))
+	mux.Handle("GET /transfer/edit/{id}", protected(dynamic(http.HandlerFunc(app.transferEdit))))
+	mux.Handle("POST /transfer/edit/{id}", protected(dynamic(http.HandlerFunc(app.transferEditPost))))
+	mux.Handle("POST /transfer/delete/{id}", protected(dynamic(http.HandlerFunc(app.transferDeletePost))))
+	mux.Handle("POST /transfer/restore/{id}", protected(dynamic(http.HandlerFunc(app.transferRestorePost))))
 
 	// Match everything else
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
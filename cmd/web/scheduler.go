@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// recurringInterval is how often the scheduler looks for due occurrences
+// and empties the trash.
+const recurringInterval = time.Hour
+
+// runScheduler posts due recurring transactions and purges the trash right
+// away, catching up on anything missed while the server was down, and then
+// every interval.
+func (app *application) runScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		app.postRecurringSafely(now)
+		app.purgeTrashSafely(now)
+		<-ticker.C
+	}
+}
+
+// purgeTrashSafely removes the transactions that have been in the trash for
+// longer than models.TrashRetention, a panic is logged like in
+// postRecurringSafely.
+func (app *application) purgeTrashSafely(now time.Time) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.errorLog.Printf("trash purge panic: %v\n%s", err, debug.Stack())
+		}
+	}()
+
+	// NOTE: The attachments go first with the same time so their files are
+	// removed, when that fails the transactions wait for the next run.
+	before := now.Add(-models.TrashRetention)
+	_, err := app.attachments.PurgeDeleted(before)
+	if err != nil {
+		app.errorLog.Printf("trash purge: %v", err)
+		return
+	}
+
+	purged, err := app.transactions.PurgeDeleted(before)
+	if err != nil {
+		app.errorLog.Printf("trash purge: %v", err)
+	}
+	if purged > 0 {
+		app.infoLog.Printf("purged %d transactions from the trash", purged)
+	}
+}
+
+// postRecurringSafely keeps a panic in one run from taking the web process
+// down with it.
+func (app *application) postRecurringSafely(now time.Time) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.errorLog.Printf("recurring scheduler panic: %v\n%s", err, debug.Stack())
+		}
+	}()
+
+	posted, err := app.postRecurring(now)
+	if err != nil {
+		app.errorLog.Printf("recurring scheduler: %v", err)
+	}
+	if posted > 0 {
+		app.infoLog.Printf("posted %d recurring transactions", posted)
+	}
+}
+
+// postRecurring inserts a transaction for every occurrence due by now. A rule
+// that fails, e.g. for lack of funds, keeps the error and is retried on the
+// next run, its later occurrences wait so they are posted in order.
+func (app *application) postRecurring(now time.Time) (int, error) {
+	due, err := app.recurring.GetDue(now)
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	failed := map[int]bool{}
+	for _, o := range due {
+		rule := o.Rule
+		if failed[rule.ID] {
+			continue
+		}
+
+		_, err := app.transactions.Insert(models.TransactionCreateForm{
+			UserId:          rule.UserID,
+			AccountId:       rule.AccountID,
+			Date:            o.Date,
+			Amount:          rule.Amount,
+			Category:        rule.Category,
+			Description:     rule.Description,
+			Currency:        rule.Amount.Currency,
+			TransactionType: int(rule.TransactionType),
+			RecurringRuleID: rule.ID,
+		})
+		switch {
+		case errors.Is(err, models.ErrDuplicateOccurrence):
+			// NOTE: Posted by a run that overlapped with this one.
+			continue
+		case err != nil:
+			failed[rule.ID] = true
+			msg := fmt.Sprintf("%s: %v", o.Date.Format("2006-01-02"), err)
+			err = app.recurring.SetError(rule.ID, msg)
+			if err != nil {
+				return posted, err
+			}
+			continue
+		}
+
+		posted++
+		if rule.LastError != "" {
+			rule.LastError = ""
+			err = app.recurring.SetError(rule.ID, "")
+			if err != nil {
+				return posted, err
+			}
+		}
+	}
+
+	return posted, nil
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"fmt"
 	"html/template"
 	"io/fs"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/markaya/meinappf/internal/models"
@@ -14,20 +15,50 @@ import (
 
 // TODO: Add success and fail flash.
 type templateData struct {
-	CurrentYear         int
-	DateStringNow       string
-	Form                any
-	Flash               string
-	IsAuthenticated     bool
-	User                *models.User
-	Account             *models.Account
-	Accounts            []*models.Account
-	Categories          []string
-	UserTotalReport     services.TotalReport
-	GroupingReports     []*models.GroupingReport
-	DateFilter          map[string]time.Time
-	IncomeTransactions  []*models.Transaction
-	ExpenseTransactions []*models.Transaction
+	CurrentYear          int
+	DateStringNow        string
+	Form                 any
+	Flash                string
+	IsAuthenticated      bool
+	User                 *models.User
+	Account              *models.Account
+	Accounts             []*models.Account
+	ArchivedAccounts     []*models.Account
+	InconsistentAccounts []*models.Account // NOTE: Stored balance disagrees with the ledger.
+	Transaction          *models.Transaction
+	Categories           []*models.Category
+	Currencies           []*models.CurrencyInfo
+	ExchangeRates        []*models.ExchangeRate
+	UserTotalReport      services.TotalReport
+	CategoryRollups      []*services.CategoryRollup
+	TagReports           []*models.TagReport
+	Payees               []*models.Payee
+	RecurringRules       []*models.RecurringRule
+	Budgets              []*services.BudgetProgress
+	Goals                []*services.GoalProgress
+	NetWorth             []*services.NetWorth
+	Counterparty         *models.Counterparty
+	Counterparties       []*models.Counterparty
+	Loan                 *models.PersonalLoan
+	Loans                []*models.PersonalLoan
+	LoanBalances         []*services.LoanBalance
+	LoanTerms            *models.LoanTerms
+	Household            *models.Household
+	Households           []*models.Household
+	Members              map[int]string // NOTE: Names of the other household members, by user id.
+	Month                time.Time
+	Today                time.Time // NOTE: For overdue loans.
+	PayeeReports         []*models.PayeeReport
+	DateFilter           map[string]time.Time
+	TagFilter            string
+	Tags                 []string
+	IncomeTransactions   []*models.Transaction
+	ExpenseTransactions  []*models.Transaction
+	Transactions         []*models.Transaction
+	Transfer             *models.Transfer
+	Transfers            []*models.Transfer
+	SearchResults        []*models.SearchResult
+	SearchText           string // NOTE: Fills the search box of the header.
 }
 
 func (t *templateData) WithDefaultDateFilter() {
@@ -41,35 +72,6 @@ func (t *templateData) WithDefaultDateFilter() {
 	t.DateFilter = filterMap
 }
 
-func (t *templateData) DefaultIncomeCategories() {
-	t.Categories = []string{
-		"publicis",
-		"rent",
-		"parents",
-		"other",
-	}
-}
-func (t *templateData) DefaultExpenseCategories() {
-	t.Categories = []string{
-		"restaurant",
-		"groceries",
-		"home",
-		"cat",
-		"bills",
-		"gym",
-		"daki",
-		"clothes",
-		"commute",
-		"health",
-		"luxury",
-		"other",
-	}
-}
-
-func formatFloat(f float64) string {
-	return fmt.Sprintf("%.2f", f)
-}
-
 func humanDate(t time.Time) string {
 	if t.IsZero() {
 		return ""
@@ -84,10 +86,30 @@ func htmlDate(t time.Time) string {
 	return t.Format("2006-01-02")
 }
 
+func joinTags(tags []string) string {
+	return strings.Join(tags, ", ")
+}
+
+// splitRows pads the split lines of a form with blank rows up to
+// models.MaxSplits.
+func splitRows(splits []models.Split) []models.Split {
+	rows := slices.Clone(splits)
+	for len(rows) < models.MaxSplits {
+		rows = append(rows, models.Split{})
+	}
+	return rows
+}
+
 var functions = template.FuncMap{
-	"humanDate":   humanDate,
-	"htmlDate":    htmlDate,
-	"formatFloat": formatFloat,
+	"humanDate": humanDate,
+	"htmlDate":  htmlDate,
+	"joinTags":  joinTags,
+	"splitRows": splitRows,
+	"schedule":  describeSchedule,
+	// NOTE: The account types are the same for every page, no need to pass
+	// them around in templateData.
+	"accountTypes": func() []models.AccountType { return models.AccountTypes },
+	"roles":        func() []models.Role { return models.Roles },
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
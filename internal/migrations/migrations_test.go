@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/markaya/meinappf/internal/assert"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_foreign_keys=on"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	db := newTestDB(t)
+	m := &Migrator{DB: db}
+
+	all, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(applied), len(all))
+
+	applied, err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(applied), 0)
+
+	rolledBack, err := m.Down(len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(rolledBack), len(all))
+
+	applied, err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(applied), len(all))
+}
+
+func TestMigratorStatus(t *testing.T) {
+	db := newTestDB(t)
+	m := &Migrator{
+		DB: db,
+		Files: fstest.MapFS{
+			"0001_first.up.sql":    {Data: []byte("CREATE TABLE first (id INTEGER);")},
+			"0001_first.down.sql":  {Data: []byte("DROP TABLE first;")},
+			"0002_second.up.sql":   {Data: []byte("CREATE TABLE second (id INTEGER);")},
+			"0002_second.down.sql": {Data: []byte("DROP TABLE second;")},
+		},
+	}
+
+	_, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rolledBack, err := m.Down(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(rolledBack), 1)
+	assert.Equal(t, rolledBack[0].Name, "second")
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(statuses), 2)
+	assert.Equal(t, statuses[0].Applied, true)
+	assert.Equal(t, statuses[1].Applied, false)
+}
+
+func TestMigratorFailedMigrationIsRolledBack(t *testing.T) {
+	db := newTestDB(t)
+	m := &Migrator{
+		DB: db,
+		Files: fstest.MapFS{
+			"0001_broken.up.sql": {Data: []byte("CREATE TABLE broken (id INTEGER); INSERT INTO missing VALUES (1);")},
+		},
+	}
+
+	_, err := m.Up()
+	if err == nil {
+		t.Fatal("expected error applying broken migration")
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'broken'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, count, 0)
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, statuses[0].Applied, false)
+}
@@ -0,0 +1,41 @@
+-- NOTE: IF NOT EXISTS so that databases created before migrations were
+-- introduced are adopted as-is and only get recorded in schema_migrations.
+CREATE TABLE IF NOT EXISTS users (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    name            TEXT NOT NULL,
+    email           TEXT NOT NULL UNIQUE,
+    hashed_password TEXT NOT NULL,
+    created         DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS accounts (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id),
+    account_name TEXT NOT NULL,
+    balance      REAL NOT NULL DEFAULT 0,
+    currency     INTEGER NOT NULL,
+    UNIQUE (user_id, account_name)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id       INTEGER NOT NULL REFERENCES accounts (id),
+    user_id          INTEGER NOT NULL REFERENCES users (id),
+    date             DATETIME NOT NULL,
+    amount           REAL NOT NULL,
+    currency         INTEGER NOT NULL,
+    category         TEXT NOT NULL,
+    description      TEXT NOT NULL DEFAULT '',
+    transaction_type INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_user_date_idx ON transactions (user_id, date);
+
+-- NOTE: Schema expected by github.com/alexedwards/scs/sqlite3store
+CREATE TABLE IF NOT EXISTS sessions (
+    token  TEXT PRIMARY KEY,
+    data   BLOB NOT NULL,
+    expiry REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions (expiry);
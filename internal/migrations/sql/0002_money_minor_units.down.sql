@@ -0,0 +1,9 @@
+ALTER TABLE transactions ADD COLUMN amount_real REAL NOT NULL DEFAULT 0;
+UPDATE transactions SET amount_real = amount / 100.0;
+ALTER TABLE transactions DROP COLUMN amount;
+ALTER TABLE transactions RENAME COLUMN amount_real TO amount;
+
+ALTER TABLE accounts ADD COLUMN balance_real REAL NOT NULL DEFAULT 0;
+UPDATE accounts SET balance_real = balance / 100.0;
+ALTER TABLE accounts DROP COLUMN balance;
+ALTER TABLE accounts RENAME COLUMN balance_real TO balance;
@@ -0,0 +1,11 @@
+-- NOTE: Money is stored as INTEGER minor units (cents/paras). Every currency
+-- in use at this point has two minor unit digits.
+ALTER TABLE transactions ADD COLUMN amount_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE transactions SET amount_minor = CAST(ROUND(amount * 100) AS INTEGER);
+ALTER TABLE transactions DROP COLUMN amount;
+ALTER TABLE transactions RENAME COLUMN amount_minor TO amount;
+
+ALTER TABLE accounts ADD COLUMN balance_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE accounts SET balance_minor = CAST(ROUND(balance * 100) AS INTEGER);
+ALTER TABLE accounts DROP COLUMN balance;
+ALTER TABLE accounts RENAME COLUMN balance_minor TO balance;
@@ -0,0 +1,14 @@
+-- NOTE: Fails on the NOT NULL constraint when anything other than RSD or EUR
+-- is in use, the old enum cannot represent it.
+ALTER TABLE transactions ADD COLUMN currency_enum INTEGER NOT NULL DEFAULT 0;
+UPDATE transactions SET currency_enum = CASE currency WHEN 'RSD' THEN 0 WHEN 'EUR' THEN 1 END;
+ALTER TABLE transactions DROP COLUMN currency;
+ALTER TABLE transactions RENAME COLUMN currency_enum TO currency;
+
+ALTER TABLE accounts ADD COLUMN currency_enum INTEGER NOT NULL DEFAULT 0;
+UPDATE accounts SET currency_enum = CASE currency WHEN 'RSD' THEN 0 WHEN 'EUR' THEN 1 END;
+ALTER TABLE accounts DROP COLUMN currency;
+ALTER TABLE accounts RENAME COLUMN currency_enum TO currency;
+
+DROP TABLE user_currencies;
+DROP TABLE currencies;
@@ -0,0 +1,55 @@
+CREATE TABLE currencies (
+    code        TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    symbol      TEXT NOT NULL,
+    minor_units INTEGER NOT NULL
+);
+
+INSERT INTO currencies (code, name, symbol, minor_units) VALUES
+    ('AUD', 'Australian Dollar', 'A$', 2),
+    ('BAM', 'Convertible Mark', 'KM', 2),
+    ('BGN', 'Bulgarian Lev', 'лв', 2),
+    ('CAD', 'Canadian Dollar', 'C$', 2),
+    ('CHF', 'Swiss Franc', 'CHF', 2),
+    ('CNY', 'Yuan Renminbi', 'CN¥', 2),
+    ('CZK', 'Czech Koruna', 'Kč', 2),
+    ('DKK', 'Danish Krone', 'kr.', 2),
+    ('EUR', 'Euro', '€', 2),
+    ('GBP', 'Pound Sterling', '£', 2),
+    ('HUF', 'Forint', 'Ft', 2),
+    ('ISK', 'Iceland Krona', 'kr', 0),
+    ('JPY', 'Yen', '¥', 0),
+    ('KRW', 'Won', '₩', 0),
+    ('MKD', 'Denar', 'ден', 2),
+    ('NOK', 'Norwegian Krone', 'kr', 2),
+    ('PLN', 'Zloty', 'zł', 2),
+    ('RON', 'Romanian Leu', 'lei', 2),
+    ('RSD', 'Serbian Dinar', 'RSD', 2),
+    ('RUB', 'Russian Ruble', '₽', 2),
+    ('SEK', 'Swedish Krona', 'kr', 2),
+    ('TRY', 'Turkish Lira', '₺', 2),
+    ('USD', 'US Dollar', '$', 2);
+
+CREATE TABLE user_currencies (
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    code    TEXT NOT NULL REFERENCES currencies (code),
+    PRIMARY KEY (user_id, code)
+);
+
+-- NOTE: Existing users keep the two currencies that used to be hard-coded.
+INSERT INTO user_currencies (user_id, code)
+SELECT id, 'RSD' FROM users
+UNION ALL
+SELECT id, 'EUR' FROM users;
+
+-- NOTE: Currency columns switch from the old iota enum (0 = RSD, 1 = EUR) to
+-- ISO 4217 codes.
+ALTER TABLE accounts ADD COLUMN currency_code TEXT NOT NULL DEFAULT '';
+UPDATE accounts SET currency_code = CASE currency WHEN 0 THEN 'RSD' WHEN 1 THEN 'EUR' END;
+ALTER TABLE accounts DROP COLUMN currency;
+ALTER TABLE accounts RENAME COLUMN currency_code TO currency;
+
+ALTER TABLE transactions ADD COLUMN currency_code TEXT NOT NULL DEFAULT '';
+UPDATE transactions SET currency_code = CASE currency WHEN 0 THEN 'RSD' WHEN 1 THEN 'EUR' END;
+ALTER TABLE transactions DROP COLUMN currency;
+ALTER TABLE transactions RENAME COLUMN currency_code TO currency;
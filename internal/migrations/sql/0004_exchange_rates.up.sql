@@ -0,0 +1,10 @@
+-- NOTE: One unit of from_currency is worth `rate` units of to_currency on date.
+CREATE TABLE exchange_rates (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_currency TEXT NOT NULL REFERENCES currencies (code),
+    to_currency   TEXT NOT NULL REFERENCES currencies (code),
+    date          DATE NOT NULL,
+    rate          REAL NOT NULL CHECK (rate > 0),
+    source        TEXT NOT NULL DEFAULT 'manual',
+    UNIQUE (from_currency, to_currency, date)
+);
@@ -0,0 +1 @@
+DROP VIEW account_balances;
@@ -0,0 +1,21 @@
+-- NOTE: The ledger is the source of truth, accounts.balance is only a cache
+-- kept in step by TransactionModel and checked against this view.
+-- transaction_type: 0 IN, 1 EX, 2 TIN (transfer source), 3 TOUT (transfer
+-- destination), 4 RIN, 5 ROUT.
+CREATE VIEW account_balances AS
+SELECT
+    a.id AS account_id,
+    COALESCE(SUM(
+        CASE t.transaction_type
+            WHEN 0 THEN t.amount
+            WHEN 1 THEN -t.amount
+            WHEN 2 THEN -t.amount
+            WHEN 3 THEN t.amount
+            WHEN 4 THEN t.amount
+            WHEN 5 THEN -t.amount
+            ELSE 0
+        END
+    ), 0) AS balance
+FROM accounts a
+LEFT JOIN transactions t ON t.account_id = a.id
+GROUP BY a.id;
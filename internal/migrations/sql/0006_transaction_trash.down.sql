@@ -0,0 +1,22 @@
+DROP VIEW account_balances;
+DELETE FROM transactions WHERE deleted_at IS NOT NULL;
+DROP INDEX transactions_deleted_at_idx;
+ALTER TABLE transactions DROP COLUMN deleted_at;
+
+CREATE VIEW account_balances AS
+SELECT
+    a.id AS account_id,
+    COALESCE(SUM(
+        CASE t.transaction_type
+            WHEN 0 THEN t.amount
+            WHEN 1 THEN -t.amount
+            WHEN 2 THEN -t.amount
+            WHEN 3 THEN t.amount
+            WHEN 4 THEN t.amount
+            WHEN 5 THEN -t.amount
+            ELSE 0
+        END
+    ), 0) AS balance
+FROM accounts a
+LEFT JOIN transactions t ON t.account_id = a.id
+GROUP BY a.id;
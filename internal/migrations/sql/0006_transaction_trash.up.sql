@@ -0,0 +1,23 @@
+-- NOTE: Deleted transactions stay in the trash for 30 days before they are
+-- purged, they no longer count towards the account balance.
+ALTER TABLE transactions ADD COLUMN deleted_at DATETIME;
+CREATE INDEX transactions_deleted_at_idx ON transactions (user_id, deleted_at);
+
+DROP VIEW account_balances;
+CREATE VIEW account_balances AS
+SELECT
+    a.id AS account_id,
+    COALESCE(SUM(
+        CASE t.transaction_type
+            WHEN 0 THEN t.amount
+            WHEN 1 THEN -t.amount
+            WHEN 2 THEN -t.amount
+            WHEN 3 THEN t.amount
+            WHEN 4 THEN t.amount
+            WHEN 5 THEN -t.amount
+            ELSE 0
+        END
+    ), 0) AS balance
+FROM accounts a
+LEFT JOIN transactions t ON t.account_id = a.id AND t.deleted_at IS NULL
+GROUP BY a.id;
@@ -0,0 +1,9 @@
+-- NOTE: Fee legs become plain expenses, deleted transfers are dropped.
+DELETE FROM transactions
+WHERE transfer_id IN (SELECT id FROM transfers WHERE deleted_at IS NOT NULL);
+
+DROP INDEX transactions_transfer_id_idx;
+ALTER TABLE transactions DROP COLUMN transfer_id;
+
+DROP INDEX transfers_user_date_idx;
+DROP TABLE transfers;
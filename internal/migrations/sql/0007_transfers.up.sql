@@ -0,0 +1,42 @@
+-- NOTE: A transfer groups its legs: the TIN row on the source account, the
+-- TOUT row on the destination account and an optional fee expense on the
+-- source account.
+CREATE TABLE transfers (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    date       DATETIME NOT NULL,
+    rate       REAL NOT NULL DEFAULT 1 CHECK (rate > 0),
+    rate_date  DATE,
+    deleted_at DATETIME
+);
+CREATE INDEX transfers_user_date_idx ON transfers (user_id, date);
+
+ALTER TABLE transactions ADD COLUMN transfer_id INTEGER REFERENCES transfers (id);
+CREATE INDEX transactions_transfer_id_idx ON transactions (transfer_id);
+
+-- NOTE: Until now InsertTransfer wrote the TIN leg immediately followed by the
+-- TOUT leg, both with the same "[T] from X to Y" description. The transfer
+-- reuses the id of its TIN leg.
+INSERT INTO transfers (id, user_id, date, rate)
+SELECT
+    tin.id,
+    tin.user_id,
+    tin.date,
+    (CAST(tout.amount AS REAL) / CASE cout.minor_units WHEN 0 THEN 1 WHEN 3 THEN 1000 ELSE 100 END)
+        / (CAST(tin.amount AS REAL) / CASE cin.minor_units WHEN 0 THEN 1 WHEN 3 THEN 1000 ELSE 100 END)
+FROM transactions tin
+JOIN transactions tout ON tout.id = tin.id + 1
+    AND tout.user_id = tin.user_id
+    AND tout.description = tin.description
+    AND tout.transaction_type = 3
+LEFT JOIN currencies cin ON cin.code = tin.currency
+LEFT JOIN currencies cout ON cout.code = tout.currency
+WHERE tin.transaction_type = 2
+AND tin.amount > 0
+AND tout.amount > 0;
+
+UPDATE transactions SET transfer_id = id
+WHERE transaction_type = 2 AND id IN (SELECT id FROM transfers);
+
+UPDATE transactions SET transfer_id = id - 1
+WHERE transaction_type = 3 AND id - 1 IN (SELECT id FROM transfers);
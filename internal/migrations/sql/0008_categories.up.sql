@@ -0,0 +1,28 @@
+-- NOTE: transactions.category keeps the category name, renaming or merging a
+-- category rewrites the transactions that use it.
+CREATE TABLE categories (
+    id               INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER NOT NULL REFERENCES users (id),
+    name             TEXT NOT NULL,
+    transaction_type INTEGER NOT NULL,
+    archived         BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (user_id, transaction_type, name)
+);
+
+-- NOTE: Existing users keep the categories that used to be hard-coded and
+-- every category they have used.
+WITH defaults (transaction_type, name) AS (
+    VALUES
+        (0, 'publicis'), (0, 'rent'), (0, 'parents'), (0, 'other'),
+        (1, 'restaurant'), (1, 'groceries'), (1, 'home'), (1, 'cat'),
+        (1, 'bills'), (1, 'gym'), (1, 'daki'), (1, 'clothes'),
+        (1, 'commute'), (1, 'health'), (1, 'luxury'), (1, 'other')
+)
+INSERT OR IGNORE INTO categories (user_id, name, transaction_type)
+SELECT u.id, d.name, d.transaction_type FROM users u, defaults d
+UNION ALL
+SELECT DISTINCT user_id, category, transaction_type
+FROM transactions
+WHERE transaction_type IN (0, 1)
+AND transfer_id IS NULL
+AND category <> '';
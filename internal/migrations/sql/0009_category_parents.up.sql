@@ -0,0 +1,4 @@
+-- NOTE: A category and its parent always have the same transaction type, top
+-- level categories have no parent.
+ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories (id);
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
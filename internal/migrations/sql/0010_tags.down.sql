@@ -0,0 +1,2 @@
+DROP TABLE transaction_tags;
+DROP TABLE tags;
@@ -0,0 +1,14 @@
+CREATE TABLE tags (
+    id      INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    name    TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- NOTE: Links go away with the transaction when the trash is purged.
+CREATE TABLE transaction_tags (
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    tag_id         INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (transaction_id, tag_id)
+);
+CREATE INDEX transaction_tags_tag_id_idx ON transaction_tags (tag_id);
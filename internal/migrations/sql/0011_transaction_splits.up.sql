@@ -0,0 +1,11 @@
+-- NOTE: A transaction without split lines is a single line of its own
+-- category and amount. With split lines transactions.category holds the
+-- category of the first line.
+CREATE TABLE transaction_splits (
+    id             INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    category       TEXT NOT NULL,
+    amount         INTEGER NOT NULL CHECK (amount > 0),
+    description    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX transaction_splits_transaction_id_idx ON transaction_splits (transaction_id);
@@ -0,0 +1,5 @@
+DROP INDEX transactions_payee_id_idx;
+ALTER TABLE transactions DROP COLUMN payee_id;
+
+DROP TABLE payee_aliases;
+DROP TABLE payees;
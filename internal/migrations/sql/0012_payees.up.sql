@@ -0,0 +1,21 @@
+-- NOTE: default_category is blank or the name of one of the user's expense
+-- categories, the same way transactions refer to categories.
+CREATE TABLE payees (
+    id               INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER NOT NULL REFERENCES users (id),
+    name             TEXT NOT NULL,
+    default_category TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, name)
+);
+
+-- NOTE: Patterns are stored normalized, lower case with single spaces, and
+-- match any description containing them.
+CREATE TABLE payee_aliases (
+    id       INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    payee_id INTEGER NOT NULL REFERENCES payees (id) ON DELETE CASCADE,
+    pattern  TEXT NOT NULL,
+    UNIQUE (payee_id, pattern)
+);
+
+ALTER TABLE transactions ADD COLUMN payee_id INTEGER REFERENCES payees (id) ON DELETE SET NULL;
+CREATE INDEX transactions_payee_id_idx ON transactions (payee_id);
@@ -0,0 +1,32 @@
+-- NOTE: day is the day of the month for monthly rules, weekly and yearly
+-- rules repeat on the weekday and date of start_date. Months shorter than day
+-- use their last day.
+CREATE TABLE recurring_rules (
+    id               INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER NOT NULL REFERENCES users (id),
+    account_id       INTEGER NOT NULL REFERENCES accounts (id),
+    transaction_type INTEGER NOT NULL CHECK (transaction_type IN (0, 1)),
+    amount           INTEGER NOT NULL CHECK (amount > 0),
+    category         TEXT NOT NULL,
+    description      TEXT NOT NULL DEFAULT '',
+    frequency        TEXT NOT NULL CHECK (frequency IN ('weekly', 'monthly', 'yearly')),
+    interval         INTEGER NOT NULL DEFAULT 1 CHECK (interval >= 1),
+    day              INTEGER NOT NULL DEFAULT 1 CHECK (day BETWEEN 1 AND 31),
+    start_date       DATETIME NOT NULL,
+    end_date         DATETIME,
+    paused           BOOLEAN NOT NULL DEFAULT FALSE,
+    last_error       TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX recurring_rules_user_id_idx ON recurring_rules (user_id);
+
+-- NOTE: One row per posted occurrence, written in the same database
+-- transaction as the transaction itself. The primary key is what keeps a
+-- restart from posting an occurrence twice. Occurrences stay when their
+-- transaction is deleted so they are not posted again. occurrence_date is
+-- plain YYYY-MM-DD text so MAX() and equality work on it.
+CREATE TABLE recurring_occurrences (
+    rule_id         INTEGER NOT NULL REFERENCES recurring_rules (id) ON DELETE CASCADE,
+    occurrence_date TEXT NOT NULL,
+    transaction_id  INTEGER REFERENCES transactions (id) ON DELETE SET NULL,
+    PRIMARY KEY (rule_id, occurrence_date)
+);
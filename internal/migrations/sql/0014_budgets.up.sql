@@ -0,0 +1,15 @@
+-- NOTE: One row per expense category, month and currency, month is plain
+-- YYYY-MM text. A budget of a parent category covers its sub-categories.
+-- With rollover set, what was left of the previous month's budget of the
+-- same category and currency is added to this month's.
+CREATE TABLE budgets (
+    id          INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL REFERENCES users (id),
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    month       TEXT NOT NULL,
+    amount      INTEGER NOT NULL CHECK (amount >= 0),
+    currency    TEXT NOT NULL,
+    rollover    BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (user_id, category_id, month, currency)
+);
+CREATE INDEX budgets_user_month_idx ON budgets (user_id, month);
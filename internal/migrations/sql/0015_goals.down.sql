@@ -0,0 +1,2 @@
+DROP TABLE goal_accounts;
+DROP TABLE goals;
@@ -0,0 +1,20 @@
+-- NOTE: A goal is funded by the balances of its accounts, which hold the
+-- goal currency, and by the transfers tagged with tag into other accounts.
+-- tag is the plain tag name, tags are only created once they are used.
+CREATE TABLE goals (
+    id       INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id  INTEGER NOT NULL REFERENCES users (id),
+    name     TEXT NOT NULL,
+    target   INTEGER NOT NULL CHECK (target > 0),
+    currency TEXT NOT NULL,
+    deadline DATETIME,
+    tag      TEXT NOT NULL DEFAULT '',
+    created  DATETIME NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE goal_accounts (
+    goal_id    INTEGER NOT NULL REFERENCES goals (id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    PRIMARY KEY (goal_id, account_id)
+);
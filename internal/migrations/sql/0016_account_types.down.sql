@@ -0,0 +1,2 @@
+ALTER TABLE accounts DROP COLUMN credit_limit;
+ALTER TABLE accounts DROP COLUMN account_type;
@@ -0,0 +1,6 @@
+-- NOTE: credit_limit is how far below zero the balance may go, only checking
+-- (overdraft), credit card and loan accounts can have one. Existing accounts
+-- become checking accounts without an overdraft.
+ALTER TABLE accounts ADD COLUMN account_type TEXT NOT NULL DEFAULT 'checking'
+    CHECK (account_type IN ('checking', 'cash', 'savings', 'credit', 'loan', 'investment'));
+ALTER TABLE accounts ADD COLUMN credit_limit INTEGER NOT NULL DEFAULT 0 CHECK (credit_limit >= 0);
@@ -0,0 +1,5 @@
+-- NOTE: Archived accounts are left out of the forms but stay in history and
+-- reports. A closed account had a zero balance when it was closed and takes
+-- no more transactions.
+ALTER TABLE accounts ADD COLUMN archived_at DATETIME;
+ALTER TABLE accounts ADD COLUMN closed_at DATETIME;
@@ -0,0 +1,29 @@
+-- NOTE: Loan rows become plain incomes and expenses so balances stay the same.
+UPDATE transactions SET transaction_type = 1, category = 'other' WHERE transaction_type = 6;
+UPDATE transactions SET transaction_type = 0, category = 'other' WHERE transaction_type = 7;
+
+DROP VIEW account_balances;
+CREATE VIEW account_balances AS
+SELECT
+    a.id AS account_id,
+    COALESCE(SUM(
+        CASE t.transaction_type
+            WHEN 0 THEN t.amount
+            WHEN 1 THEN -t.amount
+            WHEN 2 THEN -t.amount
+            WHEN 3 THEN t.amount
+            WHEN 4 THEN t.amount
+            WHEN 5 THEN -t.amount
+            ELSE 0
+        END
+    ), 0) AS balance
+FROM accounts a
+LEFT JOIN transactions t ON t.account_id = a.id AND t.deleted_at IS NULL
+GROUP BY a.id;
+
+DROP INDEX transactions_loan_id_idx;
+ALTER TABLE transactions DROP COLUMN loan_id;
+
+DROP INDEX loans_counterparty_id_idx;
+DROP TABLE loans;
+DROP TABLE counterparties;
@@ -0,0 +1,50 @@
+-- NOTE: Counterparties are the people money is lent to or borrowed from. A
+-- lent loan is paid out with a LOUT row and paid back with LIN rows, a
+-- borrowed one the other way around.
+CREATE TABLE counterparties (
+    id      INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    name    TEXT NOT NULL,
+    note    TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE loans (
+    id              INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER NOT NULL REFERENCES users (id),
+    counterparty_id INTEGER NOT NULL REFERENCES counterparties (id),
+    direction       TEXT NOT NULL CHECK (direction IN ('lent', 'borrowed')),
+    principal       INTEGER NOT NULL CHECK (principal > 0),
+    currency        TEXT NOT NULL,
+    date            DATETIME NOT NULL,
+    due_date        DATETIME,
+    description     TEXT NOT NULL DEFAULT '',
+    created         DATETIME NOT NULL
+);
+CREATE INDEX loans_counterparty_id_idx ON loans (counterparty_id);
+
+ALTER TABLE transactions ADD COLUMN loan_id INTEGER REFERENCES loans (id);
+CREATE INDEX transactions_loan_id_idx ON transactions (loan_id);
+
+-- transaction_type: 6 LOUT (money lent or paid back), 7 LIN (money borrowed
+-- or received back).
+DROP VIEW account_balances;
+CREATE VIEW account_balances AS
+SELECT
+    a.id AS account_id,
+    COALESCE(SUM(
+        CASE t.transaction_type
+            WHEN 0 THEN t.amount
+            WHEN 1 THEN -t.amount
+            WHEN 2 THEN -t.amount
+            WHEN 3 THEN t.amount
+            WHEN 4 THEN t.amount
+            WHEN 5 THEN -t.amount
+            WHEN 6 THEN -t.amount
+            WHEN 7 THEN t.amount
+            ELSE 0
+        END
+    ), 0) AS balance
+FROM accounts a
+LEFT JOIN transactions t ON t.account_id = a.id AND t.deleted_at IS NULL
+GROUP BY a.id;
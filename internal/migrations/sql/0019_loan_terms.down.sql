@@ -0,0 +1,2 @@
+DROP TABLE loan_payments;
+DROP TABLE loan_terms;
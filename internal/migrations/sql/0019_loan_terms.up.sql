@@ -0,0 +1,21 @@
+-- NOTE: Terms of loan accounts paid back in equal monthly installments, rate
+-- is the yearly interest rate in percent. Installments fall on the day of
+-- first_payment, or the last day of shorter months.
+CREATE TABLE loan_terms (
+    account_id        INTEGER NOT NULL PRIMARY KEY REFERENCES accounts (id) ON DELETE CASCADE,
+    principal         INTEGER NOT NULL CHECK (principal > 0),
+    rate              REAL NOT NULL CHECK (rate >= 0),
+    months            INTEGER NOT NULL CHECK (months > 0),
+    first_payment     DATETIME NOT NULL,
+    interest_category TEXT NOT NULL
+);
+
+-- NOTE: An installment counts as paid while its transfer is not deleted, the
+-- interest is a plain expense on the paying account.
+CREATE TABLE loan_payments (
+    account_id  INTEGER NOT NULL REFERENCES loan_terms (account_id) ON DELETE CASCADE,
+    number      INTEGER NOT NULL,
+    transfer_id INTEGER REFERENCES transfers (id) ON DELETE SET NULL,
+    interest_id INTEGER REFERENCES transactions (id) ON DELETE SET NULL,
+    PRIMARY KEY (account_id, number)
+);
@@ -0,0 +1,8 @@
+DROP VIEW account_access;
+
+DROP INDEX accounts_household_id_idx;
+ALTER TABLE accounts DROP COLUMN household_id;
+
+DROP INDEX household_members_user_id_idx;
+DROP TABLE household_members;
+DROP TABLE households;
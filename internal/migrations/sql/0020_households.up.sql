@@ -0,0 +1,39 @@
+-- NOTE: A household is a book shared by several users. Members are invited
+-- by an owner and join once they accept, accepted_at stays NULL until then.
+-- Owners manage the members, editors add transactions to the shared
+-- accounts and viewers only see them.
+CREATE TABLE households (
+    id      INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name    TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
+
+CREATE TABLE household_members (
+    household_id INTEGER NOT NULL REFERENCES households (id) ON DELETE CASCADE,
+    user_id      INTEGER NOT NULL REFERENCES users (id),
+    role         TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    invited_at   DATETIME NOT NULL,
+    accepted_at  DATETIME,
+    PRIMARY KEY (household_id, user_id)
+);
+CREATE INDEX household_members_user_id_idx ON household_members (user_id);
+
+-- NOTE: An account is shared with at most one household. The user_id of a
+-- transaction is the member who created it, which is not always the owner of
+-- the account.
+ALTER TABLE accounts ADD COLUMN household_id INTEGER REFERENCES households (id) ON DELETE SET NULL;
+CREATE INDEX accounts_household_id_idx ON accounts (household_id);
+
+-- NOTE: The role of every user that can see an account. Owners of the
+-- account get 'owner', members of the household it is shared with get
+-- 'editor' or 'viewer'; a household owner does not own the accounts of
+-- others.
+CREATE VIEW account_access AS
+SELECT a.id AS account_id, a.user_id, 'owner' AS role
+FROM accounts a
+UNION ALL
+SELECT a.id, hm.user_id, CASE hm.role WHEN 'viewer' THEN 'viewer' ELSE 'editor' END
+FROM accounts a
+JOIN household_members hm ON hm.household_id = a.household_id
+WHERE hm.accepted_at IS NOT NULL
+AND hm.user_id != a.user_id;
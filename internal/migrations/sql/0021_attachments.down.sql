@@ -0,0 +1,2 @@
+DROP INDEX attachments_transaction_id_idx;
+DROP TABLE attachments;
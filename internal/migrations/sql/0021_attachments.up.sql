@@ -0,0 +1,17 @@
+-- NOTE: Receipts and documents of a transaction. The file is kept in data,
+-- or in the attachment directory under path when the server is started with
+-- one; exactly one of the two is set. Images get a small JPEG thumbnail.
+CREATE TABLE attachments (
+    id             INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    user_id        INTEGER NOT NULL REFERENCES users (id),
+    filename       TEXT NOT NULL,
+    content_type   TEXT NOT NULL,
+    size           INTEGER NOT NULL,
+    data           BLOB,
+    path           TEXT,
+    thumbnail      BLOB,
+    created        DATETIME NOT NULL,
+    CHECK ((data IS NULL) != (path IS NULL))
+);
+CREATE INDEX attachments_transaction_id_idx ON attachments (transaction_id);
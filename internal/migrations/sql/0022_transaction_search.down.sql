@@ -0,0 +1,13 @@
+-- NOTE: The full-text index and the triggers keeping it current are created
+-- by models.SearchModel.Init in builds with FTS5, see search_fts5.go.
+DROP TRIGGER IF EXISTS transaction_search_ai;
+DROP TRIGGER IF EXISTS transaction_search_au;
+DROP TRIGGER IF EXISTS transaction_search_ad;
+DROP TRIGGER IF EXISTS transaction_search_tags_ai;
+DROP TRIGGER IF EXISTS transaction_search_tags_ad;
+DROP TRIGGER IF EXISTS transaction_search_splits_ai;
+DROP TRIGGER IF EXISTS transaction_search_splits_au;
+DROP TRIGGER IF EXISTS transaction_search_splits_ad;
+DROP TRIGGER IF EXISTS transaction_search_payees_au;
+DROP TABLE IF EXISTS transaction_search;
+DROP VIEW transaction_search_source;
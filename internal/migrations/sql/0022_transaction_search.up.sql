@@ -0,0 +1,21 @@
+-- NOTE: The searchable text of every transaction, split lines included. The
+-- full-text index of builds with FTS5 is filled from this view, see
+-- models.SearchModel.Init.
+CREATE VIEW transaction_search_source AS
+SELECT
+    t.id AS transaction_id,
+    trim(t.description || ' ' || COALESCE((
+        SELECT group_concat(s.description, ' ') FROM transaction_splits s WHERE s.transaction_id = t.id
+    ), '')) AS description,
+    trim(t.category || ' ' || COALESCE((
+        SELECT group_concat(s.category, ' ') FROM transaction_splits s WHERE s.transaction_id = t.id
+    ), '')) AS category,
+    COALESCE(p.name, '') AS payee,
+    COALESCE((
+        SELECT group_concat(tg.name, ' ')
+        FROM transaction_tags tt
+        JOIN tags tg ON tg.id = tt.tag_id
+        WHERE tt.transaction_id = t.id
+    ), '') AS tags
+FROM transactions t
+LEFT JOIN payees p ON p.id = t.payee_id;
@@ -0,0 +1,17 @@
+-- NOTE: A rate of a user survives only where no shared rate exists for the
+-- same pair and day.
+CREATE TABLE exchange_rates_shared (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_currency TEXT NOT NULL REFERENCES currencies (code),
+    to_currency   TEXT NOT NULL REFERENCES currencies (code),
+    date          DATE NOT NULL,
+    rate          REAL NOT NULL CHECK (rate > 0),
+    source        TEXT NOT NULL DEFAULT 'manual',
+    UNIQUE (from_currency, to_currency, date)
+);
+INSERT OR IGNORE INTO exchange_rates_shared (id, from_currency, to_currency, date, rate, source)
+SELECT id, from_currency, to_currency, date, rate, source FROM exchange_rates
+ORDER BY user_id IS NOT NULL, id;
+DROP INDEX exchange_rates_owner_pair_date_idx;
+DROP TABLE exchange_rates;
+ALTER TABLE exchange_rates_shared RENAME TO exchange_rates;
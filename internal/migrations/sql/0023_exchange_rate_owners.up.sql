@@ -0,0 +1,20 @@
+-- NOTE: Rates entered or uploaded on the web belong to the user that entered
+-- them and are only used for that user. Rates with a NULL user_id are shared
+-- by everyone and only come in through the `rates import` command. The rates
+-- stored so far stay shared.
+CREATE TABLE exchange_rates_owned (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       INTEGER REFERENCES users (id),
+    from_currency TEXT NOT NULL REFERENCES currencies (code),
+    to_currency   TEXT NOT NULL REFERENCES currencies (code),
+    date          DATE NOT NULL,
+    rate          REAL NOT NULL CHECK (rate > 0),
+    source        TEXT NOT NULL DEFAULT 'manual'
+);
+INSERT INTO exchange_rates_owned (id, from_currency, to_currency, date, rate, source)
+SELECT id, from_currency, to_currency, date, rate, source FROM exchange_rates;
+DROP TABLE exchange_rates;
+ALTER TABLE exchange_rates_owned RENAME TO exchange_rates;
+
+CREATE UNIQUE INDEX exchange_rates_owner_pair_date_idx
+ON exchange_rates (IFNULL(user_id, 0), from_currency, to_currency, date);
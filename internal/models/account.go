@@ -3,23 +3,62 @@ package models
 import (
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
 
 type AccountModelInterface interface {
-	Insert(userID int, accountName string, currency Currency) (int, error)
+	Insert(userID int, accountName string, currency Currency, accountType AccountType, creditLimit Money) (int, error)
 	Get(userId, id int) (*Account, error)
+	SetType(userId, id int, accountType AccountType, creditLimit Money) error
+	Rename(userId, id int, accountName string) error
+	SetArchived(userId, id int, archived bool) error
+	Close(userId, id int) error
+	Reopen(userId, id int) error
 	GetAll(userId int) ([]*Account, error)
+	GetInconsistent(userId int) ([]*Account, error)
 }
 
+// Account.Balance is derived from the transaction ledger, StoredBalance is
+// the accounts.balance column that is kept up to date on every insert.
+// CreditLimit is how far below zero the balance may go, see AccountType.
+// Role is what the user reading the account may do with it, RoleOwner for
+// their own accounts.
 type Account struct {
-	ID          int
-	UserId      int
-	AccountName string
-	Balance     float64
-	Currency    Currency
+	ID            int
+	UserId        int
+	AccountName   string
+	Balance       Money
+	StoredBalance Money
+	Currency      Currency
+	Type          AccountType
+	CreditLimit   Money
+	// ArchivedAt and ClosedAt are zero for accounts in use.
+	ArchivedAt time.Time
+	ClosedAt   time.Time
+	// HouseholdID is zero for accounts that are not shared.
+	HouseholdID int
+	Role        Role
+}
+
+// Active reports whether the account is offered in forms.
+func (a Account) Active() bool {
+	return a.ArchivedAt.IsZero() && a.ClosedAt.IsZero()
+}
+
+func (a Account) Closed() bool {
+	return !a.ClosedAt.IsZero()
+}
+
+// Available is what can be taken from the account, the limit included.
+func (a Account) Available() Money {
+	return a.Balance.Add(a.CreditLimit)
+}
+
+// Consistent reports whether the stored balance agrees with the ledger.
+func (a Account) Consistent() bool {
+	return a.Balance == a.StoredBalance
 }
 
 func (a Account) GetCurrencyString() string {
@@ -27,18 +66,22 @@ func (a Account) GetCurrencyString() string {
 }
 
 func (a Account) DisplayBalance() string {
-	return fmt.Sprintf("%.2f %s", a.Balance, a.Currency)
+	return a.Balance.String()
 }
 
 type AccountModel struct {
 	DB *sql.DB
 }
 
-func (m *AccountModel) Insert(userID int, accountName string, currency Currency) (int, error) {
-	stmt := `INSERT INTO accounts (user_id, account_name, balance, currency) 
-	VALUES (?, ?, ?, ?)`
+func (m *AccountModel) Insert(userID int, accountName string, currency Currency, accountType AccountType, creditLimit Money) (int, error) {
+	stmt := `INSERT INTO accounts (user_id, account_name, balance, currency, account_type, credit_limit) 
+	VALUES (?, ?, ?, ?, ?, ?)`
+
+	if !accountType.HasLimit() && !creditLimit.IsZero() {
+		return 0, ErrCreditLimit
+	}
 
-	result, err := m.DB.Exec(stmt, userID, accountName, 0, currency)
+	result, err := m.DB.Exec(stmt, userID, accountName, 0, currency, accountType, creditLimit.Minor)
 	if err != nil {
 		sqliteErr, b := err.(sqlite3.Error)
 		if b {
@@ -61,17 +104,39 @@ func (m *AccountModel) Insert(userID int, accountName string, currency Currency)
 	return int(id), nil
 }
 
-func (m *AccountModel) Get(userId, id int) (*Account, error) {
-	stmt := `SELECT id, user_id, account_name, balance, currency
-	FROM accounts 
-	WHERE user_id = ?
-	AND id = ?
-	`
+const accountSelect = `
+	SELECT a.id, a.user_id, a.account_name, ab.balance, a.balance, a.currency, a.account_type, a.credit_limit,
+		a.archived_at, a.closed_at, a.household_id, aa.role
+	FROM accounts a
+	JOIN account_balances ab ON ab.account_id = a.id
+	JOIN account_access aa ON aa.account_id = a.id`
+
+func scanAccount(row rowScanner) (*Account, error) {
+	a := &Account{}
+	var archivedAt, closedAt sql.NullTime
+	var householdId sql.NullInt64
+	err := row.Scan(&a.ID, &a.UserId, &a.AccountName, &a.Balance.Minor, &a.StoredBalance.Minor, &a.Currency, &a.Type, &a.CreditLimit.Minor,
+		&archivedAt, &closedAt, &householdId, &a.Role)
+	if err != nil {
+		return nil, err
+	}
+	a.ArchivedAt = archivedAt.Time
+	a.ClosedAt = closedAt.Time
+	a.HouseholdID = int(householdId.Int64)
+	a.Balance.Currency = a.Currency
+	a.StoredBalance.Currency = a.Currency
+	a.CreditLimit.Currency = a.Currency
+	return a, nil
+}
 
-	s := &Account{}
+// Get returns an account the user can see, their own or one shared with a
+// household they are a member of.
+func (m *AccountModel) Get(userId, id int) (*Account, error) {
+	stmt := accountSelect + `
+	WHERE aa.user_id = ?
+	AND a.id = ?`
 
-	row := m.DB.QueryRow(stmt, userId, id)
-	err := row.Scan(&s.ID, &s.UserId, &s.AccountName, &s.Balance, &s.Currency)
+	a, err := scanAccount(m.DB.QueryRow(stmt, userId, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -79,13 +144,151 @@ func (m *AccountModel) Get(userId, id int) (*Account, error) {
 			return nil, err
 		}
 	}
-	return s, nil
+	return a, nil
 }
 
 func (m *AccountModel) GetAll(userId int) ([]*Account, error) {
-	stmt := `SELECT id, user_id, account_name, balance, currency FROM accounts where user_id = ?`
+	stmt := accountSelect + `
+	WHERE aa.user_id = ?`
+
+	return m.query(stmt, userId)
+}
+
+// SetType changes the type and credit limit of an account. A limit that the
+// balance is already below is refused with ErrCreditLimit.
+func (m *AccountModel) SetType(userId, id int, accountType AccountType, creditLimit Money) error {
+	stmt := `
+	UPDATE accounts SET account_type = ?, credit_limit = ?
+	WHERE user_id = ?
+	AND id = ?
+	AND balance + ? >= 0;`
+
+	if !accountType.HasLimit() && !creditLimit.IsZero() {
+		return ErrCreditLimit
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, accountType, creditLimit.Minor, userId, id, creditLimit.Minor)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		var exists bool
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM accounts WHERE user_id = ? AND id = ?)`, userId, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNoRecord
+		}
+		return ErrCreditLimit
+	}
+
+	return tx.Commit()
+}
+
+func (m *AccountModel) Rename(userId, id int, accountName string) error {
+	result, err := m.DB.Exec(`UPDATE accounts SET account_name = ? WHERE user_id = ? AND id = ?`, accountName, userId, id)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return ErrDuplicateAccountName
+		}
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// SetArchived hides the account from the forms or brings it back.
+func (m *AccountModel) SetArchived(userId, id int, archived bool) error {
+	var archivedAt any
+	if archived {
+		archivedAt = time.Now().UTC()
+	}
+
+	result, err := m.DB.Exec(`UPDATE accounts SET archived_at = ? WHERE user_id = ? AND id = ?`, archivedAt, userId, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// Close records the close date of an account with a zero balance, one with
+// money left on it is refused with ErrAccountNotEmpty.
+func (m *AccountModel) Close(userId, id int) error {
+	stmt := `
+	UPDATE accounts SET closed_at = ?
+	WHERE user_id = ?
+	AND id = ?
+	AND closed_at IS NULL
+	AND balance = 0;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, time.Now().UTC(), userId, id)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		var closed sql.NullBool
+		err = tx.QueryRow(`SELECT closed_at IS NOT NULL FROM accounts WHERE user_id = ? AND id = ?`, userId, id).Scan(&closed)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNoRecord
+		case err != nil:
+			return err
+		case closed.Bool:
+			return nil
+		}
+		return ErrAccountNotEmpty
+	}
+
+	return tx.Commit()
+}
+
+// Reopen lets a closed account take transactions again.
+func (m *AccountModel) Reopen(userId, id int) error {
+	result, err := m.DB.Exec(`UPDATE accounts SET closed_at = NULL WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// GetInconsistent returns the accounts whose stored balance disagrees with
+// the sum of their transactions.
+func (m *AccountModel) GetInconsistent(userId int) ([]*Account, error) {
+	stmt := accountSelect + `
+	WHERE aa.user_id = ?
+	AND a.balance != ab.balance`
+
+	return m.query(stmt, userId)
+}
 
-	rows, err := m.DB.Query(stmt, userId)
+func (m *AccountModel) query(stmt string, args ...any) ([]*Account, error) {
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -94,8 +297,7 @@ func (m *AccountModel) GetAll(userId int) ([]*Account, error) {
 	accounts := []*Account{}
 
 	for rows.Next() {
-		a := &Account{}
-		err := rows.Scan(&a.ID, &a.UserId, &a.AccountName, &a.Balance, &a.Currency)
+		a, err := scanAccount(rows)
 		if err != nil {
 			return nil, err
 		}
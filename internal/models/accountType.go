@@ -0,0 +1,46 @@
+package models
+
+// AccountType decides what an account is for and whether its balance may go
+// below zero.
+type AccountType string
+
+const (
+	Checking   AccountType = "checking"
+	Cash       AccountType = "cash"
+	Savings    AccountType = "savings"
+	CreditCard AccountType = "credit"
+	Loan       AccountType = "loan"
+	Investment AccountType = "investment"
+)
+
+// AccountTypes lists the types in the order they are offered.
+var AccountTypes = []AccountType{Checking, Cash, Savings, CreditCard, Loan, Investment}
+
+var accountTypeLabel = map[AccountType]string{
+	Checking:   "Checking",
+	Cash:       "Cash",
+	Savings:    "Savings",
+	CreditCard: "Credit card",
+	Loan:       "Loan",
+	Investment: "Investment",
+}
+
+func (t AccountType) Known() bool {
+	_, ok := accountTypeLabel[t]
+	return ok
+}
+
+func (t AccountType) Label() string {
+	return accountTypeLabel[t]
+}
+
+// Liability reports whether the account holds money owed rather than owned.
+func (t AccountType) Liability() bool {
+	return t == CreditCard || t == Loan
+}
+
+// HasLimit reports whether the account may go below zero, down to its
+// credit limit. For checking accounts the limit is the overdraft.
+func (t AccountType) HasLimit() bool {
+	return t == Checking || t.Liability()
+}
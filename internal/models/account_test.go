@@ -0,0 +1,200 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestAccountModelDerivedBalance(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	other := newTestAccount(t, db, Euro)
+
+	insert := func(tt TransactionType, minor int64) {
+		t.Helper()
+		_, err := transactions.Insert(TransactionCreateForm{
+			UserId:          acc.UserId,
+			AccountId:       acc.ID,
+			Date:            time.Now().UTC(),
+			Amount:          NewMoney(minor, Euro),
+			Currency:        Euro,
+			Category:        "test",
+			TransactionType: int(tt),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert(Income, 10000)
+	insert(Expense, 2550)
+	insert(RebalanceIn, 100)
+	insert(RebalanceOut, 50)
+
+	acc, err := accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, acc.Balance, NewMoney(7500, Euro))
+	assert.Equal(t, acc.Consistent(), true)
+
+	inconsistent, err := accounts.GetInconsistent(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(inconsistent), 0)
+
+	_, err = db.Exec(`UPDATE accounts SET balance = 1 WHERE id = ?`, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc, err = accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, acc.Balance, NewMoney(7500, Euro))
+	assert.Equal(t, acc.StoredBalance, NewMoney(1, Euro))
+
+	inconsistent, err = accounts.GetInconsistent(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(inconsistent), 1)
+	assert.Equal(t, inconsistent[0].ID, acc.ID)
+
+	// NOTE: Other users' accounts are not part of the check.
+	inconsistent, err = accounts.GetInconsistent(other.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(inconsistent), 0)
+}
+
+func TestAccountModelCreditLimit(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	_, err := accounts.Insert(acc.UserId, "Wallet", Euro, Cash, NewMoney(100, Euro))
+	assert.Equal(t, errors.Is(err, ErrCreditLimit), true)
+
+	cardId, err := accounts.Insert(acc.UserId, "Card", Euro, CreditCard, NewMoney(50000, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := accounts.Get(acc.UserId, cardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, card.Type, CreditCard)
+	assert.Equal(t, card.Available(), NewMoney(50000, Euro))
+
+	_, err = transactions.Insert(testTransaction(card, Expense, 30000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transactions.Insert(testTransaction(card, Expense, 20001))
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+
+	card, err = accounts.Get(acc.UserId, cardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, card.Balance, NewMoney(-30000, Euro))
+	assert.Equal(t, card.Consistent(), true)
+
+	// NOTE: The balance is already below a lower limit.
+	err = accounts.SetType(acc.UserId, cardId, CreditCard, NewMoney(20000, Euro))
+	assert.Equal(t, errors.Is(err, ErrCreditLimit), true)
+	err = accounts.SetType(acc.UserId, cardId, Savings, NewMoney(0, Euro))
+	assert.Equal(t, errors.Is(err, ErrCreditLimit), true)
+	err = accounts.SetType(acc.UserId, cardId+1, CreditCard, NewMoney(0, Euro))
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	err = accounts.SetType(acc.UserId, acc.ID, Checking, NewMoney(1000, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transactions.Insert(testTransaction(acc, Expense, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccountModelStatus(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	otherId, err := accounts.Insert(acc.UserId, "Other", Euro, Checking, NewMoney(0, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = accounts.Rename(acc.UserId, otherId, acc.AccountName)
+	assert.Equal(t, errors.Is(err, ErrDuplicateAccountName), true)
+	err = accounts.Rename(acc.UserId, otherId, "Savings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = accounts.SetArchived(acc.UserId, otherId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := accounts.Get(acc.UserId, otherId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, other.AccountName, "Savings")
+	assert.Equal(t, other.Active(), false)
+	assert.Equal(t, other.Closed(), false)
+
+	id, err := transactions.Insert(testTransaction(acc, Income, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = accounts.Close(acc.UserId, acc.ID)
+	assert.Equal(t, errors.Is(err, ErrAccountNotEmpty), true)
+
+	err = transactions.Delete(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = accounts.Close(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, err := accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, closed.Closed(), true)
+
+	_, err = transactions.Insert(testTransaction(acc, Income, 1000))
+	assert.Equal(t, errors.Is(err, ErrAccountClosed), true)
+	err = transactions.Restore(acc.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrAccountClosed), true)
+
+	err = accounts.Close(acc.UserId, acc.ID+100)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	err = accounts.Reopen(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transactions.Insert(testTransaction(acc, Income, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,388 @@
+package models
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type AttachmentModelInterface interface {
+	Insert(userId, transactionId int, filename string, data []byte) (int, error)
+	Get(userId, id int) (*Attachment, error)
+	GetThumbnail(userId, id int) ([]byte, error)
+	Delete(userId, id int) error
+	PurgeDeleted(before time.Time) (int, error)
+}
+
+// MaxAttachmentSize is the largest file accepted as an attachment.
+const MaxAttachmentSize = 5 << 20
+
+// AttachmentTypes are the content types accepted as attachments, sniffed from
+// the file and not taken from the upload.
+var AttachmentTypes = []string{"image/jpeg", "image/png", "image/gif", "application/pdf"}
+
+const (
+	// thumbnailSize is the longest side of a thumbnail in pixels.
+	thumbnailSize = 160
+	// maxImagePixels keeps a small file claiming a huge image from being
+	// decoded for its thumbnail.
+	maxImagePixels = 50_000_000
+)
+
+// Attachment is a receipt or document of a transaction. Data is only set by
+// Get, lists carry the metadata.
+type Attachment struct {
+	ID            int
+	TransactionID int
+	UserID        int
+	Filename      string
+	ContentType   string
+	Size          int
+	Created       time.Time
+	Data          []byte
+	HasThumbnail  bool
+}
+
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+func (a Attachment) DisplaySize() string {
+	switch {
+	case a.Size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(a.Size)/(1<<20))
+	case a.Size >= 1<<10:
+		return fmt.Sprintf("%.0f kB", float64(a.Size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", a.Size)
+	}
+}
+
+// AttachmentModel keeps the files in the database, or in Dir when it is set.
+type AttachmentModel struct {
+	DB  *sql.DB
+	Dir string
+}
+
+// Insert attaches a file to an income or expense the user can edit.
+func (m *AttachmentModel) Insert(userId, transactionId int, filename string, data []byte) (int, error) {
+	if len(data) == 0 || len(data) > MaxAttachmentSize {
+		return 0, ErrAttachmentSize
+	}
+	contentType := http.DetectContentType(data)
+	if !knownAttachmentType(contentType) {
+		return 0, ErrAttachmentType
+	}
+
+	filename = filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if filename == "." || filename == "/" {
+		filename = "attachment"
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	t, err := scanTransaction(tx.QueryRow(`
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
+	FROM transactions
+	WHERE deleted_at IS NULL
+	AND `+editableTransaction+`
+	AND id = ?;`, userId, transactionId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, mapWriteError(err)
+	}
+	if !t.Editable() {
+		return 0, ErrNotEditable
+	}
+
+	// NOTE: A file that is no image after all simply has no thumbnail.
+	var thumbnail []byte
+	if strings.HasPrefix(contentType, "image/") {
+		thumbnail, _ = makeThumbnail(data)
+	}
+
+	var blob, path any
+	if m.Dir == "" {
+		blob = data
+	} else {
+		name, err := m.writeFile(data)
+		if err != nil {
+			return 0, err
+		}
+		// NOTE: The file is only kept once the row is committed.
+		defer func() {
+			if path != nil {
+				os.Remove(filepath.Join(m.Dir, name))
+			}
+		}()
+		path = name
+	}
+
+	stmt := `
+	INSERT INTO attachments (transaction_id, user_id, filename, content_type, size, data, path, thumbnail, created)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	result, err := tx.Exec(stmt, transactionId, userId, filename, contentType, len(data), blob, path, thumbnail, time.Now().UTC())
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	path = nil
+
+	return int(id), nil
+}
+
+// Get returns an attachment with its file, for anyone who can see the
+// transaction.
+func (m *AttachmentModel) Get(userId, id int) (*Attachment, error) {
+	stmt := `
+	SELECT id, transaction_id, user_id, filename, content_type, size, created, data, path, thumbnail IS NOT NULL
+	FROM attachments
+	WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NULL AND ` + visibleTransaction + `)
+	AND id = ?;`
+
+	a := &Attachment{}
+	var path sql.NullString
+	err := m.DB.QueryRow(stmt, userId, userId, id).Scan(&a.ID, &a.TransactionID, &a.UserID, &a.Filename,
+		&a.ContentType, &a.Size, &a.Created, &a.Data, &path, &a.HasThumbnail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	if path.Valid {
+		a.Data, err = os.ReadFile(filepath.Join(m.Dir, path.String))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// GetThumbnail returns the JPEG thumbnail of an image attachment.
+func (m *AttachmentModel) GetThumbnail(userId, id int) ([]byte, error) {
+	stmt := `
+	SELECT thumbnail
+	FROM attachments
+	WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NULL AND ` + visibleTransaction + `)
+	AND thumbnail IS NOT NULL
+	AND id = ?;`
+
+	var thumbnail []byte
+	err := m.DB.QueryRow(stmt, userId, userId, id).Scan(&thumbnail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return thumbnail, nil
+}
+
+// Delete removes an attachment of a transaction the user can edit.
+func (m *AttachmentModel) Delete(userId, id int) error {
+	stmt := `
+	DELETE FROM attachments
+	WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NULL AND ` + editableTransaction + `)
+	AND id = ?
+	RETURNING path;`
+
+	var path sql.NullString
+	err := m.DB.QueryRow(stmt, userId, id).Scan(&path)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return mapWriteError(err)
+	}
+
+	if path.Valid {
+		m.removeFile(path.String)
+	}
+
+	return nil
+}
+
+// PurgeDeleted removes the attachments of the transactions that
+// TransactionModel.PurgeDeleted is about to remove, files included. Run it
+// first with the same time.
+func (m *AttachmentModel) PurgeDeleted(before time.Time) (int, error) {
+	stmt := `
+	DELETE FROM attachments
+	WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	RETURNING path;`
+
+	rows, err := m.DB.Query(stmt, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	n := 0
+	for rows.Next() {
+		var path sql.NullString
+		err := rows.Scan(&path)
+		if err != nil {
+			return 0, err
+		}
+		if path.Valid {
+			paths = append(paths, path.String)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, path := range paths {
+		m.removeFile(path)
+	}
+
+	return n, nil
+}
+
+func (m *TransactionModel) loadAttachments(byId map[int]*Transaction, idsJSON string) error {
+	stmt := `
+	SELECT id, transaction_id, user_id, filename, content_type, size, created, thumbnail IS NOT NULL
+	FROM attachments
+	WHERE transaction_id IN (SELECT value FROM json_each(?))
+	ORDER BY id;`
+
+	rows, err := m.DB.Query(stmt, idsJSON)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Attachment
+		err := rows.Scan(&a.ID, &a.TransactionID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.Created, &a.HasThumbnail)
+		if err != nil {
+			return err
+		}
+		t := byId[a.TransactionID]
+		t.Attachments = append(t.Attachments, a)
+	}
+
+	return rows.Err()
+}
+
+// writeFile stores data under a random name in Dir and returns the name.
+func (m *AttachmentModel) writeFile(data []byte) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(b)
+
+	err = os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// removeFile removes a stored file, a file that is already gone is fine.
+func (m *AttachmentModel) removeFile(name string) {
+	os.Remove(filepath.Join(m.Dir, filepath.Base(name)))
+}
+
+func knownAttachmentType(contentType string) bool {
+	for _, t := range AttachmentTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// makeThumbnail scales an image down to fit thumbnailSize, averaging the
+// pixels of each box, and encodes it as JPEG.
+func makeThumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > maxImagePixels {
+		return nil, ErrAttachmentSize
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	scale := max(w, h)
+	tw, th := max(1, w*thumbnailSize/scale), max(1, h*thumbnailSize/scale)
+	if scale <= thumbnailSize {
+		tw, th = w, h
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+
+	// NOTE: JPEG has no transparency, transparent pixels come out white.
+	flat := image.NewRGBA(dst.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), dst, image.Point{}, draw.Over)
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAttachmentModel(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  bool
+	}{
+		{name: "Database", dir: false},
+		{name: "Directory", dir: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			attachments := &AttachmentModel{DB: db}
+			if tt.dir {
+				attachments.Dir = t.TempDir()
+			}
+			transactions := &TransactionModel{DB: db}
+
+			account := newTestAccount(t, db, Euro)
+			other := newTestAccount(t, db, Euro)
+			txId, err := transactions.Insert(testTransaction(account, Income, 500))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			receipt := testPNG(t, 640, 320)
+			id, err := attachments.Insert(account.UserId, txId, `C:\scans\receipt.png`, receipt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pdf := []byte("%PDF-1.4\n%%EOF\n")
+			_, err = attachments.Insert(account.UserId, txId, "invoice.pdf", pdf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = attachments.Insert(account.UserId, txId, "notes.txt", []byte("plain text"))
+			assert.Equal(t, errors.Is(err, ErrAttachmentType), true)
+			_, err = attachments.Insert(account.UserId, txId, "big.pdf", make([]byte, MaxAttachmentSize+1))
+			assert.Equal(t, errors.Is(err, ErrAttachmentSize), true)
+			_, err = attachments.Insert(other.UserId, txId, "invoice.pdf", pdf)
+			assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+			a, err := attachments.Get(account.UserId, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, a.Filename, "receipt.png")
+			assert.Equal(t, a.ContentType, "image/png")
+			assert.Equal(t, a.Size, len(receipt))
+			assert.Equal(t, bytes.Equal(a.Data, receipt), true)
+			assert.Equal(t, a.HasThumbnail, true)
+			_, err = attachments.Get(other.UserId, id)
+			assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+			thumbnail, err := attachments.GetThumbnail(account.UserId, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(thumbnail))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, format, "jpeg")
+			assert.Equal(t, config.Width, 160)
+			assert.Equal(t, config.Height, 80)
+
+			transaction, err := transactions.Get(account.UserId, txId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, len(transaction.Attachments), 2)
+			assert.Equal(t, transaction.Attachments[1].Filename, "invoice.pdf")
+			assert.Equal(t, transaction.Attachments[1].HasThumbnail, false)
+
+			err = attachments.Delete(other.UserId, id)
+			assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+			err = attachments.Delete(account.UserId, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = attachments.Get(account.UserId, id)
+			assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+			// NOTE: Trashed transactions keep their attachments until purged.
+			err = transactions.Delete(account.UserId, txId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := attachments.PurgeDeleted(time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, n, 1)
+
+			var left int
+			err = db.QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&left)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, left, 0)
+			if tt.dir {
+				files, err := os.ReadDir(attachments.Dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, len(files), 0)
+			}
+		})
+	}
+}
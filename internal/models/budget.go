@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type BudgetModelInterface interface {
+	Set(b Budget) (int, error)
+	GetRange(userId int, from, to time.Time) ([]*Budget, error)
+	Delete(userId, id int) error
+	CopyMonth(userId int, from, to time.Time) (int, error)
+}
+
+// Budget is the plan for one expense category in one month and currency.
+type Budget struct {
+	ID         int
+	UserID     int
+	CategoryID int
+	// Month is the first day of the month, see MonthOf.
+	Month    time.Time
+	Amount   Money
+	Rollover bool
+}
+
+// monthLayout is how budget months are stored.
+const monthLayout = "2006-01"
+
+// MonthOf returns the first day of the month of t.
+func MonthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseMonth parses a YYYY-MM month.
+func ParseMonth(s string) (time.Time, error) {
+	return time.Parse(monthLayout, s)
+}
+
+type BudgetModel struct {
+	DB *sql.DB
+}
+
+// Set adds the budget or replaces the amount and rollover of the one already
+// there for the category, month and currency.
+func (m *BudgetModel) Set(b Budget) (int, error) {
+	stmt := `
+	INSERT INTO budgets (user_id, category_id, month, amount, currency, rollover)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (user_id, category_id, month, currency)
+	DO UPDATE SET amount = excluded.amount, rollover = excluded.rollover
+	RETURNING id;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	c, err := getCategory(tx, b.UserID, b.CategoryID)
+	if err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			return 0, ErrBudgetCategory
+		}
+		return 0, err
+	}
+	if c.TransactionType != Expense {
+		return 0, ErrBudgetCategory
+	}
+
+	var id int
+	err = tx.QueryRow(stmt, b.UserID, b.CategoryID, b.Month.Format(monthLayout), b.Amount.Minor, b.Amount.Currency, b.Rollover).Scan(&id)
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetRange returns the budgets of the months from through to, both included,
+// ordered by month.
+func (m *BudgetModel) GetRange(userId int, from, to time.Time) ([]*Budget, error) {
+	stmt := `
+	SELECT id, user_id, category_id, month, amount, currency, rollover
+	FROM budgets
+	WHERE user_id = ?
+		AND month BETWEEN ? AND ?
+	ORDER BY month, id;`
+
+	rows, err := m.DB.Query(stmt, userId, from.Format(monthLayout), to.Format(monthLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []*Budget{}
+	for rows.Next() {
+		b := &Budget{}
+		var month string
+		err := rows.Scan(&b.ID, &b.UserID, &b.CategoryID, &month, &b.Amount.Minor, &b.Amount.Currency, &b.Rollover)
+		if err != nil {
+			return nil, err
+		}
+		b.Month, err = ParseMonth(month)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+func (m *BudgetModel) Delete(userId, id int) error {
+	result, err := m.DB.Exec(`DELETE FROM budgets WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// CopyMonth copies the budgets of the month from to the month to, keeping
+// the ones to already has. It returns how many were copied.
+func (m *BudgetModel) CopyMonth(userId int, from, to time.Time) (int, error) {
+	stmt := `
+	INSERT OR IGNORE INTO budgets (user_id, category_id, month, amount, currency, rollover)
+	SELECT user_id, category_id, ?, amount, currency, rollover
+	FROM budgets
+	WHERE user_id = ? AND month = ?;`
+
+	result, err := m.DB.Exec(stmt, to.Format(monthLayout), userId, from.Format(monthLayout))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestBudgetModel(t *testing.T) {
+	db := newTestDB(t)
+	budgets := &BudgetModel{DB: db}
+	categories := &CategoryModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	foodId, err := categories.Insert(acc.UserId, Expense, "food", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salaryId, err := categories.Insert(acc.UserId, Income, "salary", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	october := date("2026-10-01")
+	november := date("2026-11-01")
+
+	id, err := budgets.Set(Budget{UserID: acc.UserId, CategoryID: foodId, Month: october, Amount: NewMoney(30000, Euro)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: Setting it again replaces the amount of the same budget.
+	again, err := budgets.Set(Budget{UserID: acc.UserId, CategoryID: foodId, Month: october, Amount: NewMoney(25000, Euro), Rollover: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, again, id)
+
+	_, err = budgets.Set(Budget{UserID: acc.UserId, CategoryID: salaryId, Month: october, Amount: NewMoney(100, Euro)})
+	assert.Equal(t, errors.Is(err, ErrBudgetCategory), true)
+	_, err = budgets.Set(Budget{UserID: acc.UserId + 1, CategoryID: foodId, Month: october, Amount: NewMoney(100, Euro)})
+	assert.Equal(t, errors.Is(err, ErrBudgetCategory), true)
+
+	n, err := budgets.CopyMonth(acc.UserId, october, november)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, n, 1)
+	n, err = budgets.CopyMonth(acc.UserId, october, november)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, n, 0)
+
+	all, err := budgets.GetRange(acc.UserId, october, november)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 2)
+	assert.Equal(t, all[0].ID, id)
+	assert.Equal(t, all[0].Amount, NewMoney(25000, Euro))
+	assert.Equal(t, all[0].Rollover, true)
+	assert.Equal(t, all[1].Month, november)
+
+	err = budgets.Delete(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = budgets.Delete(acc.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	october, err = ParseMonth("2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, MonthOf(date("2026-10-17")), october)
+}
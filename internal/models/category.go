@@ -0,0 +1,430 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type CategoryModelInterface interface {
+	Insert(userId int, tt TransactionType, name string, parentId int) (int, error)
+	Get(userId, id int) (*Category, error)
+	GetAll(userId int) ([]*Category, error)
+	GetActive(userId int, tt TransactionType) ([]*Category, error)
+	Rename(userId, id int, name string) error
+	Merge(userId, fromId, intoId int) error
+	SetParent(userId, id, parentId int) error
+	SetArchived(userId, id int, archived bool) error
+}
+
+// Category is a user defined income or expense category. Transactions refer
+// to it by Name, so names stay unique per type across the whole tree.
+type Category struct {
+	ID              int
+	UserID          int
+	Name            string
+	TransactionType TransactionType
+	// ParentID is zero for top level categories.
+	ParentID int
+	// Path is the name prefixed by the names of all parents, e.g.
+	// "Home > Utilities > Electricity", Depth the number of parents.
+	Path  string
+	Depth int
+	// Archived categories are kept for existing transactions and reports but
+	// can not be picked for new ones.
+	Archived bool
+	// Count is the number of transactions, trash included, using the category.
+	Count int
+
+	pathNames []string
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+const categoryPathSeparator = " > "
+
+// categorySelect walks the tree from the top level categories down, the
+// names along the path of each category are joined with the ASCII unit
+// separator and split again by scanCategory.
+const categorySelect = `
+	WITH RECURSIVE category_paths (id, names) AS (
+		SELECT id, name FROM categories WHERE parent_id IS NULL
+		UNION ALL
+		SELECT c.id, p.names || char(31) || c.name
+		FROM categories c
+		JOIN category_paths p ON p.id = c.parent_id
+	)
+	SELECT c.id, c.user_id, c.name, c.transaction_type, c.archived, COALESCE(c.parent_id, 0),
+		(SELECT COUNT(*) FROM transactions t
+		WHERE t.user_id = c.user_id
+		AND t.transaction_type = c.transaction_type
+		AND t.transfer_id IS NULL
+		AND (t.category = c.name OR EXISTS (
+			SELECT 1 FROM transaction_splits s
+			WHERE s.transaction_id = t.id
+			AND s.category = c.name))),
+		cp.names
+	FROM categories c
+	JOIN category_paths cp ON cp.id = c.id`
+
+func (m *CategoryModel) Insert(userId int, tt TransactionType, name string, parentId int) (int, error) {
+	stmt := `INSERT INTO categories (user_id, name, transaction_type, parent_id) VALUES (?, ?, ?, ?)`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if parentId != 0 {
+		parent, err := getCategory(tx, userId, parentId)
+		if err != nil {
+			return 0, err
+		}
+		if parent.TransactionType != tt {
+			return 0, ErrCategoryParent
+		}
+	}
+
+	result, err := tx.Exec(stmt, userId, name, tt, nullId(parentId))
+	if err != nil {
+		return 0, mapCategoryError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (m *CategoryModel) Get(userId, id int) (*Category, error) {
+	stmt := categorySelect + `
+	WHERE c.user_id = ?
+	AND c.id = ?;`
+
+	c, err := scanCategory(m.DB.QueryRow(stmt, userId, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetAll returns the categories of a user in tree order, every parent right
+// before its sub-categories.
+func (m *CategoryModel) GetAll(userId int) ([]*Category, error) {
+	stmt := categorySelect + `
+	WHERE c.user_id = ?;`
+
+	categories, err := m.query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortCategoryTree(categories), nil
+}
+
+// GetActive returns the categories that can be picked for a new transaction of
+// type tt. Sub-categories of an archived category can still be picked.
+func (m *CategoryModel) GetActive(userId int, tt TransactionType) ([]*Category, error) {
+	stmt := categorySelect + `
+	WHERE c.user_id = ?
+	AND c.transaction_type = ?
+	AND NOT c.archived;`
+
+	categories, err := m.query(stmt, userId, tt)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortCategoryTree(categories), nil
+}
+
+// Rename renames a category together with every transaction using it.
+func (m *CategoryModel) Rename(userId, id int, name string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c, err := getCategory(tx, userId, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE categories SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return mapCategoryError(err)
+	}
+
+	err = moveTransactions(tx, c, name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Merge moves every transaction and budget of the category fromId to intoId
+// and removes fromId. Both have to be of the same transaction type,
+// sub-categories of fromId move up a level.
+func (m *CategoryModel) Merge(userId, fromId, intoId int) error {
+	if fromId == intoId {
+		return ErrCategoryMergeMismatch
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	from, err := getCategory(tx, userId, fromId)
+	if err != nil {
+		return err
+	}
+	into, err := getCategory(tx, userId, intoId)
+	if err != nil {
+		return err
+	}
+	if from.TransactionType != into.TransactionType {
+		return ErrCategoryMergeMismatch
+	}
+
+	err = moveTransactions(tx, from, into.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE categories SET parent_id = ? WHERE parent_id = ?`, nullId(from.ParentID), from.ID)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Budgets move along unless into already has one for the month.
+	_, err = tx.Exec(`UPDATE OR IGNORE budgets SET category_id = ? WHERE category_id = ?`, into.ID, from.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM budgets WHERE category_id = ?`, from.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM categories WHERE id = ?`, from.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetParent moves a category, together with its sub-categories, under
+// parentId, or to the top level for zero.
+func (m *CategoryModel) SetParent(userId, id, parentId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c, err := getCategory(tx, userId, id)
+	if err != nil {
+		return err
+	}
+
+	if parentId != 0 {
+		parent, err := getCategory(tx, userId, parentId)
+		if err != nil {
+			return err
+		}
+		if parent.TransactionType != c.TransactionType {
+			return ErrCategoryParent
+		}
+
+		// NOTE: Walk up from the new parent, finding c on the way means the
+		// parent is c itself or one of its sub-categories.
+		for ancestor := parent; ; {
+			if ancestor.ID == c.ID {
+				return ErrCategoryParent
+			}
+			if ancestor.ParentID == 0 {
+				break
+			}
+			ancestor, err = getCategory(tx, userId, ancestor.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE categories SET parent_id = ? WHERE id = ?`, nullId(parentId), id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *CategoryModel) SetArchived(userId, id int, archived bool) error {
+	stmt := `UPDATE categories SET archived = ? WHERE user_id = ? AND id = ?`
+
+	result, err := m.DB.Exec(stmt, archived, userId, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (m *CategoryModel) query(stmt string, args ...any) ([]*Category, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func scanCategory(row rowScanner) (*Category, error) {
+	c := &Category{}
+	var names string
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.TransactionType, &c.Archived, &c.ParentID, &c.Count, &names)
+	if err != nil {
+		return nil, err
+	}
+	c.pathNames = strings.Split(names, "\x1f")
+	c.Path = strings.Join(c.pathNames, categoryPathSeparator)
+	c.Depth = len(c.pathNames) - 1
+	return c, nil
+}
+
+// sortCategoryTree orders categories by type and path, every parent right
+// before its sub-categories.
+func sortCategoryTree(categories []*Category) []*Category {
+	slices.SortStableFunc(categories, func(a, b *Category) int {
+		if a.TransactionType != b.TransactionType {
+			return int(a.TransactionType) - int(b.TransactionType)
+		}
+		return slices.Compare(a.pathNames, b.pathNames)
+	})
+
+	return categories
+}
+
+func getCategory(tx *sql.Tx, userId, id int) (*Category, error) {
+	stmt := categorySelect + ` WHERE c.user_id = ? AND c.id = ?`
+
+	c, err := scanCategory(tx.QueryRow(stmt, userId, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// moveTransactions points the transactions and split lines of c, trash
+// included, the recurring rules posting into it and the payees defaulting to
+// it at the category called name.
+func moveTransactions(tx *sql.Tx, c *Category, name string) error {
+	stmt := `
+	UPDATE transactions SET category = ?
+	WHERE user_id = ?
+	AND transaction_type = ?
+	AND category = ?
+	AND transfer_id IS NULL;`
+
+	_, err := tx.Exec(stmt, name, c.UserID, c.TransactionType, c.Name)
+	if err != nil {
+		return err
+	}
+
+	stmt = `
+	UPDATE transaction_splits SET category = ?
+	WHERE category = ?
+	AND transaction_id IN (
+		SELECT id FROM transactions
+		WHERE user_id = ?
+		AND transaction_type = ?);`
+
+	_, err = tx.Exec(stmt, name, c.Name, c.UserID, c.TransactionType)
+	if err != nil {
+		return err
+	}
+
+	stmt = `
+	UPDATE recurring_rules SET category = ?
+	WHERE user_id = ?
+	AND transaction_type = ?
+	AND category = ?;`
+
+	_, err = tx.Exec(stmt, name, c.UserID, c.TransactionType, c.Name)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Payees only default to expense categories.
+	if c.TransactionType != Expense {
+		return nil
+	}
+
+	stmt = `UPDATE payees SET default_category = ? WHERE user_id = ? AND default_category = ?`
+	_, err = tx.Exec(stmt, name, c.UserID, c.Name)
+	return err
+}
+
+func nullId(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func mapCategoryError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrDuplicateCategory
+	}
+	return err
+}
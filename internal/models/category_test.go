@@ -0,0 +1,267 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestCategoryModel(t *testing.T) {
+	db := newTestDB(t)
+	categories := &CategoryModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	testId, err := categories.Insert(acc.UserId, Income, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salaryId, err := categories.Insert(acc.UserId, Income, "salary", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foodId, err := categories.Insert(acc.UserId, Expense, "food", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = categories.Insert(acc.UserId, Income, "salary", 0)
+	assert.Equal(t, errors.Is(err, ErrDuplicateCategory), true)
+
+	// NOTE: The same name is fine for the other type.
+	_, err = categories.Insert(acc.UserId, Expense, "salary", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	incomeId, err := transactions.Insert(testTransaction(acc, Income, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = categories.Rename(acc.UserId, testId, "bonus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	income, err := transactions.Get(acc.UserId, incomeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, income.Category, "bonus")
+
+	err = categories.Rename(acc.UserId, testId, "salary")
+	assert.Equal(t, errors.Is(err, ErrDuplicateCategory), true)
+
+	err = categories.Merge(acc.UserId, testId, foodId)
+	assert.Equal(t, errors.Is(err, ErrCategoryMergeMismatch), true)
+
+	err = categories.Merge(acc.UserId, testId, salaryId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	income, err = transactions.Get(acc.UserId, incomeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, income.Category, "salary")
+
+	_, err = categories.Get(acc.UserId, testId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	salary, err := categories.Get(acc.UserId, salaryId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, salary.Count, 1)
+
+	err = categories.SetArchived(acc.UserId, salaryId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := categories.GetActive(acc.UserId, Income)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(active), 0)
+
+	all, err := categories.GetAll(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 3)
+
+	other := newTestAccount(t, db, Euro)
+	err = categories.SetArchived(other.UserId, salaryId, false)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+}
+
+func TestCategoryModelTree(t *testing.T) {
+	db := newTestDB(t)
+	categories := &CategoryModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	insert := func(tt TransactionType, name string, parentId int) int {
+		t.Helper()
+		id, err := categories.Insert(acc.UserId, tt, name, parentId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	homeId := insert(Expense, "Home", 0)
+	utilitiesId := insert(Expense, "Utilities", homeId)
+	electricityId := insert(Expense, "Electricity", utilitiesId)
+	insert(Expense, "Furniture", homeId)
+	insert(Expense, "Food", 0)
+	salaryId := insert(Income, "Salary", 0)
+
+	_, err := categories.Insert(acc.UserId, Expense, "Bonus", salaryId)
+	assert.Equal(t, errors.Is(err, ErrCategoryParent), true)
+
+	all, err := categories.GetAll(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, c := range all {
+		paths = append(paths, c.Path)
+	}
+	assert.Equal(t, strings.Join(paths, ", "), "Salary, Food, Home, Home > Furniture, Home > Utilities, Home > Utilities > Electricity")
+
+	electricity, err := categories.Get(acc.UserId, electricityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, electricity.Depth, 2)
+
+	err = categories.SetParent(acc.UserId, homeId, electricityId)
+	assert.Equal(t, errors.Is(err, ErrCategoryParent), true)
+	err = categories.SetParent(acc.UserId, homeId, homeId)
+	assert.Equal(t, errors.Is(err, ErrCategoryParent), true)
+	err = categories.SetParent(acc.UserId, utilitiesId, salaryId)
+	assert.Equal(t, errors.Is(err, ErrCategoryParent), true)
+
+	err = categories.SetParent(acc.UserId, electricityId, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = categories.SetParent(acc.UserId, electricityId, utilitiesId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: Sub-categories of a merged category move up a level.
+	err = categories.Merge(acc.UserId, utilitiesId, homeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	electricity, err = categories.Get(acc.UserId, electricityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, electricity.Path, "Home > Electricity")
+
+	// NOTE: Sub-categories of an archived category keep its name in the path.
+	err = categories.SetArchived(acc.UserId, homeId, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := categories.GetActive(acc.UserId, Expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = []string{}
+	for _, c := range active {
+		paths = append(paths, c.Path)
+	}
+	assert.Equal(t, strings.Join(paths, ", "), "Food, Home > Electricity, Home > Furniture")
+}
+
+func TestCategoryModelMoveReferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		merge bool
+		want  string
+	}{
+		{name: "Rename", want: "housing"},
+		{name: "Merge", merge: true, want: "home"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			categories := &CategoryModel{DB: db}
+			recurring := &RecurringModel{DB: db}
+			payees := &PayeeModel{DB: db}
+
+			acc := newTestAccount(t, db, Euro)
+			rentId, err := categories.Insert(acc.UserId, Expense, "rent", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			homeId, err := categories.Insert(acc.UserId, Expense, "home", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ruleId, err := recurring.Insert(RecurringRule{
+				UserID:          acc.UserId,
+				AccountID:       acc.ID,
+				TransactionType: Expense,
+				Amount:          NewMoney(50000, Euro),
+				Category:        "rent",
+				Frequency:       Monthly,
+				Interval:        1,
+				Day:             1,
+				StartDate:       date("2026-01-01"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			payeeId, err := payees.Insert(acc.UserId, "Landlord", "rent")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// NOTE: An income category of the same name leaves payees alone.
+			incomeId, err := categories.Insert(acc.UserId, Income, "rent", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = categories.Rename(acc.UserId, incomeId, "deposit")
+			if err != nil {
+				t.Fatal(err)
+			}
+			payee, err := payees.Get(acc.UserId, payeeId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, payee.DefaultCategory, "rent")
+
+			if tt.merge {
+				err = categories.Merge(acc.UserId, rentId, homeId)
+			} else {
+				err = categories.Rename(acc.UserId, rentId, "housing")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rule, err := recurring.Get(acc.UserId, ruleId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, rule.Category, tt.want)
+			payee, err = payees.Get(acc.UserId, payeeId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, payee.DefaultCategory, tt.want)
+		})
+	}
+}
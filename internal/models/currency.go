@@ -1,38 +1,223 @@
 package models
 
-import "database/sql/driver"
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
-type Currency int
+	"github.com/mattn/go-sqlite3"
+)
+
+// Currency is an ISO 4217 code. Symbol and minor unit digits come from the
+// currencies table, see CurrencyModel.Load.
+type Currency string
 
 const (
-	SerbianDinar Currency = iota
-	Euro
+	SerbianDinar Currency = "RSD"
+	Euro         Currency = "EUR"
 )
 
-var currencyTypeName = map[Currency]string{
-	SerbianDinar: "RSD",
-	Euro:         "EUR",
+// NOTE: Most ISO 4217 currencies have two minor unit digits, used for codes
+// that are not in the registry (yet).
+const defaultMinorUnits = 2
+
+type CurrencyInfo struct {
+	Code       Currency
+	Name       string
+	Symbol     string
+	MinorUnits int
+	Enabled    bool
 }
 
-var stringToCurrencyType = map[string]Currency{
-	"RSD": SerbianDinar,
-	"EUR": Euro,
+// registry is the in-memory copy of the currencies table. It is read on every
+// Money format so it lives here instead of being queried each time.
+var registry = struct {
+	sync.RWMutex
+	byCode map[Currency]CurrencyInfo
+}{
+	byCode: map[Currency]CurrencyInfo{
+		SerbianDinar: {Code: SerbianDinar, Name: "Serbian Dinar", Symbol: "RSD", MinorUnits: 2},
+		Euro:         {Code: Euro, Name: "Euro", Symbol: "€", MinorUnits: 2},
+	},
+}
+
+func lookupCurrency(c Currency) (CurrencyInfo, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	info, ok := registry.byCode[c]
+	return info, ok
 }
 
 func GetCurrencyFromString(s string) (Currency, bool) {
-	v, b := stringToCurrencyType[s]
-	return v, b
+	c := Currency(strings.ToUpper(strings.TrimSpace(s)))
+	_, ok := lookupCurrency(c)
+	return c, ok
 }
 
 func (c Currency) String() string {
-	return currencyTypeName[c]
+	return string(c)
+}
+
+func (c Currency) Known() bool {
+	_, ok := lookupCurrency(c)
+	return ok
+}
+
+func (c Currency) Symbol() string {
+	info, ok := lookupCurrency(c)
+	if !ok || info.Symbol == "" {
+		return string(c)
+	}
+	return info.Symbol
+}
+
+func (c Currency) MinorUnits() int {
+	info, ok := lookupCurrency(c)
+	if !ok {
+		return defaultMinorUnits
+	}
+	return info.MinorUnits
 }
 
 func (c *Currency) Scan(value any) error {
-	*c = Currency(value.(int64))
+	switch v := value.(type) {
+	case string:
+		*c = Currency(v)
+	case []byte:
+		*c = Currency(v)
+	default:
+		return fmt.Errorf("models: cannot scan %T into Currency", value)
+	}
 	return nil
 }
 
 func (c Currency) Value() (driver.Value, error) {
-	return int64(c), nil
+	return string(c), nil
+}
+
+type CurrencyModelInterface interface {
+	Load() error
+	GetAll(userId int) ([]*CurrencyInfo, error)
+	GetEnabled(userId int) ([]*CurrencyInfo, error)
+	Enable(userId int, code Currency) error
+	Disable(userId int, code Currency) error
+}
+
+type CurrencyModel struct {
+	DB *sql.DB
+}
+
+// Load replaces the in-memory registry with the contents of the currencies
+// table. It is called once on startup.
+func (m *CurrencyModel) Load() error {
+	stmt := `SELECT code, name, symbol, minor_units FROM currencies`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byCode := map[Currency]CurrencyInfo{}
+	for rows.Next() {
+		c := CurrencyInfo{}
+		err := rows.Scan(&c.Code, &c.Name, &c.Symbol, &c.MinorUnits)
+		if err != nil {
+			return err
+		}
+		byCode[c.Code] = c
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	registry.Lock()
+	registry.byCode = byCode
+	registry.Unlock()
+
+	return nil
+}
+
+// GetAll returns every known currency with Enabled set for the ones the user
+// has turned on.
+func (m *CurrencyModel) GetAll(userId int) ([]*CurrencyInfo, error) {
+	stmt := `
+	SELECT c.code, c.name, c.symbol, c.minor_units, uc.user_id IS NOT NULL
+	FROM currencies c
+	LEFT JOIN user_currencies uc ON uc.code = c.code AND uc.user_id = ?
+	ORDER BY c.code;`
+
+	return m.query(stmt, userId)
+}
+
+func (m *CurrencyModel) GetEnabled(userId int) ([]*CurrencyInfo, error) {
+	stmt := `
+	SELECT c.code, c.name, c.symbol, c.minor_units, TRUE
+	FROM currencies c
+	JOIN user_currencies uc ON uc.code = c.code
+	WHERE uc.user_id = ?
+	ORDER BY c.code;`
+
+	return m.query(stmt, userId)
+}
+
+func (m *CurrencyModel) query(stmt string, args ...any) ([]*CurrencyInfo, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	currencies := []*CurrencyInfo{}
+	for rows.Next() {
+		c := &CurrencyInfo{}
+		err := rows.Scan(&c.Code, &c.Name, &c.Symbol, &c.MinorUnits, &c.Enabled)
+		if err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return currencies, nil
+}
+
+func (m *CurrencyModel) Enable(userId int, code Currency) error {
+	stmt := `INSERT OR IGNORE INTO user_currencies (user_id, code) VALUES (?, ?)`
+
+	_, err := m.DB.Exec(stmt, userId, code)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+			return ErrUnknownCurrency
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Disable refuses to turn off a currency that one of the user's accounts is
+// still held in.
+func (m *CurrencyModel) Disable(userId int, code Currency) error {
+	var inUse bool
+	stmt := `SELECT EXISTS(SELECT true FROM accounts WHERE user_id = ? AND currency = ?)`
+	err := m.DB.QueryRow(stmt, userId, code).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrCurrencyInUse
+	}
+
+	stmt = `DELETE FROM user_currencies WHERE user_id = ? AND code = ?`
+	_, err = m.DB.Exec(stmt, userId, code)
+	return err
 }
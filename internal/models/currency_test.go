@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/validator"
+)
+
+func TestGetCurrencyFromString(t *testing.T) {
+	err := (&CurrencyModel{DB: newTestDB(t)}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		want   Currency
+		wantOk bool
+	}{
+		{name: "Known", input: "EUR", want: Euro, wantOk: true},
+		{name: "Lower case and spaces", input: " rsd ", want: SerbianDinar, wantOk: true},
+		{name: "Loaded from the registry", input: "jpy", want: "JPY", wantOk: true},
+		{name: "Unknown", input: "xxx", want: "XXX", wantOk: false},
+		{name: "Empty", input: "", want: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := GetCurrencyFromString(tt.input)
+			assert.Equal(t, c, tt.want)
+			assert.Equal(t, ok, tt.wantOk)
+			assert.Equal(t, c.Known(), tt.wantOk)
+		})
+	}
+}
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	err := (&CurrencyModel{DB: newTestDB(t)}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		currency   Currency
+		input      string
+		wantDigits int
+		want       int64
+		wantErr    error
+		wantString string
+	}{
+		{name: "Two digits", currency: Euro, input: "1.5", wantDigits: 2, want: 150, wantString: "1.50 EUR"},
+		{name: "No digits", currency: "JPY", input: "150", wantDigits: 0, want: 150, wantString: "150 JPY"},
+		{name: "No digits with decimals", currency: "JPY", input: "1.5", wantDigits: 0, wantErr: ErrInvalidAmount},
+		{name: "Unknown uses the default", currency: "XXX", input: "2.25", wantDigits: defaultMinorUnits, want: 225, wantString: "2.25 XXX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.currency.MinorUnits(), tt.wantDigits)
+
+			m, err := ParseMoney(tt.input, tt.currency)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got err %v; want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			assert.Equal(t, m.Minor, tt.want)
+			assert.Equal(t, m.String(), tt.wantString)
+		})
+	}
+}
+
+func TestCurrencyScanValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    Currency
+		wantErr bool
+	}{
+		{name: "String", value: "EUR", want: Euro},
+		{name: "Bytes", value: []byte("RSD"), want: SerbianDinar},
+		{name: "Integer", value: int64(1), wantErr: true},
+		{name: "Null", value: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Currency
+			err := c.Scan(tt.value)
+			assert.Equal(t, err != nil, tt.wantErr)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, c, tt.want)
+
+			v, err := c.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, v, any(string(tt.want)))
+		})
+	}
+}
+
+func TestCurrencyModelEnableDisable(t *testing.T) {
+	db := newTestDB(t)
+	currencies := &CurrencyModel{DB: db}
+	err := currencies.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := newTestAccount(t, db, Euro)
+
+	// NOTE: Forms only accept the codes GetEnabled returns, like the account
+	// and budget handlers do.
+	permitted := func(code Currency) bool {
+		enabled, err := currencies.GetEnabled(acc.UserId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes := []string{}
+		for _, c := range enabled {
+			codes = append(codes, c.Code.String())
+		}
+		return validator.PermittedValue(code.String(), codes...)
+	}
+
+	for _, code := range []Currency{Euro, "JPY"} {
+		err = currencies.Enable(acc.UserId, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, permitted("JPY"), true)
+	assert.Equal(t, permitted("USD"), false)
+
+	tests := []struct {
+		name        string
+		code        Currency
+		enable      bool
+		wantErr     error
+		wantAllowed bool
+	}{
+		{name: "Enable unknown", code: "XXX", enable: true, wantErr: ErrUnknownCurrency},
+		{name: "Enable twice", code: "JPY", enable: true, wantAllowed: true},
+		{name: "Disable", code: "JPY", wantAllowed: false},
+		{name: "Disable in use", code: Euro, wantErr: ErrCurrencyInUse, wantAllowed: true},
+		{name: "Disable not enabled", code: "USD", wantAllowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.enable {
+				err = currencies.Enable(acc.UserId, tt.code)
+			} else {
+				err = currencies.Disable(acc.UserId, tt.code)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got err %v; want %v", err, tt.wantErr)
+			}
+			assert.Equal(t, permitted(tt.code), tt.wantAllowed)
+		})
+	}
+
+	all, err := currencies.GetAll(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range all {
+		assert.Equal(t, c.Enabled, c.Code == Euro)
+	}
+}
@@ -14,4 +14,68 @@ var (
 	ErrDuplicateAccountName = errors.New("accounts: duplicate account_name per user")
 
 	ErrAccountDoesNotExist = errors.New("transactions: user account does not exist")
+
+	ErrInsufficientFunds = errors.New("transactions: account does not have sufficient funds")
+
+	ErrCreditLimit = errors.New("accounts: balance is below the credit limit or the type has no limit")
+
+	ErrAccountNotEmpty = errors.New("accounts: only accounts with a zero balance can be closed")
+
+	ErrAccountClosed = errors.New("transactions: account is closed")
+
+	ErrConcurrentUpdate = errors.New("transactions: account was changed by another request")
+
+	ErrNotEditable = errors.New("transactions: only incomes and expenses can be changed")
+
+	ErrSplitTotal = errors.New("transactions: split lines do not add up to the amount")
+
+	ErrInvalidAmount = errors.New("models: invalid money amount")
+
+	ErrUnknownCurrency = errors.New("currencies: unknown currency code")
+
+	ErrCurrencyInUse = errors.New("currencies: currency is used by an account")
+
+	ErrDuplicateCategory = errors.New("categories: duplicate category name per user and type")
+
+	ErrCategoryMergeMismatch = errors.New("categories: only different categories of the same type can be merged")
+
+	ErrCategoryParent = errors.New("categories: parent must be a category of the same type outside of the sub-categories")
+
+	ErrDuplicatePayee = errors.New("payees: duplicate payee name")
+
+	ErrDuplicateAlias = errors.New("payees: duplicate alias for the payee")
+
+	ErrInvalidAlias = errors.New("payees: alias can not be blank")
+
+	ErrDuplicateOccurrence = errors.New("recurring: occurrence was already posted")
+
+	ErrBudgetCategory = errors.New("budgets: budgets can only be set for expense categories of the user")
+
+	ErrDuplicateGoal = errors.New("goals: duplicate goal name")
+
+	ErrGoalCurrency = errors.New("goals: accounts funding a goal must hold the goal currency")
+
+	ErrDuplicateCounterparty = errors.New("loans: duplicate counterparty name")
+
+	ErrCounterpartyInUse = errors.New("loans: counterparty has loans")
+
+	ErrLoanCurrency = errors.New("loans: account must hold the loan currency")
+
+	ErrLoanOverpaid = errors.New("loans: payment is more than the outstanding amount")
+
+	ErrNotLoanAccount = errors.New("loan terms: only loan accounts have an amortization schedule")
+
+	ErrInstallmentsPaid = errors.New("loan terms: installment is already paid")
+
+	ErrUnknownMember = errors.New("households: no user with this email")
+
+	ErrDuplicateMember = errors.New("households: user is already a member or invited")
+
+	ErrHouseholdRole = errors.New("households: only owners can manage the household")
+
+	ErrLastOwner = errors.New("households: a household needs an owner")
+
+	ErrAttachmentType = errors.New("attachments: only images and PDF documents can be attached")
+
+	ErrAttachmentSize = errors.New("attachments: file is empty or too large")
 )
@@ -0,0 +1,341 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ExchangeRateModelInterface interface {
+	Insert(userId int, from, to Currency, date time.Time, rate float64, source string) (int, error)
+	Get(userId int, from, to Currency, date time.Time) (*ExchangeRate, error)
+	GetRecent(userId, limit int) ([]*ExchangeRate, error)
+	GetLatestPerPair(userId int) ([]*ExchangeRate, error)
+	Import(userId int, rates []ExchangeRate) (*RateImportReport, error)
+}
+
+// ExchangeRate says that on Date one unit of From was worth Rate units of To.
+// UserID is the user the rate belongs to, 0 for a rate shared by everyone.
+type ExchangeRate struct {
+	ID     int
+	UserID int
+	From   Currency
+	To     Currency
+	Date   time.Time
+	Rate   float64
+	Source string
+}
+
+const RateSourceManual = "manual"
+
+func (e ExchangeRate) DisplayDate() string {
+	return e.Date.Format("02-01-2006")
+}
+
+// Inverse returns the rate for the opposite direction, To -> From.
+func (e ExchangeRate) Inverse() ExchangeRate {
+	return ExchangeRate{
+		ID:     e.ID,
+		UserID: e.UserID,
+		From:   e.To,
+		To:     e.From,
+		Date:   e.Date,
+		Rate:   1 / e.Rate,
+		Source: e.Source,
+	}
+}
+
+type ExchangeRateModel struct {
+	DB *sql.DB
+}
+
+// Insert stores a rate of the user for a day, replacing any rate the user
+// already stored for the same currency pair and day. A userId of 0 stores a
+// shared rate.
+func (m *ExchangeRateModel) Insert(userId int, from, to Currency, date time.Time, rate float64, source string) (int, error) {
+	stmt := `
+	INSERT INTO exchange_rates (user_id, from_currency, to_currency, date, rate, source)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (IFNULL(user_id, 0), from_currency, to_currency, date)
+	DO UPDATE SET rate = excluded.rate, source = excluded.source
+	RETURNING id;`
+
+	var id int
+	err := m.DB.QueryRow(stmt, rateOwner(userId), from, to, date.Format("2006-01-02"), rate, source).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Get returns the rate for the pair on date, or the nearest earlier one when
+// there is no rate for that exact day (weekends, holidays). A stored rate for
+// the opposite direction is inverted when no direct rate exists, and as a last
+// resort the rate is crossed through one of the rateBases. The rates of the
+// user win over the shared ones of the same day.
+func (m *ExchangeRateModel) Get(userId int, from, to Currency, date time.Time) (*ExchangeRate, error) {
+	if from == to {
+		return &ExchangeRate{From: from, To: to, Date: date, Rate: 1}, nil
+	}
+
+	day := date.Format("2006-01-02")
+
+	rate, err := m.getPair(userId, from, to, day)
+	if err == nil || !errors.Is(err, ErrNoRecord) {
+		return rate, err
+	}
+
+	for _, base := range rateBases {
+		if base == from || base == to {
+			continue
+		}
+
+		first, err := m.getPair(userId, from, base, day)
+		if errors.Is(err, ErrNoRecord) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		second, err := m.getPair(userId, base, to, day)
+		if errors.Is(err, ErrNoRecord) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		// NOTE: A cross rate is only as recent as its older leg.
+		crossDate := first.Date
+		if second.Date.Before(crossDate) {
+			crossDate = second.Date
+		}
+
+		return &ExchangeRate{
+			From:   from,
+			To:     to,
+			Date:   crossDate,
+			Rate:   first.Rate * second.Rate,
+			Source: fmt.Sprintf("%s via %s", first.Source, base),
+		}, nil
+	}
+
+	return nil, ErrNoRecord
+}
+
+// rateBases are the currencies the import sources quote against, ECB against
+// EUR and NBS against RSD.
+var rateBases = []Currency{Euro, SerbianDinar}
+
+func (m *ExchangeRateModel) getPair(userId int, from, to Currency, day string) (*ExchangeRate, error) {
+	stmt := `
+	SELECT id, user_id, from_currency, to_currency, date, rate, source
+	FROM exchange_rates
+	WHERE (user_id = ? OR user_id IS NULL)
+	AND from_currency = ?
+	AND to_currency = ?
+	AND date <= ?
+	ORDER BY date DESC, user_id IS NULL
+	LIMIT 1;`
+
+	direct, err := scanExchangeRate(m.DB.QueryRow(stmt, userId, from, to, day))
+	if err != nil && !errors.Is(err, ErrNoRecord) {
+		return nil, err
+	}
+
+	inverse, err := scanExchangeRate(m.DB.QueryRow(stmt, userId, to, from, day))
+	if err != nil && !errors.Is(err, ErrNoRecord) {
+		return nil, err
+	}
+
+	switch {
+	case direct == nil && inverse == nil:
+		return nil, ErrNoRecord
+	case inverse == nil:
+		return direct, nil
+	case direct == nil || inverse.Date.After(direct.Date):
+		r := inverse.Inverse()
+		return &r, nil
+	default:
+		return direct, nil
+	}
+}
+
+// RateImportReport summarises an Import. A day counts as inserted when at
+// least one of its rates was new, and as skipped when all of them were
+// already stored.
+type RateImportReport struct {
+	DaysInserted      int
+	DaysSkipped       int
+	RatesInserted     int
+	RatesSkipped      int
+	UnknownCurrencies []Currency
+}
+
+func (r RateImportReport) String() string {
+	s := fmt.Sprintf("%d days imported (%d rates), %d days already present (%d rates skipped)",
+		r.DaysInserted, r.RatesInserted, r.DaysSkipped, r.RatesSkipped)
+	if len(r.UnknownCurrencies) > 0 {
+		s += fmt.Sprintf(", unknown currencies ignored: %v", r.UnknownCurrencies)
+	}
+	return s
+}
+
+// Import stores rates of the user in a single transaction, a userId of 0
+// stores shared rates. Rates already stored for the same owner, pair and day
+// are left untouched, so importing the same file twice is a no-op and
+// manually entered rates are never overwritten.
+func (m *ExchangeRateModel) Import(userId int, rates []ExchangeRate) (*RateImportReport, error) {
+	stmt := `
+	INSERT INTO exchange_rates (user_id, from_currency, to_currency, date, rate, source)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (IFNULL(user_id, 0), from_currency, to_currency, date) DO NOTHING;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.Prepare(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer insert.Close()
+
+	report := &RateImportReport{}
+	unknown := map[Currency]bool{}
+	insertedByDay := map[string]bool{}
+
+	for _, r := range rates {
+		for _, c := range []Currency{r.From, r.To} {
+			if !c.Known() && !unknown[c] {
+				unknown[c] = true
+				report.UnknownCurrencies = append(report.UnknownCurrencies, c)
+			}
+		}
+		if unknown[r.From] || unknown[r.To] {
+			report.RatesSkipped++
+			continue
+		}
+
+		day := r.Date.Format("2006-01-02")
+		if _, ok := insertedByDay[day]; !ok {
+			insertedByDay[day] = false
+		}
+
+		result, err := insert.Exec(rateOwner(userId), r.From, r.To, day, r.Rate, r.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+
+		if n == 0 {
+			report.RatesSkipped++
+		} else {
+			report.RatesInserted++
+			insertedByDay[day] = true
+		}
+	}
+
+	for _, inserted := range insertedByDay {
+		if inserted {
+			report.DaysInserted++
+		} else {
+			report.DaysSkipped++
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// GetRecent returns the newest rates the user can use, their own and the
+// shared ones.
+func (m *ExchangeRateModel) GetRecent(userId, limit int) ([]*ExchangeRate, error) {
+	stmt := `
+	SELECT id, user_id, from_currency, to_currency, date, rate, source
+	FROM exchange_rates
+	WHERE user_id = ? OR user_id IS NULL
+	ORDER BY date DESC, from_currency, to_currency, user_id IS NULL
+	LIMIT ?;`
+
+	rows, err := m.DB.Query(stmt, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanExchangeRates(rows)
+}
+
+// GetLatestPerPair returns the newest rate the user can use for every pair,
+// their own rate when they have one for the same day as the shared one.
+func (m *ExchangeRateModel) GetLatestPerPair(userId int) ([]*ExchangeRate, error) {
+	stmt := `
+	SELECT id, user_id, from_currency, to_currency, date, rate, source
+	FROM (
+		SELECT *, ROW_NUMBER() OVER (
+			PARTITION BY from_currency, to_currency
+			ORDER BY date DESC, user_id IS NULL
+		) AS n
+		FROM exchange_rates
+		WHERE user_id = ? OR user_id IS NULL
+	)
+	WHERE n = 1
+	ORDER BY from_currency, to_currency;`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanExchangeRates(rows)
+}
+
+func scanExchangeRate(row rowScanner) (*ExchangeRate, error) {
+	e := &ExchangeRate{}
+	var userId sql.NullInt64
+	err := row.Scan(&e.ID, &userId, &e.From, &e.To, &e.Date, &e.Rate, &e.Source)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	e.UserID = int(userId.Int64)
+	return e, nil
+}
+
+// rateOwner is the user_id stored with a rate, NULL for a shared rate.
+func rateOwner(userId int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(userId), Valid: userId != 0}
+}
+
+func scanExchangeRates(rows *sql.Rows) ([]*ExchangeRate, error) {
+	rates := []*ExchangeRate{}
+
+	for rows.Next() {
+		e, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestExchangeRateModelGet(t *testing.T) {
+	db := newTestDB(t)
+	m := &ExchangeRateModel{DB: db}
+	userId := newTestAccount(t, db, Euro).UserId
+	otherId := newTestAccount(t, db, Euro).UserId
+
+	day := func(d int) time.Time {
+		return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, r := range []struct {
+		from, to Currency
+		date     time.Time
+		rate     float64
+	}{
+		{Euro, SerbianDinar, day(2), 117.1},
+		{Euro, SerbianDinar, day(5), 117.2},
+		{SerbianDinar, Euro, day(9), 0.008},
+	} {
+		_, err := m.Insert(userId, r.from, r.to, r.date, r.rate, RateSourceManual)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		from, to Currency
+		date     time.Time
+		wantRate float64
+		wantDate time.Time
+		wantErr  error
+	}{
+		{name: "Exact day", from: Euro, to: SerbianDinar, date: day(2), wantRate: 117.1, wantDate: day(2)},
+		{name: "Nearest earlier", from: Euro, to: SerbianDinar, date: day(7), wantRate: 117.2, wantDate: day(5)},
+		{name: "Newer inverse wins", from: Euro, to: SerbianDinar, date: day(10), wantRate: 125, wantDate: day(9)},
+		{name: "Inverse only", from: SerbianDinar, to: Euro, date: day(3), wantRate: 1 / 117.1, wantDate: day(2)},
+		{name: "Before first rate", from: Euro, to: SerbianDinar, date: day(1), wantErr: ErrNoRecord},
+		{name: "Same currency", from: Euro, to: Euro, date: day(1), wantRate: 1, wantDate: day(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := m.Get(userId, tt.from, tt.to, tt.date)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got err %v; want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			assert.Equal(t, rate.Rate, tt.wantRate)
+			assert.Equal(t, rate.Date.Equal(tt.wantDate), true)
+		})
+	}
+
+	before, err := m.Get(userId, Euro, SerbianDinar, day(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := m.Insert(userId, Euro, SerbianDinar, day(5), 117.3, RateSourceManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, before.ID)
+	rate, err := m.Get(userId, Euro, SerbianDinar, day(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rate.Rate, 117.3)
+
+	// NOTE: The rates of a user are theirs alone, shared rates are used by
+	// everyone unless they have their own for the day.
+	_, err = m.Get(otherId, Euro, SerbianDinar, day(5))
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	_, err = m.Insert(0, Euro, SerbianDinar, day(5), 117.5, "ecb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rate, err = m.Get(otherId, Euro, SerbianDinar, day(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rate.Rate, 117.5)
+	assert.Equal(t, rate.UserID, 0)
+	rate, err = m.Get(userId, Euro, SerbianDinar, day(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rate.Rate, 117.3)
+
+	latest, err := m.GetLatestPerPair(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(latest), 2)
+	assert.Equal(t, latest[0].Rate, 117.3)
+	latest, err = m.GetLatestPerPair(otherId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(latest), 1)
+	assert.Equal(t, latest[0].Rate, 117.5)
+}
+
+func TestExchangeRateModelImport(t *testing.T) {
+	db := newTestDB(t)
+	err := (&CurrencyModel{DB: db}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &ExchangeRateModel{DB: db}
+
+	day := func(d int) time.Time {
+		return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	_, err = m.Insert(0, Euro, "USD", day(3), 1.5, RateSourceManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rates := []ExchangeRate{
+		{From: Euro, To: "USD", Date: day(2), Rate: 1.08, Source: "ecb"},
+		{From: Euro, To: "XXX", Date: day(2), Rate: 2, Source: "ecb"},
+		{From: Euro, To: "USD", Date: day(3), Rate: 1.09, Source: "ecb"},
+		{From: Euro, To: "JPY", Date: day(2), Rate: 160, Source: "ecb"},
+	}
+
+	report, err := m.Import(0, rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, report.RatesInserted, 2)
+	assert.Equal(t, report.RatesSkipped, 2)
+	assert.Equal(t, report.DaysInserted, 1)
+	assert.Equal(t, report.DaysSkipped, 1)
+	assert.Equal(t, len(report.UnknownCurrencies), 1)
+
+	// NOTE: The manual rate must survive the import.
+	rate, err := m.Get(0, Euro, "USD", day(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rate.Rate, 1.5)
+
+	report, err = m.Import(0, rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, report.RatesInserted, 0)
+	assert.Equal(t, report.DaysInserted, 0)
+	assert.Equal(t, report.DaysSkipped, 2)
+
+	// NOTE: JPY -> EUR -> USD, dated by the older JPY leg.
+	rate, err = m.Get(0, "JPY", "USD", day(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpyToEur := 1 / rates[3].Rate
+	assert.Equal(t, rate.Rate, jpyToEur*1.5)
+	assert.Equal(t, rate.Date.Equal(day(2)), true)
+
+	// NOTE: An upload on the web only stores rates for the user uploading.
+	userId := newTestAccount(t, db, Euro).UserId
+	report, err = m.Import(userId, []ExchangeRate{{From: Euro, To: "USD", Date: day(3), Rate: 1.2, Source: "ecb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, report.RatesInserted, 1)
+	rate, err = m.Get(userId, Euro, "USD", day(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rate.Rate, 1.2)
+	rate, err = m.Get(0, Euro, "USD", day(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rate.Rate, 1.5)
+}
@@ -10,19 +10,41 @@ type TransactionCreateForm struct {
 	UserId          int
 	AccountId       int
 	Date            time.Time
-	Amount          float64
+	Amount          Money
 	Category        string
 	Description     string
-	Currency        int
+	Currency        Currency
 	TransactionType int
+	Tags            []string
+	// Splits, when set, must add up to Amount.
+	Splits []Split
+	// PayeeID is zero for none, Payee the name typed into the form.
+	PayeeID int
+	Payee   string
+	// RecurringRuleID is set when the transaction is posted for an
+	// occurrence of a recurring rule, Date being the occurrence.
+	RecurringRuleID int
 	validator.Validator
 }
 
 type TransferCreateForm struct {
+	// UserId is the member making the transfer, it must be able to edit both
+	// accounts.
+	UserId     int
 	FromAcc    Account
-	FromAmount float64
+	FromAmount Money
 	ToAcc      Account
-	ToAmount   float64
-	Date       time.Time
+	ToAmount   Money
+	// Fee is charged to FromAcc on top of FromAmount, zero for none.
+	Fee  Money
+	Date time.Time
+	// Rate is the number of ToAcc currency units for one FromAcc currency
+	// unit, RateDate is the day the stored rate was published for.
+	Rate         float64
+	RateDate     time.Time
+	RateOverride bool
+	// Tags go on both sides of the transfer, a goal can be funded by the
+	// transfers carrying its tag.
+	Tags []string
 	validator.Validator
 }
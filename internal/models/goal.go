@@ -0,0 +1,331 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type GoalModelInterface interface {
+	Insert(g Goal) (int, error)
+	Get(userId, id int) (*Goal, error)
+	GetAll(userId int, since time.Time) ([]*Goal, error)
+	Update(g Goal) error
+	Delete(userId, id int) error
+}
+
+// Goal is an amount to be saved, optionally by a deadline.
+type Goal struct {
+	ID     int
+	UserID int
+	Name   string
+	Target Money
+	// Deadline is zero for goals without one.
+	Deadline time.Time
+	// Tag funds the goal with the transfers carrying it, blank for none.
+	Tag        string
+	AccountIDs []int
+	Created    time.Time
+	// Saved is the balance of the accounts plus the tagged transfers into
+	// other accounts, Recent the part of Saved that came in since the time
+	// given to GetAll. Get leaves both zero.
+	Saved  Money
+	Recent Money
+}
+
+type GoalModel struct {
+	DB *sql.DB
+}
+
+const goalSelect = `
+	SELECT g.id, g.user_id, g.name, g.target, g.currency, g.deadline, g.tag, g.created
+	FROM goals g`
+
+func (m *GoalModel) Insert(g Goal) (int, error) {
+	stmt := `
+	INSERT INTO goals (user_id, name, target, currency, deadline, tag, created)
+	VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, g.UserID, g.Name, g.Target.Minor, g.Target.Currency, nullDate(g.Deadline), g.Tag, time.Now().UTC())
+	if err != nil {
+		return 0, mapGoalError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = setGoalAccounts(tx, g.UserID, int(id), g.Target.Currency, g.AccountIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (m *GoalModel) Get(userId, id int) (*Goal, error) {
+	stmt := goalSelect + ` WHERE g.user_id = ? AND g.id = ?`
+
+	goals, err := m.query(stmt, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(goals) == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return goals[0], nil
+}
+
+// GetAll returns the goals of the user with what was saved for them, the
+// ones with the nearest deadline first.
+func (m *GoalModel) GetAll(userId int, since time.Time) ([]*Goal, error) {
+	stmt := goalSelect + `
+	WHERE g.user_id = ?
+	ORDER BY g.deadline IS NULL, g.deadline, g.name;`
+
+	goals, err := m.query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.loadFunds(userId, goals, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+func (m *GoalModel) Update(g Goal) error {
+	stmt := `
+	UPDATE goals SET name = ?, target = ?, currency = ?, deadline = ?, tag = ?
+	WHERE user_id = ? AND id = ?;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, g.Name, g.Target.Minor, g.Target.Currency, nullDate(g.Deadline), g.Tag, g.UserID, g.ID)
+	if err != nil {
+		return mapGoalError(err)
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	err = setGoalAccounts(tx, g.UserID, g.ID, g.Target.Currency, g.AccountIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a goal, its accounts and transfers are kept.
+func (m *GoalModel) Delete(userId, id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM goal_accounts WHERE goal_id IN (SELECT id FROM goals WHERE user_id = ? AND id = ?)`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM goals WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadFunds fills in Saved and Recent of the goals of the user in one query.
+// Tagged transfers into the goal's own accounts are already part of their
+// balance and are not counted twice.
+func (m *GoalModel) loadFunds(userId int, goals []*Goal, since time.Time) error {
+	stmt := `
+	SELECT
+		g.id,
+		COALESCE((
+			SELECT SUM(ab.balance)
+			FROM goal_accounts ga
+			JOIN account_balances ab ON ab.account_id = ga.account_id
+			WHERE ga.goal_id = g.id
+		), 0),
+		COALESCE((
+			SELECT SUM(CASE WHEN t.transaction_type IN (?, ?, ?, ?) THEN -t.amount ELSE t.amount END)
+			FROM goal_accounts ga
+			JOIN transactions t ON t.account_id = ga.account_id
+			WHERE ga.goal_id = g.id
+				AND t.deleted_at IS NULL
+				AND t.date >= ?
+		), 0),
+		COALESCE(SUM(t.amount), 0),
+		COALESCE(SUM(CASE WHEN t.date >= ? THEN t.amount ELSE 0 END), 0)
+	FROM goals g
+	LEFT JOIN tags tg ON tg.user_id = g.user_id AND tg.name = g.tag
+	LEFT JOIN transaction_tags tt ON tt.tag_id = tg.id
+	LEFT JOIN transactions t ON t.id = tt.transaction_id
+		AND t.user_id = g.user_id
+		AND t.transaction_type = ?
+		AND t.currency = g.currency
+		AND t.deleted_at IS NULL
+		AND t.account_id NOT IN (SELECT account_id FROM goal_accounts WHERE goal_id = g.id)
+	WHERE g.user_id = ?
+	GROUP BY g.id;`
+
+	rows, err := m.DB.Query(stmt, Expense, TransferIn, RebalanceOut, LoanOut, since, since, TransferOut, userId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byId := make(map[int]*Goal, len(goals))
+	for _, g := range goals {
+		byId[g.ID] = g
+	}
+
+	for rows.Next() {
+		var id int
+		var balance, recentBalance, tagged, recentTagged int64
+		err := rows.Scan(&id, &balance, &recentBalance, &tagged, &recentTagged)
+		if err != nil {
+			return err
+		}
+		if g, ok := byId[id]; ok {
+			g.Saved = NewMoney(balance+tagged, g.Target.Currency)
+			g.Recent = NewMoney(recentBalance+recentTagged, g.Target.Currency)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (m *GoalModel) query(stmt string, args ...any) ([]*Goal, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []*Goal{}
+	for rows.Next() {
+		g := &Goal{}
+		var deadline sql.NullTime
+		err := rows.Scan(&g.ID, &g.UserID, &g.Name, &g.Target.Minor, &g.Target.Currency, &deadline, &g.Tag, &g.Created)
+		if err != nil {
+			return nil, err
+		}
+		if deadline.Valid {
+			g.Deadline = dateOf(deadline.Time)
+		}
+		g.Saved = NewMoney(0, g.Target.Currency)
+		g.Recent = NewMoney(0, g.Target.Currency)
+		goals = append(goals, g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, g := range goals {
+		g.AccountIDs, err = m.accountIds(g.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return goals, nil
+}
+
+func (m *GoalModel) accountIds(goalId int) ([]int, error) {
+	rows, err := m.DB.Query(`SELECT account_id FROM goal_accounts WHERE goal_id = ? ORDER BY account_id`, goalId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// setGoalAccounts replaces the accounts funding a goal. They have to be
+// accounts the user can edit in the goal currency.
+func setGoalAccounts(tx *sql.Tx, userId, goalId int, currency Currency, accountIds []int) error {
+	_, err := tx.Exec(`DELETE FROM goal_accounts WHERE goal_id = ?`, goalId)
+	if err != nil {
+		return err
+	}
+
+	for _, accountId := range accountIds {
+		var accCurrency Currency
+		err := tx.QueryRow(`
+		SELECT a.currency FROM accounts a
+		JOIN account_access aa ON aa.account_id = a.id
+		WHERE aa.user_id = ? AND a.id = ? AND aa.role != ?`, userId, accountId, RoleViewer).Scan(&accCurrency)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrAccountDoesNotExist
+			}
+			return err
+		}
+		if accCurrency != currency {
+			return ErrGoalCurrency
+		}
+
+		_, err = tx.Exec(`INSERT OR IGNORE INTO goal_accounts (goal_id, account_id) VALUES (?, ?)`, goalId, accountId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mapGoalError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrDuplicateGoal
+	}
+	return err
+}
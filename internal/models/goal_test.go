@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestGoalModel(t *testing.T) {
+	db := newTestDB(t)
+	goals := &GoalModel{DB: db}
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	savings := newTestAccount(t, db, Euro)
+	checkingId, err := accounts.Insert(savings.UserId, "Checking", Euro, Checking, NewMoney(0, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checking, err := accounts.Get(savings.UserId, checkingId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dinarsId, err := accounts.Insert(savings.UserId, "Dinars", SerbianDinar, Checking, NewMoney(0, SerbianDinar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = transactions.Insert(testTransaction(savings, Income, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transactions.Insert(testTransaction(checking, Income, 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goal := Goal{UserID: savings.UserId, Name: "Car", Target: NewMoney(100000, Euro), Tag: "car", AccountIDs: []int{savings.ID}}
+	id, err := goals.Insert(goal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = goals.Insert(goal)
+	assert.Equal(t, errors.Is(err, ErrDuplicateGoal), true)
+
+	goal.Name = "Trip"
+	goal.AccountIDs = []int{dinarsId}
+	_, err = goals.Insert(goal)
+	assert.Equal(t, errors.Is(err, ErrGoalCurrency), true)
+
+	// NOTE: A tagged transfer into another account counts, one into a funding
+	// account is already part of its balance.
+	transfer := func(from, to *Account, minor int64) {
+		_, err := transactions.InsertTransfer(TransferCreateForm{
+			UserId:     from.UserId,
+			FromAcc:    *from,
+			ToAcc:      *to,
+			Date:       time.Now().UTC(),
+			FromAmount: NewMoney(minor, Euro),
+			ToAmount:   NewMoney(minor, Euro),
+			Rate:       1,
+			Tags:       []string{"car"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	transfer(savings, checking, 2000)
+	transfer(checking, savings, 1000)
+
+	all, err := goals.GetAll(savings.UserId, time.Now().AddDate(0, -3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 1)
+	assert.Equal(t, all[0].Saved, NewMoney(11000, Euro))
+	assert.Equal(t, all[0].Recent, NewMoney(11000, Euro))
+
+	all, err = goals.GetAll(savings.UserId, time.Now().AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, all[0].Recent, NewMoney(0, Euro))
+
+	goal.ID = id
+	goal.Name = "Car"
+	goal.Deadline = time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	goal.AccountIDs = []int{savings.ID, checking.ID}
+	err = goals.Update(goal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := goals.Get(savings.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got.Deadline, goal.Deadline)
+	assert.Equal(t, len(got.AccountIDs), 2)
+
+	_, err = goals.Get(savings.UserId+1, id)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	err = goals.Delete(savings.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = goals.Delete(savings.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+}
@@ -0,0 +1,501 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type HouseholdModelInterface interface {
+	Insert(userId int, name string) (int, error)
+	Get(userId, id int) (*Household, error)
+	GetAll(userId int) ([]*Household, error)
+	Invite(userId, id int, email string, role Role) error
+	Accept(userId, id int) error
+	Leave(userId, id int) error
+	SetRole(userId, id, memberId int, role Role) error
+	RemoveMember(userId, id, memberId int) error
+	Delete(userId, id int) error
+	ShareAccount(userId, id, accountId int) error
+	UnshareAccount(userId, accountId int) error
+	GetMemberNames(userId int) (map[int]string, error)
+}
+
+// Role is what a member may do in a household. On an account it is what the
+// user reading it may do, see the account_access view.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+var Roles = []Role{RoleOwner, RoleEditor, RoleViewer}
+
+func (r Role) Known() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether the role may do what needs the other role. Owners
+// may do anything an editor may and editors anything a viewer may.
+func (r Role) Allows(need Role) bool {
+	return r.rank() >= need.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Household is a book shared by its members. Role and Accepted are those of
+// the user reading it.
+type Household struct {
+	ID       int
+	Name     string
+	Created  time.Time
+	Role     Role
+	Accepted bool
+	Members  []Member
+	// Accounts are the accounts shared with the household.
+	Accounts []*Account
+}
+
+type Member struct {
+	UserID int
+	Name   string
+	Email  string
+	Role   Role
+	// AcceptedAt is zero while the invitation is pending.
+	InvitedAt  time.Time
+	AcceptedAt time.Time
+}
+
+func (m Member) Pending() bool {
+	return m.AcceptedAt.IsZero()
+}
+
+type HouseholdModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a household with the user as its owner.
+func (m *HouseholdModel) Insert(userId int, name string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(`INSERT INTO households (name, created) VALUES (?, ?)`, name, now)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO household_members (household_id, user_id, role, invited_at, accepted_at)
+	VALUES (?, ?, ?, ?, ?)`, id, userId, RoleOwner, now, now)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const householdSelect = `
+	SELECT h.id, h.name, h.created, hm.role, hm.accepted_at IS NOT NULL
+	FROM households h
+	JOIN household_members hm ON hm.household_id = h.id`
+
+// Get returns a household the user is a member of, with its members and
+// shared accounts. Pending invitations are only listed by GetAll.
+func (m *HouseholdModel) Get(userId, id int) (*Household, error) {
+	stmt := householdSelect + `
+	WHERE hm.user_id = ?
+	AND h.id = ?
+	AND hm.accepted_at IS NOT NULL`
+
+	h := &Household{}
+	err := m.DB.QueryRow(stmt, userId, id).Scan(&h.ID, &h.Name, &h.Created, &h.Role, &h.Accepted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`
+	SELECT u.id, u.name, u.email, hm.role, hm.invited_at, hm.accepted_at
+	FROM household_members hm
+	JOIN users u ON u.id = hm.user_id
+	WHERE hm.household_id = ?
+	ORDER BY hm.accepted_at IS NULL, u.name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	h.Members = []Member{}
+	for rows.Next() {
+		var member Member
+		var acceptedAt sql.NullTime
+		err := rows.Scan(&member.UserID, &member.Name, &member.Email, &member.Role, &member.InvitedAt, &acceptedAt)
+		if err != nil {
+			return nil, err
+		}
+		member.AcceptedAt = acceptedAt.Time
+		h.Members = append(h.Members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	accounts := &AccountModel{DB: m.DB}
+	h.Accounts, err = accounts.query(accountSelect+`
+	WHERE aa.user_id = ?
+	AND a.household_id = ?
+	ORDER BY a.account_name`, userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// GetAll returns the households of the user, invitations still to accept
+// included.
+func (m *HouseholdModel) GetAll(userId int) ([]*Household, error) {
+	stmt := householdSelect + `
+	WHERE hm.user_id = ?
+	ORDER BY hm.accepted_at IS NULL DESC, h.name`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	households := []*Household{}
+	for rows.Next() {
+		h := &Household{}
+		err := rows.Scan(&h.ID, &h.Name, &h.Created, &h.Role, &h.Accepted)
+		if err != nil {
+			return nil, err
+		}
+		households = append(households, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return households, nil
+}
+
+// Invite adds the user with the email as a pending member, only owners can
+// invite.
+func (m *HouseholdModel) Invite(userId, id int, email string, role Role) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkHouseholdOwner(tx, userId, id)
+	if err != nil {
+		return err
+	}
+
+	var inviteeId int
+	err = tx.QueryRow(`SELECT id FROM users WHERE email = ?`, strings.TrimSpace(email)).Scan(&inviteeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUnknownMember
+		}
+		return err
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO household_members (household_id, user_id, role, invited_at)
+	VALUES (?, ?, ?, ?)`, id, inviteeId, role, time.Now().UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return ErrDuplicateMember
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Accept makes a pending invitation a membership.
+func (m *HouseholdModel) Accept(userId, id int) error {
+	stmt := `
+	UPDATE household_members SET accepted_at = ?
+	WHERE household_id = ?
+	AND user_id = ?
+	AND accepted_at IS NULL;`
+
+	result, err := m.DB.Exec(stmt, time.Now().UTC(), id, userId)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// Leave ends a membership or declines an invitation. The last owner has to
+// hand the household over or delete it instead.
+func (m *HouseholdModel) Leave(userId, id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = removeMember(tx, id, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetRole changes the role of a member, only owners can.
+func (m *HouseholdModel) SetRole(userId, id, memberId int, role Role) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkHouseholdOwner(tx, userId, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`UPDATE household_members SET role = ? WHERE household_id = ? AND user_id = ?`, role, id, memberId)
+	if err != nil {
+		return err
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	err = checkOwnerLeft(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveMember takes a member or an invitation out of the household, only
+// owners can.
+func (m *HouseholdModel) RemoveMember(userId, id, memberId int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkHouseholdOwner(tx, userId, id)
+	if err != nil {
+		return err
+	}
+
+	err = removeMember(tx, id, memberId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the household, the accounts shared with it go back to
+// their owners.
+func (m *HouseholdModel) Delete(userId, id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkHouseholdOwner(tx, userId, id)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: The foreign keys would do the same, the members and shares are
+	// cleared here as well so no access is left behind without them.
+	_, err = tx.Exec(`UPDATE accounts SET household_id = NULL WHERE household_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM household_members WHERE household_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM households WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ShareAccount shares an account of the user with a household the user is a
+// member of. An account already shared with another household moves.
+func (m *HouseholdModel) ShareAccount(userId, id, accountId int) error {
+	stmt := `
+	UPDATE accounts SET household_id = ?
+	WHERE user_id = ?
+	AND id = ?
+	AND EXISTS (
+		SELECT 1 FROM household_members
+		WHERE household_id = ? AND user_id = ? AND accepted_at IS NOT NULL
+	);`
+
+	result, err := m.DB.Exec(stmt, id, userId, accountId, id, userId)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// UnshareAccount makes an account of the user private again.
+func (m *HouseholdModel) UnshareAccount(userId, accountId int) error {
+	stmt := `
+	UPDATE accounts SET household_id = NULL
+	WHERE user_id = ?
+	AND id = ?
+	AND household_id IS NOT NULL;`
+
+	result, err := m.DB.Exec(stmt, userId, accountId)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// GetMemberNames returns the names of the users sharing a household with
+// the user, keyed by user id. The user is left out.
+func (m *HouseholdModel) GetMemberNames(userId int) (map[int]string, error) {
+	stmt := `
+	SELECT DISTINCT u.id, u.name
+	FROM household_members me
+	JOIN household_members hm ON hm.household_id = me.household_id
+	JOIN users u ON u.id = hm.user_id
+	WHERE me.user_id = ?
+	AND me.accepted_at IS NOT NULL
+	AND hm.accepted_at IS NOT NULL
+	AND hm.user_id != me.user_id;`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[int]string{}
+	for rows.Next() {
+		var id int
+		var name string
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// checkHouseholdOwner returns ErrNoRecord unless the user is a member of the
+// household and ErrHouseholdRole unless an owner.
+func checkHouseholdOwner(tx *sql.Tx, userId, id int) error {
+	var role Role
+	err := tx.QueryRow(`
+	SELECT role FROM household_members
+	WHERE household_id = ? AND user_id = ? AND accepted_at IS NOT NULL`, id, userId).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	if role != RoleOwner {
+		return ErrHouseholdRole
+	}
+	return nil
+}
+
+// checkOwnerLeft returns ErrLastOwner if no member would own the household.
+func checkOwnerLeft(tx *sql.Tx, id int) error {
+	var owners int
+	err := tx.QueryRow(`
+	SELECT COUNT(*) FROM household_members
+	WHERE household_id = ? AND role = ? AND accepted_at IS NOT NULL`, id, RoleOwner).Scan(&owners)
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// removeMember deletes a membership and takes the accounts the member shared
+// back out of the household.
+func removeMember(tx *sql.Tx, id, memberId int) error {
+	result, err := tx.Exec(`DELETE FROM household_members WHERE household_id = ? AND user_id = ?`, id, memberId)
+	if err != nil {
+		return err
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	err = checkOwnerLeft(tx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE accounts SET household_id = NULL WHERE household_id = ? AND user_id = ?`, id, memberId)
+	return err
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestHouseholdModel(t *testing.T) {
+	db := newTestDB(t)
+	households := &HouseholdModel{DB: db}
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+	users := &UserModel{DB: db}
+
+	owner := newTestAccount(t, db, Euro)
+	editor := newTestAccount(t, db, Euro)
+	viewer := newTestAccount(t, db, Euro)
+	editorUser, err := users.Get(editor.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewerUser, err := users.Get(viewer.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := households.Insert(owner.UserId, "Home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = households.Invite(owner.UserId, id, editorUser.Email, RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.Invite(owner.UserId, id, editorUser.Email, RoleViewer)
+	assert.Equal(t, errors.Is(err, ErrDuplicateMember), true)
+	err = households.Invite(owner.UserId, id, "nobody@example.com", RoleViewer)
+	assert.Equal(t, errors.Is(err, ErrUnknownMember), true)
+	err = households.Invite(owner.UserId, id, viewerUser.Email, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: Pending members see nothing yet.
+	_, err = households.Get(editor.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	all, err := households.GetAll(editor.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 1)
+	assert.Equal(t, all[0].Accepted, false)
+
+	err = households.Accept(editor.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.Accept(viewer.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.Invite(editor.UserId, id, viewerUser.Email, RoleViewer)
+	assert.Equal(t, errors.Is(err, ErrHouseholdRole), true)
+
+	err = households.ShareAccount(owner.UserId, id, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.ShareAccount(owner.UserId, id, editor.ID)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	shared, err := accounts.Get(editor.UserId, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, shared.Role, RoleEditor)
+	assert.Equal(t, shared.HouseholdID, id)
+	shared, err = accounts.Get(viewer.UserId, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, shared.Role, RoleViewer)
+	_, err = accounts.Get(owner.UserId, editor.ID)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	// NOTE: The editor adds to the shared account, the transaction is theirs.
+	tf := testTransaction(owner, Income, 5000)
+	tf.UserId = editor.UserId
+	txId, err := transactions.Insert(tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen, err := transactions.Get(viewer.UserId, txId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, seen.UserID, editor.UserId)
+	err = transactions.Delete(viewer.UserId, txId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	// NOTE: A transfer from the shared account to the editor's own account is
+	// the editor's, the owner can not reach the other leg.
+	from, err := accounts.Get(editor.UserId, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer := TransferCreateForm{
+		UserId:     editor.UserId,
+		FromAcc:    *from,
+		FromAmount: NewMoney(1000, Euro),
+		ToAcc:      *editor,
+		ToAmount:   NewMoney(1000, Euro),
+		Date:       time.Now().UTC(),
+		Rate:       1,
+		Tags:       []string{"rent"},
+	}
+	trId, err := transactions.InsertTransfer(transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := transactions.GetTransfer(editor.UserId, trId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tr.UserID, editor.UserId)
+	assert.Equal(t, tr.From.UserID, editor.UserId)
+	assert.Equal(t, tr.To.UserID, editor.UserId)
+	assert.Equal(t, len(tr.Tags), 1)
+	_, err = transactions.GetTransfer(owner.UserId, trId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	err = transactions.DeleteTransfer(owner.UserId, trId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	listed, err := transactions.GetTransfers(owner.UserId, time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(listed), 0)
+
+	transfer.UserId = viewer.UserId
+	_, err = transactions.InsertTransfer(transfer)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	transfer.UserId = owner.UserId
+	err = transactions.UpdateTransfer(trId, transfer)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	transfer.UserId = editor.UserId
+	transfer.FromAmount = NewMoney(2000, Euro)
+	transfer.ToAmount = NewMoney(2000, Euro)
+	err = transactions.UpdateTransfer(trId, transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine, err := accounts.Get(editor.UserId, editor.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mine.Balance, NewMoney(2000, Euro))
+	err = transactions.DeleteTransfer(editor.UserId, trId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: The rules of a member only post while they can edit the account.
+	recurring := &RecurringModel{DB: db}
+	_, err = recurring.Insert(RecurringRule{
+		UserID:          editor.UserId,
+		AccountID:       owner.ID,
+		TransactionType: Income,
+		Amount:          NewMoney(100, Euro),
+		Frequency:       Monthly,
+		Interval:        1,
+		Day:             1,
+		StartDate:       time.Now().UTC().AddDate(0, -1, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err := recurring.GetDue(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(due) > 0, true)
+	err = households.SetRole(owner.UserId, id, editor.UserId, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err = recurring.GetDue(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(due), 0)
+	err = households.SetRole(owner.UserId, id, editor.UserId, RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := households.GetMemberNames(owner.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(names), 2)
+
+	err = households.SetRole(owner.UserId, id, owner.UserId, RoleEditor)
+	assert.Equal(t, errors.Is(err, ErrLastOwner), true)
+	err = households.Leave(owner.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrLastOwner), true)
+
+	// NOTE: Leaving takes the shared accounts along, the transaction stays
+	// with its creator.
+	err = households.SetRole(owner.UserId, id, editor.UserId, RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = households.Leave(owner.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = accounts.Get(viewer.UserId, owner.ID)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	_, err = transactions.Get(viewer.UserId, txId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	_, err = transactions.Get(editor.UserId, txId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transactions.Delete(editor.UserId, txId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	account, err := accounts.Get(owner.UserId, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, account.HouseholdID, 0)
+	assert.Equal(t, account.Balance, NewMoney(5000, Euro))
+
+	err = households.Delete(viewer.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrHouseholdRole), true)
+	err = households.Delete(editor.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err = households.GetAll(viewer.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 0)
+}
@@ -0,0 +1,509 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type LoanModelInterface interface {
+	InsertCounterparty(userId int, name, note string) (int, error)
+	GetCounterparty(userId, id int) (*Counterparty, error)
+	GetCounterparties(userId int) ([]*Counterparty, error)
+	DeleteCounterparty(userId, id int) error
+	Insert(l PersonalLoan, account Account) (int, error)
+	Get(userId, id int) (*PersonalLoan, error)
+	GetAll(userId int) ([]*PersonalLoan, error)
+	GetByCounterparty(userId, counterpartyId int) ([]*PersonalLoan, error)
+	Repay(userId, id int, account Account, amount Money, date time.Time) (int, error)
+	DeletePayment(userId, id, transactionId int) error
+	Delete(userId, id int) error
+}
+
+// Counterparty is a person money is lent to or borrowed from.
+type Counterparty struct {
+	ID     int
+	UserID int
+	Name   string
+	Note   string
+	// Count is the number of loans with the counterparty.
+	Count int
+}
+
+type LoanDirection string
+
+const (
+	// Lent loans are owed to the user.
+	Lent LoanDirection = "lent"
+	// Borrowed loans are owed by the user.
+	Borrowed LoanDirection = "borrowed"
+)
+
+func (d LoanDirection) Known() bool {
+	return d == Lent || d == Borrowed
+}
+
+// disbursement is the type of the transaction paying out the loan.
+func (d LoanDirection) disbursement() TransactionType {
+	if d == Lent {
+		return LoanOut
+	}
+	return LoanIn
+}
+
+// repayment is the type of the transactions paying the loan back.
+func (d LoanDirection) repayment() TransactionType {
+	if d == Lent {
+		return LoanIn
+	}
+	return LoanOut
+}
+
+// PersonalLoan is money lent to or borrowed from a counterparty. Paying it
+// out and back are transactions against the user's accounts.
+type PersonalLoan struct {
+	ID             int
+	UserID         int
+	CounterpartyID int
+	Counterparty   string
+	Direction      LoanDirection
+	Principal      Money
+	Date           time.Time
+	// DueDate is zero for loans without one.
+	DueDate     time.Time
+	Description string
+	Created     time.Time
+	// AccountID is the account the loan was paid out from or into.
+	AccountID int
+	Repaid    Money
+	// Payments are the repayments, only loaded by Get.
+	Payments []*Transaction
+}
+
+func (l *PersonalLoan) Outstanding() Money {
+	return l.Principal.Sub(l.Repaid)
+}
+
+// Overdue reports whether the due date has passed before the loan was paid
+// back.
+func (l *PersonalLoan) Overdue(now time.Time) bool {
+	return !l.DueDate.IsZero() && l.DueDate.Before(dateOf(now)) && l.Outstanding().Minor > 0
+}
+
+type LoanModel struct {
+	DB *sql.DB
+}
+
+func (m *LoanModel) InsertCounterparty(userId int, name, note string) (int, error) {
+	stmt := `INSERT INTO counterparties (user_id, name, note) VALUES (?, ?, ?)`
+
+	result, err := m.DB.Exec(stmt, userId, name, note)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, ErrDuplicateCounterparty
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const counterpartySelect = `
+	SELECT c.id, c.user_id, c.name, c.note, (SELECT COUNT(*) FROM loans l WHERE l.counterparty_id = c.id)
+	FROM counterparties c`
+
+func (m *LoanModel) GetCounterparty(userId, id int) (*Counterparty, error) {
+	c := &Counterparty{}
+	err := m.DB.QueryRow(counterpartySelect+` WHERE c.user_id = ? AND c.id = ?`, userId, id).
+		Scan(&c.ID, &c.UserID, &c.Name, &c.Note, &c.Count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (m *LoanModel) GetCounterparties(userId int) ([]*Counterparty, error) {
+	rows, err := m.DB.Query(counterpartySelect+` WHERE c.user_id = ? ORDER BY c.name`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counterparties := []*Counterparty{}
+	for rows.Next() {
+		c := &Counterparty{}
+		err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Note, &c.Count)
+		if err != nil {
+			return nil, err
+		}
+		counterparties = append(counterparties, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counterparties, nil
+}
+
+// DeleteCounterparty removes a counterparty without loans.
+func (m *LoanModel) DeleteCounterparty(userId, id int) error {
+	stmt := `
+	DELETE FROM counterparties
+	WHERE user_id = ? AND id = ?
+	AND NOT EXISTS (SELECT 1 FROM loans WHERE counterparty_id = counterparties.id);`
+
+	result, err := m.DB.Exec(stmt, userId, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		_, err := m.GetCounterparty(userId, id)
+		if err != nil {
+			return err
+		}
+		return ErrCounterpartyInUse
+	}
+
+	return nil
+}
+
+// Insert adds a loan and pays it out from or into the account, which has to
+// hold the loan currency.
+func (m *LoanModel) Insert(l PersonalLoan, account Account) (int, error) {
+	stmt := `
+	INSERT INTO loans (user_id, counterparty_id, direction, principal, currency, date, due_date, description, created)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	if account.Currency != l.Principal.Currency {
+		return 0, ErrLoanCurrency
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	var counterparty string
+	err = tx.QueryRow(`SELECT name FROM counterparties WHERE user_id = ? AND id = ?`, l.UserID, l.CounterpartyID).Scan(&counterparty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	result, err := tx.Exec(stmt, l.UserID, l.CounterpartyID, l.Direction, l.Principal.Minor, l.Principal.Currency,
+		l.Date, nullDate(l.DueDate), l.Description, time.Now().UTC())
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	desc := fmt.Sprintf("[L] borrowed from %s", counterparty)
+	if l.Direction == Lent {
+		desc = fmt.Sprintf("[L] lent to %s", counterparty)
+	}
+
+	_, err = insertLoanTransaction(tx, l.UserID, int(id), account, l.Principal, l.Date, desc, l.Direction.disbursement())
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	return int(id), nil
+}
+
+const loanSelect = `
+	SELECT l.id, l.user_id, l.counterparty_id, c.name, l.direction, l.principal, l.currency, l.date, l.due_date, l.description, l.created,
+		COALESCE((SELECT t.account_id FROM transactions t WHERE t.loan_id = l.id AND t.transaction_type = CASE l.direction WHEN 'lent' THEN ? ELSE ? END), 0),
+		COALESCE((SELECT SUM(t.amount) FROM transactions t WHERE t.loan_id = l.id AND t.transaction_type = CASE l.direction WHEN 'lent' THEN ? ELSE ? END), 0)
+	FROM loans l
+	JOIN counterparties c ON c.id = l.counterparty_id`
+
+func (m *LoanModel) Get(userId, id int) (*PersonalLoan, error) {
+	loans, err := m.query(loanSelect+` WHERE l.user_id = ? AND l.id = ?`, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(loans) == 0 {
+		return nil, ErrNoRecord
+	}
+	l := loans[0]
+
+	stmt := `
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
+	FROM transactions
+	WHERE loan_id = ?
+	AND transaction_type = ?
+	ORDER BY date, id;`
+
+	rows, err := m.DB.Query(stmt, l.ID, l.Direction.repayment())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	l.Payments, err = scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetAll returns the loans of the user, the ones with the nearest due date
+// first.
+func (m *LoanModel) GetAll(userId int) ([]*PersonalLoan, error) {
+	stmt := loanSelect + `
+	WHERE l.user_id = ?
+	ORDER BY l.due_date IS NULL, l.due_date, l.date;`
+
+	return m.query(stmt, userId)
+}
+
+func (m *LoanModel) GetByCounterparty(userId, counterpartyId int) ([]*PersonalLoan, error) {
+	stmt := loanSelect + `
+	WHERE l.user_id = ?
+	AND l.counterparty_id = ?
+	ORDER BY l.due_date IS NULL, l.due_date, l.date;`
+
+	return m.query(stmt, userId, counterpartyId)
+}
+
+// Repay records a payment of the loan from or into the account. Paying back
+// more than is outstanding is refused with ErrLoanOverpaid.
+func (m *LoanModel) Repay(userId, id int, account Account, amount Money, date time.Time) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	l := PersonalLoan{}
+	var repaid int64
+	err = tx.QueryRow(`
+	SELECT l.direction, l.principal, l.currency, c.name,
+		COALESCE((SELECT SUM(t.amount) FROM transactions t WHERE t.loan_id = l.id AND t.transaction_type = CASE l.direction WHEN 'lent' THEN ? ELSE ? END), 0)
+	FROM loans l
+	JOIN counterparties c ON c.id = l.counterparty_id
+	WHERE l.user_id = ? AND l.id = ?`, LoanIn, LoanOut, userId, id).
+		Scan(&l.Direction, &l.Principal.Minor, &l.Principal.Currency, &l.Counterparty, &repaid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, mapWriteError(err)
+	}
+
+	if account.Currency != l.Principal.Currency || amount.Currency != l.Principal.Currency {
+		return 0, ErrLoanCurrency
+	}
+	if repaid+amount.Minor > l.Principal.Minor {
+		return 0, ErrLoanOverpaid
+	}
+
+	desc := fmt.Sprintf("[L] repaid to %s", l.Counterparty)
+	if l.Direction == Lent {
+		desc = fmt.Sprintf("[L] repaid by %s", l.Counterparty)
+	}
+
+	paymentId, err := insertLoanTransaction(tx, userId, id, account, amount, date, desc, l.Direction.repayment())
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	return paymentId, nil
+}
+
+// DeletePayment removes a repayment recorded by mistake and takes it back
+// from its account.
+func (m *LoanModel) DeletePayment(userId, id, transactionId int) error {
+	stmt := `
+	SELECT t.id, t.account_id, t.user_id, t.date, t.amount, t.currency, t.category, t.description, t.transaction_type, t.transfer_id
+	FROM transactions t
+	JOIN loans l ON l.id = t.loan_id
+	WHERE l.user_id = ?
+	AND l.id = ?
+	AND t.id = ?
+	AND t.transaction_type = CASE l.direction WHEN 'lent' THEN ? ELSE ? END;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	t, err := scanTransaction(tx.QueryRow(stmt, userId, id, transactionId, LoanIn, LoanOut))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return mapWriteError(err)
+	}
+
+	err = deleteLoanTransaction(tx, t)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// Delete removes a loan together with its payout and repayments, their
+// amounts are taken back from the accounts.
+func (m *LoanModel) Delete(userId, id int) error {
+	stmt := `
+	SELECT t.id, t.account_id, t.user_id, t.date, t.amount, t.currency, t.category, t.description, t.transaction_type, t.transfer_id
+	FROM transactions t
+	JOIN loans l ON l.id = t.loan_id
+	WHERE l.user_id = ?
+	AND l.id = ?
+	ORDER BY t.id;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(stmt, userId, id)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	transactions, err := scanTransactions(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Repayments go first, so the money paid back on a borrowed loan is
+	// returned before its payout is taken from the account.
+	for i := len(transactions) - 1; i >= 0; i-- {
+		err = deleteLoanTransaction(tx, transactions[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM loans WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+func (m *LoanModel) query(stmt string, args ...any) ([]*PersonalLoan, error) {
+	args = append([]any{LoanOut, LoanIn, LoanIn, LoanOut}, args...)
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := []*PersonalLoan{}
+	for rows.Next() {
+		l := &PersonalLoan{}
+		var dueDate sql.NullTime
+		var repaid int64
+		err := rows.Scan(&l.ID, &l.UserID, &l.CounterpartyID, &l.Counterparty, &l.Direction, &l.Principal.Minor,
+			&l.Principal.Currency, &l.Date, &dueDate, &l.Description, &l.Created, &l.AccountID, &repaid)
+		if err != nil {
+			return nil, err
+		}
+		l.Date = dateOf(l.Date)
+		if dueDate.Valid {
+			l.DueDate = dateOf(dueDate.Time)
+		}
+		l.Repaid = NewMoney(repaid, l.Principal.Currency)
+		loans = append(loans, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return loans, nil
+}
+
+func insertLoanTransaction(tx *sql.Tx, userId, loanId int, account Account, amount Money, date time.Time, desc string, txType TransactionType) (int, error) {
+	stmt := `
+	INSERT INTO transactions (account_id, user_id, date, amount, currency, category, description, transaction_type, loan_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	result, err := tx.Exec(stmt, account.ID, userId, date, amount.Minor, account.Currency, "loan", desc, txType, loanId)
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	err = applyDelta(tx, account.ID, txType.Sign()*amount.Minor)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// deleteLoanTransaction removes a loan transaction for good, loan rows never
+// go to the trash.
+func deleteLoanTransaction(tx *sql.Tx, t *Transaction) error {
+	_, err := tx.Exec(`DELETE FROM transactions WHERE id = ?`, t.ID)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return applyDelta(tx, t.AccountID, -t.TransactionType.Sign()*t.Amount.Minor)
+}
@@ -0,0 +1,338 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type LoanTermsModelInterface interface {
+	Set(t LoanTerms) error
+	Get(userId, accountId int) (*LoanTerms, error)
+	Pay(userId, accountId, number int, from Account, date time.Time) error
+	Delete(userId, accountId int) error
+}
+
+// LoanTerms are the terms of a loan account paid back in equal monthly
+// installments.
+type LoanTerms struct {
+	AccountID int
+	UserID    int
+	Principal Money
+	// Rate is the yearly interest rate in percent.
+	Rate   float64
+	Months int
+	// FirstPayment is the date of the first installment, the others follow
+	// monthly on the same day.
+	FirstPayment     time.Time
+	InterestCategory string
+	// Paid holds the numbers of the installments paid so far.
+	Paid []int
+}
+
+// Installment is one monthly payment of a loan, split into interest and
+// principal. Remaining is the principal left once it is paid.
+type Installment struct {
+	Number    int
+	Date      time.Time
+	Payment   Money
+	Interest  Money
+	Principal Money
+	Remaining Money
+	Paid      bool
+}
+
+// Schedule returns the installments of the loan. Interest is charged monthly
+// on the remaining principal, the last installment pays off what is left
+// after rounding.
+func (t LoanTerms) Schedule() []Installment {
+	schedule := make([]Installment, 0, t.Months)
+	if t.Months < 1 || t.Principal.Minor <= 0 {
+		return schedule
+	}
+
+	rate := t.Rate / 1200
+	principal := float64(t.Principal.Minor)
+	payment := principal / float64(t.Months)
+	if rate > 0 {
+		payment = principal * rate / (1 - math.Pow(1+rate, -float64(t.Months)))
+	}
+
+	first := dateOf(t.FirstPayment)
+	remaining := t.Principal.Minor
+	for n := 1; n <= t.Months; n++ {
+		interest := int64(math.Round(float64(remaining) * rate))
+		paid := int64(math.Round(payment)) - interest
+		if n == t.Months || paid > remaining {
+			paid = remaining
+		}
+		remaining -= paid
+
+		schedule = append(schedule, Installment{
+			Number:    n,
+			Date:      clampedDate(first.Year(), first.Month()+time.Month(n-1), first.Day()),
+			Payment:   NewMoney(interest+paid, t.Principal.Currency),
+			Interest:  NewMoney(interest, t.Principal.Currency),
+			Principal: NewMoney(paid, t.Principal.Currency),
+			Remaining: NewMoney(remaining, t.Principal.Currency),
+			Paid:      slices.Contains(t.Paid, n),
+		})
+	}
+
+	return schedule
+}
+
+// TotalInterest is the interest paid over the whole term.
+func (t LoanTerms) TotalInterest() Money {
+	total := NewMoney(0, t.Principal.Currency)
+	for _, i := range t.Schedule() {
+		total = total.Add(i.Interest)
+	}
+	return total
+}
+
+// NextInstallment returns the first installment not paid yet, false once the
+// loan is paid off.
+func (t LoanTerms) NextInstallment() (Installment, bool) {
+	for _, i := range t.Schedule() {
+		if !i.Paid {
+			return i, true
+		}
+	}
+	return Installment{}, false
+}
+
+type LoanTermsModel struct {
+	DB *sql.DB
+}
+
+// Set saves the terms of a loan account, raising its credit limit to the
+// principal so the balance can hold the debt. Terms can not change once an
+// installment is paid.
+func (m *LoanTermsModel) Set(t LoanTerms) error {
+	stmt := `
+	INSERT INTO loan_terms (account_id, principal, rate, months, first_payment, interest_category)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (account_id) DO UPDATE SET
+		principal = excluded.principal,
+		rate = excluded.rate,
+		months = excluded.months,
+		first_payment = excluded.first_payment,
+		interest_category = excluded.interest_category;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	var accountType AccountType
+	var currency Currency
+	err = tx.QueryRow(`SELECT account_type, currency FROM accounts WHERE user_id = ? AND id = ?`, t.UserID, t.AccountID).
+		Scan(&accountType, &currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return mapWriteError(err)
+	}
+	if accountType != Loan {
+		return ErrNotLoanAccount
+	}
+	if currency != t.Principal.Currency {
+		return ErrLoanCurrency
+	}
+
+	var paid bool
+	err = tx.QueryRow(`SELECT EXISTS (`+paidSelect+`)`, t.AccountID).Scan(&paid)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	if paid {
+		return ErrInstallmentsPaid
+	}
+
+	_, err = tx.Exec(stmt, t.AccountID, t.Principal.Minor, t.Rate, t.Months, dateOf(t.FirstPayment), t.InterestCategory)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	_, err = tx.Exec(`UPDATE accounts SET credit_limit = MAX(credit_limit, ?) WHERE id = ?`, t.Principal.Minor, t.AccountID)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+func (m *LoanTermsModel) Get(userId, accountId int) (*LoanTerms, error) {
+	stmt := `
+	SELECT lt.account_id, a.user_id, lt.principal, a.currency, lt.rate, lt.months, lt.first_payment, lt.interest_category
+	FROM loan_terms lt
+	JOIN accounts a ON a.id = lt.account_id
+	WHERE a.user_id = ?
+	AND lt.account_id = ?;`
+
+	t := &LoanTerms{}
+	err := m.DB.QueryRow(stmt, userId, accountId).Scan(&t.AccountID, &t.UserID, &t.Principal.Minor, &t.Principal.Currency,
+		&t.Rate, &t.Months, &t.FirstPayment, &t.InterestCategory)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	t.FirstPayment = dateOf(t.FirstPayment)
+
+	rows, err := m.DB.Query(paidSelect+` ORDER BY lp.number`, t.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t.Paid = []int{}
+	for rows.Next() {
+		var n int
+		err := rows.Scan(&n)
+		if err != nil {
+			return nil, err
+		}
+		t.Paid = append(t.Paid, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Pay pays an installment from the account: the interest as an expense in
+// the interest category and the principal as a transfer to the loan account.
+func (m *LoanTermsModel) Pay(userId, accountId, number int, from Account, date time.Time) error {
+	terms, err := m.Get(userId, accountId)
+	if err != nil {
+		return err
+	}
+	if number < 1 || number > terms.Months {
+		return ErrNoRecord
+	}
+	if slices.Contains(terms.Paid, number) {
+		return ErrInstallmentsPaid
+	}
+	if from.Currency != terms.Principal.Currency {
+		return ErrLoanCurrency
+	}
+	installment := terms.Schedule()[number-1]
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	loan, err := scanAccount(tx.QueryRow(accountSelect+` WHERE aa.user_id = ? AND a.id = ?`, userId, accountId))
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	// NOTE: The transfer of an earlier payment was deleted.
+	_, err = tx.Exec(`
+	DELETE FROM loan_payments
+	WHERE account_id = ? AND number = ?
+	AND (transfer_id IS NULL OR transfer_id IN (SELECT id FROM transfers WHERE deleted_at IS NOT NULL))`, accountId, number)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	var interestId any
+	if installment.Interest.Minor > 0 {
+		interestId, err = insertTransaction(tx, TransactionCreateForm{
+			UserId:          userId,
+			AccountId:       from.ID,
+			Date:            date,
+			Amount:          installment.Interest,
+			Currency:        from.Currency,
+			Category:        terms.InterestCategory,
+			Description:     fmt.Sprintf("interest of %s, installment %d", loan.AccountName, number),
+			TransactionType: int(Expense),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`INSERT INTO transfers (user_id, date, rate) VALUES (?, ?, 1);`, userId, date)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	transferId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	err = insertTransferLegs(tx, int(transferId), TransferCreateForm{
+		UserId:     userId,
+		FromAcc:    from,
+		FromAmount: installment.Principal,
+		ToAcc:      *loan,
+		ToAmount:   installment.Principal,
+		Date:       date,
+		Rate:       1,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO loan_payments (account_id, number, transfer_id, interest_id) VALUES (?, ?, ?, ?)`,
+		accountId, number, transferId, interestId)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return ErrInstallmentsPaid
+		}
+		return mapWriteError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// Delete drops the terms of a loan account, the payments made stay in the
+// ledger.
+func (m *LoanTermsModel) Delete(userId, accountId int) error {
+	stmt := `
+	DELETE FROM loan_terms
+	WHERE account_id = ?
+	AND account_id IN (SELECT id FROM accounts WHERE user_id = ?);`
+
+	result, err := m.DB.Exec(stmt, accountId, userId)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// paidSelect selects the numbers of the paid installments of a loan
+// account, the ones whose transfer is not deleted.
+const paidSelect = `
+	SELECT lp.number
+	FROM loan_payments lp
+	JOIN transfers tr ON tr.id = lp.transfer_id
+	WHERE lp.account_id = ?
+	AND tr.deleted_at IS NULL`
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestLoanTermsSchedule(t *testing.T) {
+	terms := LoanTerms{Principal: NewMoney(1200000, Euro), Rate: 12, Months: 12, FirstPayment: date("2026-01-31")}
+
+	schedule := terms.Schedule()
+	assert.Equal(t, len(schedule), 12)
+	assert.Equal(t, schedule[0].Payment, NewMoney(106619, Euro))
+	assert.Equal(t, schedule[0].Interest, NewMoney(12000, Euro))
+	assert.Equal(t, schedule[0].Principal, NewMoney(94619, Euro))
+	assert.Equal(t, schedule[0].Remaining, NewMoney(1105381, Euro))
+	assert.Equal(t, schedule[1].Date, date("2026-02-28"))
+	assert.Equal(t, schedule[11].Date, date("2026-12-31"))
+	assert.Equal(t, schedule[11].Remaining, NewMoney(0, Euro))
+
+	principal := NewMoney(0, Euro)
+	for _, i := range schedule {
+		principal = principal.Add(i.Principal)
+	}
+	assert.Equal(t, principal, terms.Principal)
+
+	terms = LoanTerms{Principal: NewMoney(100000, Euro), Months: 3, FirstPayment: date("2026-01-15")}
+	schedule = terms.Schedule()
+	assert.Equal(t, schedule[0].Payment, NewMoney(33333, Euro))
+	assert.Equal(t, schedule[2].Payment, NewMoney(33334, Euro))
+	assert.Equal(t, terms.TotalInterest(), NewMoney(0, Euro))
+
+	terms.Paid = []int{1}
+	next, ok := terms.NextInstallment()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, next.Number, 2)
+}
+
+func TestLoanTermsModel(t *testing.T) {
+	db := newTestDB(t)
+	loanTerms := &LoanTermsModel{DB: db}
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	checking := newTestAccount(t, db, Euro)
+	_, err := transactions.Insert(testTransaction(checking, Income, 200000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checking, err = accounts.Get(checking.UserId, checking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mortgageId, err := accounts.Insert(checking.UserId, "Mortgage", Euro, Loan, NewMoney(0, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	terms := LoanTerms{
+		AccountID:        checking.ID,
+		UserID:           checking.UserId,
+		Principal:        NewMoney(1200000, Euro),
+		Rate:             12,
+		Months:           12,
+		FirstPayment:     date("2026-01-31"),
+		InterestCategory: "interest",
+	}
+	err = loanTerms.Set(terms)
+	assert.Equal(t, errors.Is(err, ErrNotLoanAccount), true)
+
+	terms.AccountID = mortgageId
+	err = loanTerms.Set(terms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mortgage, err := accounts.Get(checking.UserId, mortgageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mortgage.CreditLimit, NewMoney(1200000, Euro))
+	_, err = transactions.Rebalance(*mortgage, NewMoney(-1200000, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = loanTerms.Pay(checking.UserId, mortgageId, 1, *checking, date("2026-01-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loanTerms.Pay(checking.UserId, mortgageId, 1, *checking, date("2026-01-31"))
+	assert.Equal(t, errors.Is(err, ErrInstallmentsPaid), true)
+	err = loanTerms.Pay(checking.UserId, mortgageId, 13, *checking, date("2026-01-31"))
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	err = loanTerms.Set(terms)
+	assert.Equal(t, errors.Is(err, ErrInstallmentsPaid), true)
+
+	saved, err := loanTerms.Get(checking.UserId, mortgageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(saved.Paid), 1)
+	assert.Equal(t, saved.Paid[0], 1)
+	assert.Equal(t, saved.FirstPayment, date("2026-01-31"))
+
+	mortgage, err = accounts.Get(checking.UserId, mortgageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mortgage.Balance, NewMoney(-1105381, Euro))
+	assert.Equal(t, mortgage.Consistent(), true)
+	checking, err = accounts.Get(checking.UserId, checking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, checking.Balance, NewMoney(200000-106619, Euro))
+
+	// NOTE: Deleting the transfer marks the installment unpaid again.
+	transfers, err := transactions.GetTransfers(checking.UserId, date("2026-01-01"), date("2026-02-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(transfers), 1)
+	err = transactions.DeleteTransfer(checking.UserId, transfers[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err = loanTerms.Get(checking.UserId, mortgageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(saved.Paid), 0)
+	err = loanTerms.Pay(checking.UserId, mortgageId, 1, *checking, date("2026-02-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = loanTerms.Delete(checking.UserId, mortgageId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loanTerms.Get(checking.UserId, mortgageId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestLoanModel(t *testing.T) {
+	db := newTestDB(t)
+	loans := &LoanModel{DB: db}
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	_, err := transactions.Insert(testTransaction(acc, Income, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anaId, err := loans.InsertCounterparty(acc.UserId, "Ana", "sister")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loans.InsertCounterparty(acc.UserId, "Ana", "")
+	assert.Equal(t, errors.Is(err, ErrDuplicateCounterparty), true)
+
+	lent := PersonalLoan{
+		UserID:         acc.UserId,
+		CounterpartyID: anaId,
+		Direction:      Lent,
+		Principal:      NewMoney(6000, Euro),
+		Date:           date("2026-01-10"),
+		DueDate:        date("2026-03-01"),
+	}
+	_, err = loans.Insert(lent, *acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lent.Principal = NewMoney(5000, Euro)
+	_, err = loans.Insert(lent, *acc)
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+	lent.Principal = NewMoney(100, SerbianDinar)
+	_, err = loans.Insert(lent, *acc)
+	assert.Equal(t, errors.Is(err, ErrLoanCurrency), true)
+
+	borrowedId, err := loans.Insert(PersonalLoan{
+		UserID:         acc.UserId,
+		CounterpartyID: anaId,
+		Direction:      Borrowed,
+		Principal:      NewMoney(2000, Euro),
+		Date:           date("2026-02-01"),
+	}, *acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := loans.GetByCounterparty(acc.UserId, anaId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 2)
+	lentId := all[0].ID
+	assert.Equal(t, all[0].Direction, Lent)
+	assert.Equal(t, all[0].AccountID, acc.ID)
+	assert.Equal(t, all[0].Overdue(date("2026-03-02")), true)
+	assert.Equal(t, all[1].Overdue(date("2026-03-02")), false)
+
+	account, err := accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, account.Balance, NewMoney(6000, Euro))
+	assert.Equal(t, account.Consistent(), true)
+
+	paymentId, err := loans.Repay(acc.UserId, lentId, *acc, NewMoney(2500, Euro), date("2026-02-15"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loans.Repay(acc.UserId, lentId, *acc, NewMoney(3501, Euro), date("2026-02-16"))
+	assert.Equal(t, errors.Is(err, ErrLoanOverpaid), true)
+	_, err = loans.Repay(acc.UserId, borrowedId, *acc, NewMoney(500, Euro), date("2026-02-16"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loan, err := loans.Get(acc.UserId, lentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, loan.Repaid, NewMoney(2500, Euro))
+	assert.Equal(t, loan.Outstanding(), NewMoney(3500, Euro))
+	assert.Equal(t, len(loan.Payments), 1)
+	assert.Equal(t, loan.Payments[0].TransactionType, LoanIn)
+
+	account, err = accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, account.Balance, NewMoney(8000, Euro))
+	assert.Equal(t, account.Consistent(), true)
+
+	err = loans.DeletePayment(acc.UserId, borrowedId, paymentId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	err = loans.DeletePayment(acc.UserId, lentId, paymentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = loans.DeleteCounterparty(acc.UserId, anaId)
+	assert.Equal(t, errors.Is(err, ErrCounterpartyInUse), true)
+
+	err = loans.Delete(acc.UserId, lentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loans.Delete(acc.UserId, borrowedId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loans.DeleteCounterparty(acc.UserId, anaId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err = accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, account.Balance, NewMoney(10000, Euro))
+	assert.Equal(t, account.Consistent(), true)
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the minor units of its currency (cents, paras...), so
+// that sums of many transactions do not drift the way float64 does.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+func NewMoney(minor int64, currency Currency) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "1234", "12.5" or "-0,99" into
+// minor units of currency. More fractional digits than the currency allows
+// is an error instead of being silently rounded.
+func ParseMoney(s string, currency Currency) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	digits := currency.MinorUnits()
+	if whole == "" && frac == "" || len(frac) > digits {
+		return Money{}, ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return Money{}, ErrInvalidAmount
+			}
+		}
+	}
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// Add and Sub expect both operands in the same currency, use Convert first
+// otherwise.
+func (m Money) Add(o Money) Money {
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Minor: m.Minor - o.Minor, Currency: m.Currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+func (m Money) Abs() Money {
+	if m.Minor < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Convert returns m expressed in currency `to`, where rate is the number of
+// `to` major units for one major unit of m.Currency.
+func (m Money) Convert(to Currency, rate float64) Money {
+	scale := math.Pow10(to.MinorUnits() - m.Currency.MinorUnits())
+	return Money{
+		Minor:    int64(math.Round(float64(m.Minor) * rate * scale)),
+		Currency: to,
+	}
+}
+
+// Float is meant for ratios and charts only, never for arithmetic.
+func (m Money) Float() float64 {
+	return float64(m.Minor) / math.Pow10(m.Currency.MinorUnits())
+}
+
+// Decimal formats the amount without currency, as used by form inputs.
+func (m Money) Decimal() string {
+	digits := m.Currency.MinorUnits()
+
+	sign := ""
+	minor := m.Minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	if digits == 0 {
+		return fmt.Sprintf("%s%d", sign, minor)
+	}
+
+	unit := int64(math.Pow10(digits))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/unit, digits, minor%unit)
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Decimal(), m.Currency)
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr error
+	}{
+		{name: "Whole", input: "1234", want: 123400},
+		{name: "One decimal", input: "12.5", want: 1250},
+		{name: "Two decimals", input: "0.07", want: 7},
+		{name: "Comma separator", input: "3,99", want: 399},
+		{name: "Leading dot", input: ".5", want: 50},
+		{name: "Negative", input: "-10.01", want: -1001},
+		{name: "Spaces", input: " 42 ", want: 4200},
+		{name: "Too many decimals", input: "1.234", wantErr: ErrInvalidAmount},
+		{name: "Letters", input: "12a", wantErr: ErrInvalidAmount},
+		{name: "Empty", input: "", wantErr: ErrInvalidAmount},
+		{name: "Only sign", input: "-", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMoney(tt.input, Euro)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got err %v; want %v", err, tt.wantErr)
+			}
+			assert.Equal(t, m.Minor, tt.want)
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, NewMoney(123456, Euro).String(), "1234.56 EUR")
+	assert.Equal(t, NewMoney(-5, SerbianDinar).String(), "-0.05 RSD")
+	assert.Equal(t, NewMoney(0, Euro).Decimal(), "0.00")
+}
+
+func TestMoneyConvert(t *testing.T) {
+	assert.Equal(t, NewMoney(1000, Euro).Convert(SerbianDinar, 117.17), NewMoney(117170, SerbianDinar))
+	assert.Equal(t, NewMoney(100, SerbianDinar).Convert(Euro, 1/117.0), NewMoney(1, Euro))
+}
+
+func TestMoneySumDoesNotDrift(t *testing.T) {
+	sum := NewMoney(0, Euro)
+	for range 1000 {
+		sum = sum.Add(NewMoney(10, Euro))
+	}
+	assert.Equal(t, sum.Decimal(), "100.00")
+}
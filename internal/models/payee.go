@@ -0,0 +1,473 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type PayeeModelInterface interface {
+	Insert(userId int, name, defaultCategory string) (int, error)
+	Get(userId, id int) (*Payee, error)
+	GetAll(userId int) ([]*Payee, error)
+	GetByName(userId int, name string) (*Payee, error)
+	Match(userId int, description string) (*Payee, error)
+	SetDefaultCategory(userId, id int, category string) error
+	Delete(userId, id int) error
+	AddAlias(userId, id int, pattern string) (int, error)
+	RemoveAlias(userId, aliasId int) error
+	GetReport(userId int, startDate, endDate time.Time) ([]*PayeeReport, error)
+}
+
+// Payee is the merchant or person on the other side of a transaction. Aliases
+// recognise the payee in bank style descriptions like "MAXI 123 BGD".
+type Payee struct {
+	ID     int
+	UserID int
+	Name   string
+	// DefaultCategory is picked for a new expense of the payee when no
+	// category is given, blank for none.
+	DefaultCategory string
+	Aliases         []PayeeAlias
+	// Count is the number of transactions, trash included, of the payee.
+	Count int
+}
+
+type PayeeAlias struct {
+	ID      int
+	Pattern string
+}
+
+// PayeeReport is the expense of one payee in one currency.
+type PayeeReport struct {
+	PayeeID  int
+	Payee    string
+	Currency Currency
+	Count    int
+	Amount   Money
+}
+
+type PayeeModel struct {
+	DB *sql.DB
+}
+
+// NormalizePayee lower cases s and collapses runs of white space, aliases are
+// stored and matched in this form.
+func NormalizePayee(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Insert adds a payee together with its normalized name as the first alias,
+// existing transactions matching it are assigned to the new payee.
+func (m *PayeeModel) Insert(userId int, name, defaultCategory string) (int, error) {
+	stmt := `INSERT INTO payees (user_id, name, default_category) VALUES (?, ?, ?)`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// NOTE: Names only differing in case or spaces are the same payee, the
+	// unique index can not tell.
+	rows, err := tx.Query(`SELECT name FROM payees WHERE user_id = ?`, userId)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var existing string
+		err := rows.Scan(&existing)
+		if err != nil {
+			return 0, err
+		}
+		if NormalizePayee(existing) == NormalizePayee(name) {
+			return 0, ErrDuplicatePayee
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(stmt, userId, name, defaultCategory)
+	if err != nil {
+		return 0, mapPayeeError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = addAlias(tx, userId, int(id), NormalizePayee(name))
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (m *PayeeModel) Get(userId, id int) (*Payee, error) {
+	payees, err := m.GetAll(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(payees, func(p *Payee) bool { return p.ID == id })
+	if i < 0 {
+		return nil, ErrNoRecord
+	}
+
+	return payees[i], nil
+}
+
+// GetAll returns the payees of a user ordered by name, aliases included.
+func (m *PayeeModel) GetAll(userId int) ([]*Payee, error) {
+	stmt := `
+	SELECT p.id, p.user_id, p.name, p.default_category,
+		(SELECT COUNT(*) FROM transactions t WHERE t.payee_id = p.id)
+	FROM payees p
+	WHERE p.user_id = ?
+	ORDER BY p.name;`
+
+	rows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payees := []*Payee{}
+	byId := map[int]*Payee{}
+	for rows.Next() {
+		p := &Payee{}
+		err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.DefaultCategory, &p.Count)
+		if err != nil {
+			return nil, err
+		}
+		payees = append(payees, p)
+		byId[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt = `
+	SELECT a.id, a.payee_id, a.pattern
+	FROM payee_aliases a
+	JOIN payees p ON p.id = a.payee_id
+	WHERE p.user_id = ?
+	ORDER BY a.pattern;`
+
+	aliasRows, err := m.DB.Query(stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer aliasRows.Close()
+
+	for aliasRows.Next() {
+		var payeeId int
+		a := PayeeAlias{}
+		err := aliasRows.Scan(&a.ID, &payeeId, &a.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		byId[payeeId].Aliases = append(byId[payeeId].Aliases, a)
+	}
+	if err := aliasRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payees, nil
+}
+
+// GetByName finds a payee by its name, ignoring case and extra spaces.
+func (m *PayeeModel) GetByName(userId int, name string) (*Payee, error) {
+	payees, err := m.GetAll(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(payees, func(p *Payee) bool { return NormalizePayee(p.Name) == NormalizePayee(name) })
+	if i < 0 {
+		return nil, ErrNoRecord
+	}
+
+	return payees[i], nil
+}
+
+// Match finds the payee with the longest alias contained in description.
+func (m *PayeeModel) Match(userId int, description string) (*Payee, error) {
+	stmt := `
+	SELECT a.payee_id
+	FROM payee_aliases a
+	JOIN payees p ON p.id = a.payee_id
+	WHERE p.user_id = ?
+		AND instr(?, a.pattern) > 0
+	ORDER BY length(a.pattern) DESC, a.id
+	LIMIT 1;`
+
+	normalized := NormalizePayee(description)
+	if normalized == "" {
+		return nil, ErrNoRecord
+	}
+
+	var id int
+	err := m.DB.QueryRow(stmt, userId, normalized).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return m.Get(userId, id)
+}
+
+func (m *PayeeModel) SetDefaultCategory(userId, id int, category string) error {
+	stmt := `UPDATE payees SET default_category = ? WHERE user_id = ? AND id = ?`
+
+	result, err := m.DB.Exec(stmt, category, userId, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// Delete removes a payee, its transactions are kept without a payee.
+func (m *PayeeModel) Delete(userId, id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// NOTE: Done by hand as well as by the foreign key, the dsn decides
+	// whether sqlite enforces those.
+	_, err = tx.Exec(`UPDATE transactions SET payee_id = NULL WHERE user_id = ? AND payee_id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM payee_aliases WHERE payee_id IN (SELECT id FROM payees WHERE user_id = ? AND id = ?)`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM payees WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddAlias adds a match pattern to a payee and assigns the transactions
+// without a payee whose description matches it, returning how many were.
+func (m *PayeeModel) AddAlias(userId, id int, pattern string) (int, error) {
+	pattern = NormalizePayee(pattern)
+	if pattern == "" {
+		return 0, ErrInvalidAlias
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM payees WHERE user_id = ? AND id = ?)`, userId, id).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrNoRecord
+	}
+
+	n, err := addAlias(tx, userId, id, pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (m *PayeeModel) RemoveAlias(userId, aliasId int) error {
+	stmt := `
+	DELETE FROM payee_aliases
+	WHERE id = ?
+		AND payee_id IN (SELECT id FROM payees WHERE user_id = ?);`
+
+	result, err := m.DB.Exec(stmt, aliasId, userId)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// GetReport sums the expenses per payee and currency, largest first.
+func (m *PayeeModel) GetReport(userId int, startDate, endDate time.Time) ([]*PayeeReport, error) {
+	stmt := `
+	SELECT p.id, p.name, t.currency, COUNT(t.id), SUM(t.amount)
+	FROM payees p
+	JOIN transactions t ON t.payee_id = p.id
+	WHERE t.deleted_at IS NULL
+		AND p.user_id = ?
+		AND t.transaction_type = ?
+		AND t.date BETWEEN ? AND ?
+	GROUP BY p.id, t.currency
+	ORDER BY t.currency, SUM(t.amount) DESC;`
+
+	rows, err := m.DB.Query(stmt, userId, Expense, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*PayeeReport{}
+	for rows.Next() {
+		r := &PayeeReport{}
+		err := rows.Scan(&r.PayeeID, &r.Payee, &r.Currency, &r.Count, &r.Amount.Minor)
+		if err != nil {
+			return nil, err
+		}
+		r.Amount.Currency = r.Currency
+		reports = append(reports, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+func addAlias(tx *sql.Tx, userId, payeeId int, pattern string) (int, error) {
+	_, err := tx.Exec(`INSERT INTO payee_aliases (payee_id, pattern) VALUES (?, ?)`, payeeId, pattern)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, ErrDuplicateAlias
+		}
+		return 0, err
+	}
+
+	// NOTE: Descriptions are matched in Go, sqlite can not normalize white
+	// space the way NormalizePayee does.
+	stmt := `
+	SELECT id, description FROM transactions
+	WHERE user_id = ?
+		AND payee_id IS NULL
+		AND transfer_id IS NULL;`
+
+	rows, err := tx.Query(stmt, userId)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		var description string
+		err := rows.Scan(&id, &description)
+		if err != nil {
+			return 0, err
+		}
+		if strings.Contains(NormalizePayee(description), pattern) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		_, err = tx.Exec(`UPDATE transactions SET payee_id = ? WHERE id = ?`, payeeId, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+// setPayee points a transaction at payeeId, zero or a payee of another user
+// leave it without one.
+func setPayee(tx *sql.Tx, userId, transactionId, payeeId int) error {
+	stmt := `
+	UPDATE transactions
+	SET payee_id = (SELECT id FROM payees WHERE id = ? AND user_id = ?)
+	WHERE id = ?;`
+
+	_, err := tx.Exec(stmt, payeeId, userId, transactionId)
+	return mapWriteError(err)
+}
+
+func (m *TransactionModel) loadPayees(byId map[int]*Transaction, idsJSON string) error {
+	stmt := `
+	SELECT t.id, p.id, p.name
+	FROM transactions t
+	JOIN payees p ON p.id = t.payee_id
+	WHERE t.id IN (SELECT value FROM json_each(?));`
+
+	rows, err := m.DB.Query(stmt, idsJSON)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var payeeId int
+		var name string
+		err := rows.Scan(&id, &payeeId, &name)
+		if err != nil {
+			return err
+		}
+		byId[id].PayeeID = payeeId
+		byId[id].Payee = name
+	}
+
+	return rows.Err()
+}
+
+// expectRow turns an update or delete that touched nothing into ErrNoRecord.
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+func mapPayeeError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrDuplicatePayee
+	}
+	return err
+}
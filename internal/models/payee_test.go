@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestNormalizePayee(t *testing.T) {
+	assert.Equal(t, NormalizePayee("  MAXI   123\tBGD "), "maxi 123 bgd")
+	assert.Equal(t, NormalizePayee(""), "")
+}
+
+func TestPayeeModel(t *testing.T) {
+	db := newTestDB(t)
+	payees := &PayeeModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	_, err := transactions.Insert(testTransaction(acc, Income, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expense := testTransaction(acc, Expense, 1200)
+	expense.Description = "MAXI  123 BGD"
+	expenseId, err := transactions.Insert(expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxiId, err := payees.Insert(acc.UserId, "Maxi", "groceries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = payees.Insert(acc.UserId, "Maxi", "")
+	assert.Equal(t, errors.Is(err, ErrDuplicatePayee), true)
+
+	// NOTE: The name is the first alias and picks up the existing expense.
+	got, err := transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got.PayeeID, maxiId)
+	assert.Equal(t, got.Payee, "Maxi")
+
+	idea, err := payees.Insert(acc.UserId, "Idea", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := payees.AddAlias(acc.UserId, idea, " IDEA   Novi Sad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, n, 0)
+	_, err = payees.AddAlias(acc.UserId, idea, "idea novi sad")
+	assert.Equal(t, errors.Is(err, ErrDuplicateAlias), true)
+
+	p, err := payees.Match(acc.UserId, "POS IDEA NOVI SAD 0042")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, p.ID, idea)
+	_, err = payees.Match(acc.UserId, "lidl")
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	p, err = payees.GetByName(acc.UserId, "  maxi ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, p.DefaultCategory, "groceries")
+	assert.Equal(t, p.Count, 1)
+
+	other := newTestAccount(t, db, Euro)
+	_, err = payees.AddAlias(other.UserId, idea, "x")
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	_, err = payees.Match(other.UserId, "maxi")
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	expense.PayeeID = idea
+	expense.Description = ""
+	_, err = transactions.Insert(expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	reports, err := payees.GetReport(acc.UserId, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(reports), 2)
+	assert.Equal(t, reports[0].Amount.Minor, int64(1200))
+
+	// NOTE: Deleting a payee keeps its transactions.
+	err = payees.Delete(acc.UserId, maxiId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got.PayeeID, 0)
+}
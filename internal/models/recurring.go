@@ -0,0 +1,402 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type RecurringModelInterface interface {
+	Insert(r RecurringRule) (int, error)
+	Get(userId, id int) (*RecurringRule, error)
+	GetAll(userId int) ([]*RecurringRule, error)
+	Update(r RecurringRule) error
+	Delete(userId, id int) error
+	SetPaused(userId, id int, paused bool) error
+	GetDue(now time.Time) ([]*Occurrence, error)
+	SetError(id int, msg string) error
+}
+
+type Frequency string
+
+const (
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+func (f Frequency) Known() bool {
+	return f == Weekly || f == Monthly || f == Yearly
+}
+
+// RecurringRule posts an income or expense every Interval weeks, months or
+// years from StartDate until EndDate. Monthly rules post on Day, clamped to
+// the last day of shorter months, weekly and yearly rules on the weekday and
+// date of StartDate.
+type RecurringRule struct {
+	ID              int
+	UserID          int
+	AccountID       int
+	AccountName     string
+	TransactionType TransactionType
+	Amount          Money
+	Category        string
+	Description     string
+	Frequency       Frequency
+	Interval        int
+	Day             int
+	StartDate       time.Time
+	// EndDate is zero for rules without an end.
+	EndDate time.Time
+	Paused  bool
+	// LastError is why the last attempt to post failed, blank once it posts.
+	LastError string
+	// LastDate is the latest posted occurrence, zero before the first one.
+	LastDate time.Time
+}
+
+// Occurrence is one date a rule is due on.
+type Occurrence struct {
+	Rule *RecurringRule
+	Date time.Time
+}
+
+// occurrenceLayout is how occurrence dates are stored.
+const occurrenceLayout = "2006-01-02"
+
+// Dates returns the occurrences of the rule after the date after up to and
+// including until, in order.
+func (r RecurringRule) Dates(after, until time.Time) []time.Time {
+	dates := []time.Time{}
+	r.each(func(d time.Time) bool {
+		if d.After(until) {
+			return false
+		}
+		if d.After(after) {
+			dates = append(dates, d)
+		}
+		return true
+	})
+	return dates
+}
+
+// Next returns the first occurrence after the date after, zero when the rule
+// has ended by then.
+func (r RecurringRule) Next(after time.Time) time.Time {
+	var next time.Time
+	r.each(func(d time.Time) bool {
+		if d.After(after) {
+			next = d
+			return false
+		}
+		return true
+	})
+	return next
+}
+
+// NextDue returns the first occurrence that is not posted yet, zero when the
+// rule has ended.
+func (r RecurringRule) NextDue() time.Time {
+	return r.Next(r.LastDate)
+}
+
+// each calls fn with every occurrence of the rule in order until fn returns
+// false or the rule ends.
+func (r RecurringRule) each(fn func(time.Time) bool) {
+	start := dateOf(r.StartDate)
+	interval := max(r.Interval, 1)
+
+	for n := 0; ; n++ {
+		var d time.Time
+		switch r.Frequency {
+		case Weekly:
+			d = start.AddDate(0, 0, 7*interval*n)
+		case Monthly:
+			d = clampedDate(start.Year(), start.Month()+time.Month(interval*n), r.Day)
+			if d.Before(start) {
+				continue
+			}
+		case Yearly:
+			d = clampedDate(start.Year()+interval*n, start.Month(), start.Day())
+		default:
+			return
+		}
+
+		if !r.EndDate.IsZero() && d.After(dateOf(r.EndDate)) {
+			return
+		}
+		if !fn(d) {
+			return
+		}
+	}
+}
+
+// clampedDate is the day of the month, or its last day when the month is
+// shorter. month may be past December.
+func clampedDate(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// dateOf drops the time of day, keeping the calendar date as UTC midnight.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type RecurringModel struct {
+	DB *sql.DB
+}
+
+const recurringSelect = `
+	SELECT r.id, r.user_id, r.account_id, a.account_name, r.transaction_type, r.amount, a.currency,
+		r.category, r.description, r.frequency, r.interval, r.day, r.start_date,
+		r.end_date, r.paused, r.last_error,
+		(SELECT COALESCE(MAX(o.occurrence_date), '') FROM recurring_occurrences o WHERE o.rule_id = r.id)
+	FROM recurring_rules r
+	JOIN accounts a ON a.id = r.account_id`
+
+func (m *RecurringModel) Insert(r RecurringRule) (int, error) {
+	stmt := `
+	INSERT INTO recurring_rules (user_id, account_id, transaction_type, amount, category, description,
+		frequency, interval, day, start_date, end_date)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = checkAccountEditor(tx, r.UserID, r.AccountID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(stmt, r.UserID, r.AccountID, r.TransactionType, r.Amount.Minor, r.Category, r.Description,
+		r.Frequency, r.Interval, r.Day, dateOf(r.StartDate), nullDate(r.EndDate))
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (m *RecurringModel) Get(userId, id int) (*RecurringRule, error) {
+	stmt := recurringSelect + ` WHERE r.user_id = ? AND r.id = ?`
+
+	r, err := scanRecurringRule(m.DB.QueryRow(stmt, userId, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (m *RecurringModel) GetAll(userId int) ([]*RecurringRule, error) {
+	stmt := recurringSelect + ` WHERE r.user_id = ? ORDER BY r.start_date, r.id`
+
+	return m.query(stmt, userId)
+}
+
+// Update changes a rule. Occurrences already posted are kept, the new
+// schedule applies to the ones after the last posted.
+func (m *RecurringModel) Update(r RecurringRule) error {
+	stmt := `
+	UPDATE recurring_rules SET account_id = ?, amount = ?, category = ?, description = ?,
+		frequency = ?, interval = ?, day = ?, start_date = ?, end_date = ?, last_error = ''
+	WHERE user_id = ? AND id = ?;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkAccountEditor(tx, r.UserID, r.AccountID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(stmt, r.AccountID, r.Amount.Minor, r.Category, r.Description,
+		r.Frequency, r.Interval, r.Day, dateOf(r.StartDate), nullDate(r.EndDate), r.UserID, r.ID)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a rule, the transactions it posted are kept.
+func (m *RecurringModel) Delete(userId, id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM recurring_occurrences WHERE rule_id IN (SELECT id FROM recurring_rules WHERE user_id = ? AND id = ?)`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM recurring_rules WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+
+	err = expectRow(result)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *RecurringModel) SetPaused(userId, id int, paused bool) error {
+	result, err := m.DB.Exec(`UPDATE recurring_rules SET paused = ? WHERE user_id = ? AND id = ?`, paused, userId, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(result)
+}
+
+// GetDue returns the occurrences of all active rules, of every user, that
+// are due by now and not posted yet, oldest first. Occurrences missed while
+// the server was down are included. Rules of users that can no longer edit
+// the account, e.g. after leaving its household, are left out.
+func (m *RecurringModel) GetDue(now time.Time) ([]*Occurrence, error) {
+	stmt := recurringSelect + `
+	WHERE r.paused = FALSE
+	AND r.account_id IN (SELECT account_id FROM account_access WHERE user_id = r.user_id AND role != ?)`
+
+	rules, err := m.query(stmt, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	today := dateOf(now)
+	due := []*Occurrence{}
+	for _, r := range rules {
+		// NOTE: A zero LastDate is before any start date.
+		for _, d := range r.Dates(r.LastDate, today) {
+			due = append(due, &Occurrence{Rule: r, Date: d})
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].Date.Before(due[j].Date)
+	})
+
+	return due, nil
+}
+
+func (m *RecurringModel) SetError(id int, msg string) error {
+	_, err := m.DB.Exec(`UPDATE recurring_rules SET last_error = ? WHERE id = ?`, msg, id)
+	return err
+}
+
+func (m *RecurringModel) query(stmt string, args ...any) ([]*RecurringRule, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*RecurringRule{}
+	for rows.Next() {
+		r, err := scanRecurringRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func scanRecurringRule(row rowScanner) (*RecurringRule, error) {
+	r := &RecurringRule{}
+	var endDate sql.NullTime
+	var lastDate string
+	err := row.Scan(&r.ID, &r.UserID, &r.AccountID, &r.AccountName, &r.TransactionType, &r.Amount.Minor, &r.Amount.Currency,
+		&r.Category, &r.Description, &r.Frequency, &r.Interval, &r.Day, &r.StartDate,
+		&endDate, &r.Paused, &r.LastError, &lastDate)
+	if err != nil {
+		return nil, err
+	}
+
+	r.StartDate = dateOf(r.StartDate)
+	if endDate.Valid {
+		r.EndDate = dateOf(endDate.Time)
+	}
+	if lastDate != "" {
+		r.LastDate, err = time.Parse(occurrenceLayout, lastDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// recordOccurrence marks the occurrence a transaction was posted for, failing
+// with ErrDuplicateOccurrence when it already was.
+func recordOccurrence(tx *sql.Tx, tf TransactionCreateForm, transactionId int) error {
+	if tf.RecurringRuleID == 0 {
+		return nil
+	}
+
+	stmt := `INSERT INTO recurring_occurrences (rule_id, occurrence_date, transaction_id) VALUES (?, ?, ?)`
+
+	_, err := tx.Exec(stmt, tf.RecurringRuleID, tf.Date.Format(occurrenceLayout), transactionId)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return ErrDuplicateOccurrence
+		}
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// checkAccountEditor returns ErrAccountDoesNotExist unless the user owns the
+// account or edits it as a household member.
+func checkAccountEditor(tx *sql.Tx, userId, accountId int) error {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM account_access WHERE user_id = ? AND account_id = ? AND role != ?)`, userId, accountId, RoleViewer).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAccountDoesNotExist
+	}
+	return nil
+}
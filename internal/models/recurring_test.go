@@ -0,0 +1,195 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestRecurringRuleDates(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  RecurringRule
+		after string
+		until string
+		want  string
+	}{
+		{
+			name:  "Monthly clamps to short months",
+			rule:  RecurringRule{Frequency: Monthly, Interval: 1, Day: 31, StartDate: date("2024-01-15")},
+			until: "2024-04-30",
+			want:  "2024-01-31 2024-02-29 2024-03-31 2024-04-30",
+		},
+		{
+			name:  "Monthly skips day before start",
+			rule:  RecurringRule{Frequency: Monthly, Interval: 1, Day: 1, StartDate: date("2024-01-15")},
+			until: "2024-03-01",
+			want:  "2024-02-01 2024-03-01",
+		},
+		{
+			name:  "Every other week",
+			rule:  RecurringRule{Frequency: Weekly, Interval: 2, StartDate: date("2024-01-01")},
+			until: "2024-02-01",
+			want:  "2024-01-01 2024-01-15 2024-01-29",
+		},
+		{
+			name:  "Yearly on leap day",
+			rule:  RecurringRule{Frequency: Yearly, Interval: 1, StartDate: date("2024-02-29")},
+			until: "2026-12-31",
+			want:  "2024-02-29 2025-02-28 2026-02-28",
+		},
+		{
+			name:  "Ends",
+			rule:  RecurringRule{Frequency: Monthly, Interval: 1, Day: 5, StartDate: date("2024-01-01"), EndDate: date("2024-03-04")},
+			until: "2024-12-31",
+			want:  "2024-01-05 2024-02-05",
+		},
+		{
+			name:  "After last posted",
+			rule:  RecurringRule{Frequency: Monthly, Interval: 1, Day: 5, StartDate: date("2024-01-01")},
+			after: "2024-02-05",
+			until: "2024-03-31",
+			want:  "2024-03-05",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var after time.Time
+			if tt.after != "" {
+				after = date(tt.after)
+			}
+			got := []string{}
+			for _, d := range tt.rule.Dates(after, date(tt.until)) {
+				got = append(got, d.Format("2006-01-02"))
+			}
+			assert.Equal(t, strings.Join(got, " "), tt.want)
+		})
+	}
+
+	rule := RecurringRule{Frequency: Monthly, Interval: 1, Day: 5, StartDate: date("2024-01-01"), EndDate: date("2024-03-04")}
+	assert.Equal(t, rule.Next(date("2024-01-05")), date("2024-02-05"))
+	assert.Equal(t, rule.Next(date("2024-02-05")).IsZero(), true)
+}
+
+func TestRecurringModel(t *testing.T) {
+	db := newTestDB(t)
+	recurring := &RecurringModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	other := newTestAccount(t, db, Euro)
+
+	rule := RecurringRule{
+		UserID:          acc.UserId,
+		AccountID:       acc.ID,
+		TransactionType: Income,
+		Amount:          NewMoney(1000, Euro),
+		Category:        "salary",
+		Frequency:       Monthly,
+		Interval:        1,
+		Day:             10,
+		StartDate:       date("2026-01-01"),
+	}
+
+	_, err := recurring.Insert(RecurringRule{UserID: other.UserId, AccountID: acc.ID, Frequency: Monthly, Interval: 1, Day: 1, Amount: NewMoney(1, Euro), StartDate: date("2026-01-01")})
+	assert.Equal(t, errors.Is(err, ErrAccountDoesNotExist), true)
+
+	id, err := recurring.Insert(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := date("2026-03-15").Add(9 * time.Hour)
+	due, err := recurring.GetDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(due), 3)
+	assert.Equal(t, due[0].Date, date("2026-01-10"))
+	assert.Equal(t, due[0].Rule.Amount, NewMoney(1000, Euro))
+
+	post := func(o *Occurrence) error {
+		_, err := transactions.Insert(TransactionCreateForm{
+			UserId:          o.Rule.UserID,
+			AccountId:       o.Rule.AccountID,
+			Date:            o.Date,
+			Amount:          o.Rule.Amount,
+			Currency:        o.Rule.Amount.Currency,
+			Category:        o.Rule.Category,
+			TransactionType: int(o.Rule.TransactionType),
+			RecurringRuleID: o.Rule.ID,
+		})
+		return err
+	}
+
+	for _, o := range due[:2] {
+		err = post(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = post(due[0])
+	assert.Equal(t, errors.Is(err, ErrDuplicateOccurrence), true)
+
+	// NOTE: Only the occurrence that was not posted is due again.
+	due, err = recurring.GetDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(due), 1)
+	assert.Equal(t, due[0].Date, date("2026-03-10"))
+
+	got, err := recurring.Get(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got.LastDate, date("2026-02-10"))
+	assert.Equal(t, got.EndDate.IsZero(), true)
+
+	err = recurring.SetPaused(acc.UserId, id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err = recurring.GetDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(due), 0)
+
+	got.EndDate = date("2026-12-31")
+	got.Day = 20
+	err = recurring.Update(*got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = recurring.Get(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got.EndDate, date("2026-12-31"))
+	assert.Equal(t, got.Day, 20)
+
+	err = recurring.Delete(other.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+	err = recurring.Delete(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := transactions.GetAll(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 2)
+}
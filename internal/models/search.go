@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type SearchModelInterface interface {
+	Search(userId int, q SearchQuery) ([]*SearchResult, error)
+}
+
+// SearchLimit is the most results a search returns.
+const SearchLimit = 100
+
+// maxSearchTerms keeps a pasted paragraph from becoming a huge query.
+const maxSearchTerms = 10
+
+// majorAmount is the amount of a transaction in major units, currencies
+// differ in their minor unit digits. The power of ten is cut from '10000'.
+const majorAmount = `t.amount * 1.0 / CAST(substr('10000', 1, 1 + IFNULL(
+		(SELECT minor_units FROM currencies WHERE code = t.currency), 2)) AS INTEGER)`
+
+// SearchQuery finds the transactions matching all words of Text in their
+// description, category, payee or tags. The other fields narrow the results
+// down, their zero values leave them open.
+type SearchQuery struct {
+	Text      string
+	AccountID int
+	StartDate time.Time
+	EndDate   time.Time
+	// MinAmount and MaxAmount are in major units of the currency of each
+	// transaction, the size of the amount whether income or expense.
+	MinAmount float64
+	MaxAmount float64
+}
+
+// Fragment is a piece of a searched text, Match is set on the pieces
+// matching a search term.
+type Fragment struct {
+	Text  string
+	Match bool
+}
+
+// SearchResult is a transaction found by a search with its text split up
+// for highlighting the matches.
+type SearchResult struct {
+	Transaction *Transaction
+	Description []Fragment
+	Category    []Fragment
+	Payee       []Fragment
+	Tags        [][]Fragment
+}
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// Search returns the visible transactions matching the query, best matches
+// first when there is text to match and newest first otherwise.
+func (m *SearchModel) Search(userId int, q SearchQuery) ([]*SearchResult, error) {
+	terms := SearchTerms(q.Text)
+
+	joins := ""
+	conditions := []string{"t.deleted_at IS NULL", visibleTransaction}
+	args := []any{userId, userId}
+	order := "t.date DESC, t.id DESC"
+
+	if len(terms) > 0 {
+		join, condition, matchArgs, rank := searchMatch(terms)
+		joins = join
+		conditions = append(conditions, condition)
+		args = append(args, matchArgs...)
+		if rank != "" {
+			order = rank + ", " + order
+		}
+	}
+	if q.AccountID > 0 {
+		conditions = append(conditions, "t.account_id = ?")
+		args = append(args, q.AccountID)
+	}
+	if !q.StartDate.IsZero() {
+		conditions = append(conditions, "t.date >= ?")
+		args = append(args, q.StartDate.Format("2006-01-02"))
+	}
+	// NOTE: Dates are stored with a time, the end day is taken whole.
+	if !q.EndDate.IsZero() {
+		conditions = append(conditions, "t.date < ?")
+		args = append(args, q.EndDate.AddDate(0, 0, 1).Format("2006-01-02"))
+	}
+	if q.MinAmount > 0 {
+		conditions = append(conditions, majorAmount+" >= ?")
+		args = append(args, q.MinAmount)
+	}
+	if q.MaxAmount > 0 {
+		conditions = append(conditions, majorAmount+" <= ?")
+		args = append(args, q.MaxAmount)
+	}
+	args = append(args, SearchLimit)
+
+	stmt := `
+	SELECT t.id, t.account_id, t.user_id, t.date, t.amount, t.currency, t.category, t.description, t.transaction_type, t.transfer_id
+	FROM transactions t ` + joins + `
+	WHERE ` + strings.Join(conditions, "\n\tAND ") + `
+	ORDER BY ` + order + `
+	LIMIT ?;`
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err = (&TransactionModel{DB: m.DB}).withDetails(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchResult, 0, len(transactions))
+	for _, t := range transactions {
+		result := &SearchResult{
+			Transaction: t,
+			Description: Highlight(t.Description, terms),
+			Category:    Highlight(t.Category, terms),
+			Payee:       Highlight(t.Payee, terms),
+		}
+		for _, tag := range t.Tags {
+			result.Tags = append(result.Tags, Highlight(tag, terms))
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// SearchTerms splits a search into lower case words of letters and digits,
+// everything else separates them.
+func SearchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := []string{}
+	for _, word := range words {
+		if !slices.Contains(terms, word) && len(terms) < maxSearchTerms {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// Highlight splits text into the fragments matching one of the terms and the
+// ones in between, ignoring case.
+func Highlight(text string, terms []string) []Fragment {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// NOTE: Longer terms first, so "coffee" wins over "co" at the same place.
+	sorted := slices.Clone(terms)
+	slices.SortFunc(sorted, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	fragments := []Fragment{}
+	start := 0
+	for i := 0; i < len(lower); {
+		n := 0
+		for _, term := range sorted {
+			t := []rune(term)
+			if len(t) > 0 && slices.Equal(lower[i:min(i+len(t), len(lower))], t) {
+				n = len(t)
+				break
+			}
+		}
+		if n == 0 {
+			i++
+			continue
+		}
+		if start < i {
+			fragments = append(fragments, Fragment{Text: string(runes[start:i])})
+		}
+		fragments = append(fragments, Fragment{Text: string(runes[i : i+n]), Match: true})
+		i += n
+		start = i
+	}
+	if start < len(runes) {
+		fragments = append(fragments, Fragment{Text: string(runes[start:])})
+	}
+
+	return fragments
+}
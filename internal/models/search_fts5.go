@@ -0,0 +1,105 @@
+//go:build sqlite_fts5
+
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// searchIndex is the full-text index of transaction_search_source, kept
+// current by triggers on everything the view reads. Each trigger rewrites the
+// rows of the transactions it touches.
+const searchIndex = `
+CREATE VIRTUAL TABLE IF NOT EXISTS transaction_search USING fts5 (
+    description, category, payee, tags,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS transaction_search_ai AFTER INSERT ON transactions BEGIN
+    %[1]s
+END;
+CREATE TRIGGER IF NOT EXISTS transaction_search_au AFTER UPDATE ON transactions BEGIN
+    DELETE FROM transaction_search WHERE rowid = OLD.id;
+    %[1]s
+END;
+CREATE TRIGGER IF NOT EXISTS transaction_search_ad AFTER DELETE ON transactions BEGIN
+    DELETE FROM transaction_search WHERE rowid = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS transaction_search_tags_ai AFTER INSERT ON transaction_tags BEGIN
+    %[2]s
+END;
+CREATE TRIGGER IF NOT EXISTS transaction_search_tags_ad AFTER DELETE ON transaction_tags BEGIN
+    %[3]s
+END;
+
+CREATE TRIGGER IF NOT EXISTS transaction_search_splits_ai AFTER INSERT ON transaction_splits BEGIN
+    %[2]s
+END;
+CREATE TRIGGER IF NOT EXISTS transaction_search_splits_au AFTER UPDATE ON transaction_splits BEGIN
+    %[2]s
+END;
+CREATE TRIGGER IF NOT EXISTS transaction_search_splits_ad AFTER DELETE ON transaction_splits BEGIN
+    %[3]s
+END;
+
+CREATE TRIGGER IF NOT EXISTS transaction_search_payees_au AFTER UPDATE OF name ON payees BEGIN
+    DELETE FROM transaction_search WHERE rowid IN (SELECT id FROM transactions WHERE payee_id = NEW.id);
+    INSERT INTO transaction_search (rowid, description, category, payee, tags)
+    SELECT transaction_id, description, category, payee, tags FROM transaction_search_source
+    WHERE transaction_id IN (SELECT id FROM transactions WHERE payee_id = NEW.id);
+END;`
+
+// searchRefresh rewrites the index row of one transaction.
+const searchRefresh = `
+    DELETE FROM transaction_search WHERE rowid = %[1]s;
+    INSERT INTO transaction_search (rowid, description, category, payee, tags)
+    SELECT transaction_id, description, category, payee, tags FROM transaction_search_source
+    WHERE transaction_id = %[1]s;`
+
+// Init creates the full-text index and fills it from scratch, writes made by
+// a build without FTS5 are not in it.
+func (m *SearchModel) Init() error {
+	schema := fmt.Sprintf(searchIndex,
+		fmt.Sprintf(searchRefresh, "NEW.id"),
+		fmt.Sprintf(searchRefresh, "NEW.transaction_id"),
+		fmt.Sprintf(searchRefresh, "OLD.transaction_id"))
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(schema)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+	DELETE FROM transaction_search;
+	INSERT INTO transaction_search (rowid, description, category, payee, tags)
+	SELECT transaction_id, description, category, payee, tags FROM transaction_search_source;`)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// searchMatch matches every term as the prefix of a word in the index,
+// ranked by relevance.
+func searchMatch(terms []string) (join, condition string, args []any, order string) {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		// NOTE: Terms are letters and digits only, see SearchTerms.
+		quoted = append(quoted, `"`+term+`"*`)
+	}
+
+	join = "JOIN transaction_search ON transaction_search.rowid = t.id"
+	condition = "transaction_search MATCH ?"
+	args = []any{strings.Join(quoted, " ")}
+	order = "transaction_search.rank"
+	return join, condition, args, order
+}
@@ -0,0 +1,44 @@
+//go:build !sqlite_fts5
+
+package models
+
+import "strings"
+
+// searchTriggers are the triggers a build with FTS5 keeps its index current
+// with. They fail every write without the FTS5 module, so they are dropped.
+var searchTriggers = []string{
+	"transaction_search_ai",
+	"transaction_search_au",
+	"transaction_search_ad",
+	"transaction_search_tags_ai",
+	"transaction_search_tags_ad",
+	"transaction_search_splits_ai",
+	"transaction_search_splits_au",
+	"transaction_search_splits_ad",
+	"transaction_search_payees_au",
+}
+
+// Init drops the triggers of the full-text index, a build with FTS5 fills the
+// index again on its next start.
+func (m *SearchModel) Init() error {
+	for _, trigger := range searchTriggers {
+		_, err := m.DB.Exec(`DROP TRIGGER IF EXISTS ` + trigger)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchMatch looks for every term anywhere in the searchable text. Without
+// FTS5 there is no ranking, sqlite lower() only folds ASCII letters.
+func searchMatch(terms []string) (join, condition string, args []any, order string) {
+	join = "JOIN transaction_search_source s ON s.transaction_id = t.id"
+	matches := make([]string, 0, len(terms))
+	for _, term := range terms {
+		matches = append(matches, "instr(lower(s.description || ' ' || s.category || ' ' || s.payee || ' ' || s.tags), ?) > 0")
+		args = append(args, term)
+	}
+	condition = "(" + strings.Join(matches, " AND ") + ")"
+	return join, condition, args, ""
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, len(SearchTerms("  ,;  ")), 0)
+
+	terms := SearchTerms(`Dentist "spring", DENTIST 2026!`)
+	assert.Equal(t, len(terms), 3)
+	assert.Equal(t, terms[0], "dentist")
+	assert.Equal(t, terms[1], "spring")
+	assert.Equal(t, terms[2], "2026")
+}
+
+func TestHighlight(t *testing.T) {
+	fragments := Highlight("Coffee at Corner Café", []string{"co", "coffee", "café"})
+	assert.Equal(t, len(fragments), 5)
+	assert.Equal(t, fragments[0], Fragment{Text: "Coffee", Match: true})
+	assert.Equal(t, fragments[1], Fragment{Text: " at "})
+	assert.Equal(t, fragments[2], Fragment{Text: "Co", Match: true})
+	assert.Equal(t, fragments[3], Fragment{Text: "rner "})
+	assert.Equal(t, fragments[4], Fragment{Text: "Café", Match: true})
+
+	assert.Equal(t, len(Highlight("", []string{"co"})), 0)
+	assert.Equal(t, len(Highlight("rent", nil)), 1)
+}
+
+func TestSearchModel(t *testing.T) {
+	db := newTestDB(t)
+	search := &SearchModel{DB: db}
+	err := search.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := &TransactionModel{DB: db}
+	payees := &PayeeModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	other := newTestAccount(t, db, Euro)
+
+	_, err = transactions.Insert(testTransaction(acc, Income, 500000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dentist := testTransaction(acc, Expense, 12000)
+	dentist.Description = "Dentist checkup"
+	dentist.Date = time.Date(2026, time.April, 14, 0, 0, 0, 0, time.UTC)
+	dentist.Tags = []string{"health"}
+	dentistId, err := transactions.Insert(dentist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groceries := testTransaction(acc, Expense, 3000)
+	groceries.Description = "MAXI 123"
+	groceries.Date = time.Date(2026, time.May, 2, 0, 0, 0, 0, time.UTC)
+	groceriesId, err := transactions.Insert(groceries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hidden := testTransaction(other, Income, 100)
+	hidden.Description = "dentist refund"
+	_, err = transactions.Insert(hidden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := search.Search(acc.UserId, SearchQuery{Text: "dent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Transaction.ID, dentistId)
+	assert.Equal(t, results[0].Description[0], Fragment{Text: "Dent", Match: true})
+
+	// NOTE: Every word has to match, in any of the fields.
+	results, err = search.Search(acc.UserId, SearchQuery{Text: "dentist health"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Tags[0][0], Fragment{Text: "health", Match: true})
+	results, err = search.Search(acc.UserId, SearchQuery{Text: "dentist groceries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 0)
+
+	// NOTE: The payee picks up the existing expense and is searchable.
+	_, err = payees.Insert(acc.UserId, "Maxi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = search.Search(acc.UserId, SearchQuery{Text: "maxi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Transaction.ID, groceriesId)
+	assert.Equal(t, results[0].Payee[0], Fragment{Text: "Maxi", Match: true})
+
+	results, err = search.Search(acc.UserId, SearchQuery{Text: "test", MinAmount: 25, MaxAmount: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 2)
+	results, err = search.Search(acc.UserId, SearchQuery{
+		Text:      "test",
+		StartDate: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2026, time.April, 14, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Transaction.ID, dentistId)
+
+	// NOTE: Yen have no minor units, 150 is 150 yen and not 1.50.
+	yen := newTestAccount(t, db, "JPY")
+	yenId, err := transactions.Insert(testTransaction(yen, Income, 150))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = search.Search(yen.UserId, SearchQuery{MinAmount: 100, MaxAmount: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Transaction.ID, yenId)
+
+	results, err = search.Search(acc.UserId, SearchQuery{AccountID: other.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 0)
+
+	// NOTE: Edited and deleted transactions leave the results.
+	dentist.Description = "Orthodontist"
+	err = transactions.Update(dentistId, dentist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = search.Search(acc.UserId, SearchQuery{Text: "dentist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 0)
+	err = transactions.Delete(acc.UserId, groceriesId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = search.Search(acc.UserId, SearchQuery{Text: "maxi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 0)
+}
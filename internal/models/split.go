@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// MaxSplits is the number of lines a transaction can be split into.
+const MaxSplits = 4
+
+// Split is one line of a transaction spread over several categories, the
+// amounts of all lines add up to the transaction amount.
+type Split struct {
+	Category    string
+	Amount      Money
+	Description string
+}
+
+// Lines returns the split lines of a transaction, a transaction that is not
+// split is a single line of its own category and amount.
+func (a Transaction) Lines() []Split {
+	if len(a.Splits) > 0 {
+		return a.Splits
+	}
+	return []Split{{Category: a.Category, Amount: a.Amount, Description: a.Description}}
+}
+
+// SplitsTotal adds up the amounts of split lines.
+func SplitsTotal(splits []Split) int64 {
+	var total int64
+	for _, s := range splits {
+		total += s.Amount.Minor
+	}
+	return total
+}
+
+// setSplits replaces the split lines of a transaction.
+func setSplits(tx *sql.Tx, transactionId int, tf TransactionCreateForm) error {
+	if len(tf.Splits) > 0 && SplitsTotal(tf.Splits) != tf.Amount.Minor {
+		return ErrSplitTotal
+	}
+
+	_, err := tx.Exec(`DELETE FROM transaction_splits WHERE transaction_id = ?`, transactionId)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	stmt := `INSERT INTO transaction_splits (transaction_id, category, amount, description) VALUES (?, ?, ?, ?)`
+	for _, s := range tf.Splits {
+		_, err = tx.Exec(stmt, transactionId, s.Category, s.Amount.Minor, s.Description)
+		if err != nil {
+			return mapWriteError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m *TransactionModel) loadSplits(byId map[int]*Transaction, idsJSON string) error {
+	stmt := `
+	SELECT transaction_id, category, amount, description
+	FROM transaction_splits
+	WHERE transaction_id IN (SELECT value FROM json_each(?))
+	ORDER BY id;`
+
+	rows, err := m.DB.Query(stmt, idsJSON)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		s := Split{}
+		err := rows.Scan(&id, &s.Category, &s.Amount.Minor, &s.Description)
+		if err != nil {
+			return err
+		}
+		t := byId[id]
+		s.Amount.Currency = t.Currency
+		t.Splits = append(t.Splits, s)
+	}
+
+	return rows.Err()
+}
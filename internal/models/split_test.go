@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestTransactionModelSplits(t *testing.T) {
+	db := newTestDB(t)
+	transactions := &TransactionModel{DB: db}
+	categories := &CategoryModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	_, err := transactions.Insert(testTransaction(acc, Income, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groceriesId, err := categories.Insert(acc.UserId, Expense, "groceries", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expense := testTransaction(acc, Expense, 3000)
+	expense.Category = "groceries"
+	expense.Splits = []Split{
+		{Category: "groceries", Amount: NewMoney(2000, Euro), Description: "food"},
+		{Category: "household", Amount: NewMoney(1500, Euro)},
+	}
+	_, err = transactions.Insert(expense)
+	assert.Equal(t, errors.Is(err, ErrSplitTotal), true)
+
+	expense.Splits[1].Amount = NewMoney(1000, Euro)
+	expenseId, err := transactions.Insert(expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(got.Splits), 2)
+	assert.Equal(t, got.Splits[0].Description, "food")
+	assert.Equal(t, got.Splits[1].Amount, NewMoney(1000, Euro))
+	assert.Equal(t, len(got.Lines()), 2)
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	groupings, err := transactions.GetGroupingByDate(acc.UserId, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(groupings), 2)
+	assert.Equal(t, groupings[0].Category, "groceries")
+	assert.Equal(t, groupings[0].CategoryID, groceriesId)
+	assert.Equal(t, groupings[0].Amount.Minor, int64(2000))
+	assert.Equal(t, groupings[1].Category, "household")
+	assert.Equal(t, groupings[1].Amount.Minor, int64(1000))
+
+	// NOTE: Renaming a category rewrites the split lines using it.
+	err = categories.Rename(acc.UserId, groceriesId, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got.Category, "food")
+	assert.Equal(t, got.Splits[0].Category, "food")
+
+	// NOTE: Updating without splits turns it back into a single line.
+	expense.Category = "food"
+	expense.Splits = nil
+	err = transactions.Update(expenseId, expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(got.Splits), 0)
+	assert.Equal(t, len(got.Lines()), 1)
+	assert.Equal(t, got.Lines()[0].Amount.Minor, int64(3000))
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxTags is the number of tags a single transaction can carry.
+const MaxTags = 10
+
+// TagReport sums the incomes and expenses carrying a tag in one currency.
+type TagReport struct {
+	Tag      string
+	Currency Currency
+	Count    int
+	Income   Money
+	Expense  Money
+}
+
+// ParseTags splits a comma separated tag list. Tags are lower cased, blanks
+// and duplicates are dropped.
+func ParseTags(s string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// setTags replaces the tags of a transaction, creating the tags the user did
+// not have yet.
+func setTags(tx *sql.Tx, userId, transactionId int, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = ?`, transactionId)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec(`INSERT OR IGNORE INTO tags (user_id, name) VALUES (?, ?)`, userId, tag)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		stmt := `
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT ?, id FROM tags WHERE user_id = ? AND name = ?;`
+
+		_, err = tx.Exec(stmt, transactionId, userId, tag)
+		if err != nil {
+			return mapWriteError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m *TransactionModel) loadTags(byId map[int]*Transaction, idsJSON string) error {
+	stmt := `
+	SELECT tt.transaction_id, tg.name
+	FROM transaction_tags tt
+	JOIN tags tg ON tg.id = tt.tag_id
+	WHERE tt.transaction_id IN (SELECT value FROM json_each(?))
+	ORDER BY tg.name;`
+
+	rows, err := m.DB.Query(stmt, idsJSON)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var tag string
+		err := rows.Scan(&id, &tag)
+		if err != nil {
+			return err
+		}
+		byId[id].Tags = append(byId[id].Tags, tag)
+	}
+
+	return rows.Err()
+}
+
+// GetTags returns the names of all tags of a user.
+func (m *TransactionModel) GetTags(userId int) ([]string, error) {
+	rows, err := m.DB.Query(`SELECT name FROM tags WHERE user_id = ? ORDER BY name`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		err := rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (m *TransactionModel) GetTagReport(userId int, startDate, endDate time.Time) ([]*TagReport, error) {
+	stmt := `
+	SELECT
+		tg.name,
+		t.currency,
+		COUNT(t.id),
+		SUM(CASE WHEN t.transaction_type = ? THEN t.amount ELSE 0 END),
+		SUM(CASE WHEN t.transaction_type = ? THEN t.amount ELSE 0 END)
+	FROM tags tg
+	JOIN transaction_tags tt ON tt.tag_id = tg.id
+	JOIN transactions t ON t.id = tt.transaction_id
+	WHERE t.deleted_at IS NULL
+		AND t.transaction_type IN (?, ?)
+		AND tg.user_id = ?
+		AND t.date BETWEEN ? AND ?
+	GROUP BY tg.name, t.currency
+	ORDER BY tg.name, t.currency;`
+
+	rows, err := m.DB.Query(stmt, Income, Expense, Income, Expense, userId, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*TagReport{}
+	for rows.Next() {
+		r := &TagReport{}
+		err := rows.Scan(&r.Tag, &r.Currency, &r.Count, &r.Income.Minor, &r.Expense.Minor)
+		if err != nil {
+			return nil, err
+		}
+		r.Income.Currency = r.Currency
+		r.Expense.Currency = r.Currency
+		reports = append(reports, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
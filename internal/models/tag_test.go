@@ -0,0 +1,120 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Empty", input: "", want: ""},
+		{name: "Blanks", input: " , ,", want: ""},
+		{name: "Trim and lower", input: " Wedding ,vacation-2026", want: "wedding|vacation-2026"},
+		{name: "Duplicates", input: "tax, TAX, tax", want: "tax"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, strings.Join(ParseTags(tt.input), "|"), tt.want)
+		})
+	}
+}
+
+func TestTransactionModelTags(t *testing.T) {
+	db := newTestDB(t)
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	income := testTransaction(acc, Income, 1000)
+	income.Tags = []string{"wedding"}
+	_, err := transactions.Insert(income)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expense := testTransaction(acc, Expense, 300)
+	expense.Tags = []string{"wedding", "tax-deductible"}
+	expenseId, err := transactions.Insert(expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.Join(got.Tags, ","), "tax-deductible,wedding")
+
+	tags, err := transactions.GetTags(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(tags), 2)
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	reports, err := transactions.GetTagReport(acc.UserId, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(reports), 2)
+	assert.Equal(t, reports[1].Tag, "wedding")
+	assert.Equal(t, reports[1].Count, 2)
+	assert.Equal(t, reports[1].Income.Minor, int64(1000))
+	assert.Equal(t, reports[1].Expense.Minor, int64(300))
+
+	tagged, err := transactions.GetByDateAndType(acc.UserId, Expense, " Tax-Deductible ", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(tagged), 1)
+	assert.Equal(t, tagged[0].ID, expenseId)
+	tagged, err = transactions.GetByDateAndType(acc.UserId, Income, "tax-deductible", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(tagged), 0)
+	tagged, err = transactions.GetByDateAndType(acc.UserId, Income, "", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(tagged), 1)
+
+	expense.Tags = []string{"vacation-2026"}
+	err = transactions.Update(expenseId, expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := transactions.GetAll(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range all {
+		if tr.ID == expenseId {
+			assert.Equal(t, strings.Join(tr.Tags, ","), "vacation-2026")
+		}
+	}
+
+	// NOTE: Purging the trash removes the links along with the transaction.
+	err = transactions.Delete(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transactions.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err = transactions.GetTagReport(acc.UserId, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(reports), 1)
+	assert.Equal(t, reports[0].Tag, "wedding")
+}
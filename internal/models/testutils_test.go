@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/migrations"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = (&migrations.Migrator{DB: db}).Up()
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+// newTestAccount creates a user with a single empty account and returns it.
+func newTestAccount(t *testing.T, db *sql.DB, currency Currency) *Account {
+	users := &UserModel{DB: db}
+	email := fmt.Sprintf("user%d@example.com", time.Now().UnixNano())
+	err := users.Insert("Test", email, "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userId, err := users.Authenticate(email, "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accounts := &AccountModel{DB: db}
+	id, err := accounts.Insert(userId, "Test "+string(currency), currency, Checking, NewMoney(0, currency))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := accounts.Get(userId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return account
+}
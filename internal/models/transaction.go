@@ -2,49 +2,90 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
+	"strings"
 	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
 
 type TransactionsModelInterface interface {
-	Insert(tf TransactionCreateForm, newBalance float64) (int, error)
-	InsertTransfer(tf TransferCreateForm) error
-	Get(id int) (*Transaction, error)
+	Insert(tf TransactionCreateForm) (int, error)
+	InsertTransfer(tf TransferCreateForm) (int, error)
+	GetTransfer(userId, id int) (*Transfer, error)
+	UpdateTransfer(id int, tf TransferCreateForm) error
+	DeleteTransfer(userId, id int) error
+	RestoreTransfer(userId, id int) error
+	Rebalance(account Account, newBalance Money) (int, error)
+	Update(id int, tf TransactionCreateForm) error
+	Delete(userId, id int) error
+	Restore(userId, id int) error
+	GetDeleted(userId int, since time.Time) ([]*Transaction, error)
+	PurgeDeleted(before time.Time) (int, error)
+	Get(userId, id int) (*Transaction, error)
 	GetAll(userId int) ([]*Transaction, error)
 	GetByDate(userId int, startDate, endDate time.Time) ([]*Transaction, error)
 	GetByType(userId int, tt TransactionType) ([]*Transaction, error)
-	GetByDateAndType(userId int, tt TransactionType, startDate, endDate time.Time) ([]*Transaction, error)
+	GetByDateAndType(userId int, tt TransactionType, tag string, startDate, endDate time.Time) ([]*Transaction, error)
 	GetLatest(userId, limit int, tt TransactionType) ([]*Transaction, error)
-	GetTransfers(userId int, startDate, endDate time.Time) ([]*Transaction, error)
+	GetTransfers(userId int, startDate, endDate time.Time) ([]*Transfer, error)
 	GetGroupingByDate(userId int, startDate, endDate time.Time) ([]*GroupingReport, error)
+	GetTags(userId int) ([]string, error)
+	GetTagReport(userId int, startDate, endDate time.Time) ([]*TagReport, error)
 }
 
 type GroupingReport struct {
-	Category string
-	Count    int
-	Amount   float64
-	Currency Currency
+	Category   string
+	CategoryID int
+	Count      int
+	Amount     Money
+	Currency   Currency
 }
 
 type Transaction struct {
-	ID              int
-	AccountID       int
+	ID        int
+	AccountID int
+	// UserID is the member who created the transaction, on a shared account
+	// it is not always the owner of the account.
 	UserID          int
 	Date            time.Time
-	Amount          float64
+	Amount          Money
 	Currency        Currency
 	Category        string
 	Description     string
 	TransactionType TransactionType
+	// TransferID is set on the legs of a transfer, see Transfer.
+	TransferID int
+	Tags       []string
+	// Splits is empty unless the transaction is spread over several
+	// categories, see Lines.
+	Splits []Split
+	// PayeeID is zero for transactions without a payee.
+	PayeeID int
+	Payee   string
+	// Attachments holds the metadata of the attached files, see
+	// AttachmentModel.Get for the file itself.
+	Attachments []Attachment
+	// DeletedAt is only set for transactions returned by GetDeleted.
+	DeletedAt time.Time
 }
 
-func NewRebalance(account Account, balanceDiff float64) TransactionCreateForm {
+// NOTE: A transaction is seen by the member who created it and by everyone
+// who can see its account, changing it takes an account the user can edit.
+// Both filters are used on the transactions table without an alias.
+const (
+	visibleTransaction  = `(user_id = ? OR account_id IN (SELECT account_id FROM account_access WHERE user_id = ?))`
+	editableTransaction = `account_id IN (SELECT account_id FROM account_access WHERE user_id = ? AND role != 'viewer')`
+)
+
+// TrashRetention is how long deleted transactions can be restored.
+const TrashRetention = 30 * 24 * time.Hour
+
+func NewRebalance(account Account, balanceDiff Money) TransactionCreateForm {
 	var transactionType TransactionType
-	if balanceDiff > 0 {
+	if balanceDiff.Minor > 0 {
 		transactionType = RebalanceOut
 	} else {
 		transactionType = RebalanceIn
@@ -53,16 +94,22 @@ func NewRebalance(account Account, balanceDiff float64) TransactionCreateForm {
 		UserId:          account.UserId,
 		AccountId:       account.ID,
 		Date:            time.Now().UTC(),
-		Amount:          math.Abs(balanceDiff),
-		Currency:        int(account.Currency),
+		Amount:          balanceDiff.Abs(),
+		Currency:        account.Currency,
 		Category:        "rebalance",
 		Description:     fmt.Sprintf("rebalance of account \"%s\"", account.AccountName),
 		TransactionType: int(transactionType),
 	}
 }
 
+// Editable reports whether the transaction can be edited or deleted on its
+// own. Transfer legs, fees included, change together with their transfer.
+func (a Transaction) Editable() bool {
+	return a.TransferID == 0 && (a.TransactionType == Income || a.TransactionType == Expense)
+}
+
 func (a Transaction) DisplayAmount() string {
-	return fmt.Sprintf("%.2f %s", a.Amount, a.Currency)
+	return a.Amount.String()
 }
 
 func (a Transaction) DisplayDate() string {
@@ -73,101 +120,189 @@ type TransactionModel struct {
 	DB *sql.DB
 }
 
-func (m *TransactionModel) InsertTransfer(tf TransferCreateForm) error {
-	stmt1 := `
-	INSERT INTO transactions (account_id, user_id, date, amount, currency, category, description, transaction_type) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
-	stmt2 := `UPDATE accounts SET balance = ? WHERE id = ?;`
-	tx, err := m.DB.Begin()
+func scanTransaction(row rowScanner, extra ...any) (*Transaction, error) {
+	t := &Transaction{}
+	var transferId sql.NullInt64
+	dest := []any{&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount.Minor, &t.Currency, &t.Category, &t.Description, &t.TransactionType, &transferId}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
+	t.Amount.Currency = t.Currency
+	t.TransferID = int(transferId.Int64)
+	return t, nil
+}
 
-	desc := fmt.Sprintf("[T] from %s to %s", tf.FromAcc.AccountName, tf.ToAcc.AccountName)
-	_, err = tx.Exec(stmt1, tf.FromAcc.ID, tf.FromAcc.UserId, tf.Date, tf.FromAmount, tf.FromAcc.Currency, "transfer", desc, TransferIn)
+// withDetails loads the tags, split lines, payees and attachments of all
+// transactions, one query each.
+func (m *TransactionModel) withDetails(transactions []*Transaction) ([]*Transaction, error) {
+	if len(transactions) == 0 {
+		return transactions, nil
+	}
+
+	byId := make(map[int]*Transaction, len(transactions))
+	ids := make([]int, 0, len(transactions))
+	for _, t := range transactions {
+		byId[t.ID] = t
+		ids = append(ids, t.ID)
+	}
 
+	// NOTE: The ids go in as one JSON array, a list can be longer than the
+	// number of parameters sqlite allows.
+	idsJSON, err := json.Marshal(ids)
 	if err != nil {
-		sqliteErr, ok := err.(sqlite3.Error)
-		if ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
-				return ErrAccountDoesNotExist
-			}
-		}
-		return err
+		return nil, err
 	}
 
-	newBalance := tf.FromAcc.Balance - tf.FromAmount
-	_, err = tx.Exec(stmt2, newBalance, tf.FromAcc.ID)
+	err = m.loadTags(byId, string(idsJSON))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tx.Exec(stmt1, tf.ToAcc.ID, tf.ToAcc.UserId, tf.Date, tf.ToAmount, tf.ToAcc.Currency, "transfer", desc, TransferOut)
+	err = m.loadSplits(byId, string(idsJSON))
+	if err != nil {
+		return nil, err
+	}
 
+	err = m.loadPayees(byId, string(idsJSON))
 	if err != nil {
-		sqliteErr, ok := err.(sqlite3.Error)
-		if ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
-				return ErrAccountDoesNotExist
-			}
+		return nil, err
+	}
+
+	err = m.loadAttachments(byId, string(idsJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
+	transactions := []*Transaction{}
+
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
 		}
-		return err
+		transactions = append(transactions, t)
 	}
 
-	newBalance = tf.ToAcc.Balance + tf.ToAmount
-	_, err = tx.Exec(stmt2, newBalance, tf.ToAcc.ID)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// applyDelta adds delta to the stored balance of an account. A negative delta
+// that would take the balance below its credit limit, zero for most accounts,
+// is refused with ErrInsufficientFunds; the check is part of the UPDATE so two
+// concurrent withdrawals can not both pass it. Closed accounts refuse any
+// delta with ErrAccountClosed.
+func applyDelta(tx *sql.Tx, accountId int, delta int64) error {
+	stmt := `
+	UPDATE accounts SET balance = balance + ?
+	WHERE id = ?
+	AND closed_at IS NULL
+	AND (? >= 0 OR balance + credit_limit + ? >= 0);`
+
+	result, err := tx.Exec(stmt, delta, accountId, delta, delta)
 	if err != nil {
-		return err
+		return mapWriteError(err)
 	}
 
-	err = tx.Commit()
+	n, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		var closed bool
+		err = tx.QueryRow(`SELECT closed_at IS NOT NULL FROM accounts WHERE id = ?`, accountId).Scan(&closed)
+		if err == nil && closed {
+			return ErrAccountClosed
+		}
+		return ErrInsufficientFunds
+	}
 
 	return nil
 }
 
-func (m *TransactionModel) Insert(tf TransactionCreateForm, newBalance float64) (int, error) {
-	stmt1 := `
-	INSERT INTO transactions (account_id, user_id, date, amount, currency, category, description, transaction_type) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
-
-	stmt2 := `UPDATE accounts SET balance = ? WHERE id = ?;`
+// mapWriteError turns sqlite errors a caller can act on into model errors.
+// Busy and locked mean another request holds the write lock for longer than
+// the busy timeout.
+func mapWriteError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch {
+		case sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+			return ErrAccountDoesNotExist
+		case sqliteErr.Code == sqlite3.ErrBusy, sqliteErr.Code == sqlite3.ErrLocked:
+			return ErrConcurrentUpdate
+		}
+	}
+	return err
+}
 
+// Insert adds the transaction to the ledger and applies its amount to the
+// stored account balance in the same database transaction.
+func (m *TransactionModel) Insert(tf TransactionCreateForm) (int, error) {
 	tx, err := m.DB.Begin()
 	if err != nil {
-		return 0, err
+		return 0, mapWriteError(err)
 	}
 	defer tx.Rollback()
-	defer func() {
-		// NOTE: Something like this is possible if you want to check rollback err.
-		rbErr := tx.Rollback()
-		if rbErr != nil && err == nil {
-			err = rbErr
-		}
-	}()
 
-	_, err = tx.Exec(stmt1, tf.AccountId, tf.UserId, tf.Date, tf.Amount, Currency(tf.Currency), tf.Category, tf.Description, TransactionType(tf.TransactionType))
+	id, err := insertTransaction(tx, tf)
+	if err != nil {
+		return 0, err
+	}
 
+	err = setTags(tx, tf.UserId, id, tf.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = setSplits(tx, id, tf)
+	if err != nil {
+		return 0, err
+	}
+
+	err = setPayee(tx, tf.UserId, id, tf.PayeeID)
 	if err != nil {
-		sqliteErr, ok := err.(sqlite3.Error)
-		if ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
-				return 0, ErrAccountDoesNotExist
-			}
-		}
 		return 0, err
 	}
 
-	result, err := tx.Exec(stmt2, newBalance, tf.AccountId)
+	err = recordOccurrence(tx, tf, id)
 	if err != nil {
 		return 0, err
 	}
 
 	err = tx.Commit()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	return id, nil
+}
+
+func insertTransaction(tx *sql.Tx, tf TransactionCreateForm) (int, error) {
+	stmt := `
+	INSERT INTO transactions (account_id, user_id, date, amount, currency, category, description, transaction_type) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+
+	txType := TransactionType(tf.TransactionType)
+	result, err := tx.Exec(stmt, tf.AccountId, tf.UserId, tf.Date, tf.Amount.Minor, tf.Currency, tf.Category, tf.Description, txType)
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	err = applyDelta(tx, tf.AccountId, txType.Sign()*tf.Amount.Minor)
 	if err != nil {
 		return 0, err
 	}
@@ -183,16 +318,53 @@ func (m *TransactionModel) Insert(tf TransactionCreateForm, newBalance float64)
 	return int(id), nil
 }
 
-func (m *TransactionModel) Get(id int) (*Transaction, error) {
+// Rebalance records the difference between the account balance the user saw
+// and newBalance. The account must not have changed since it was read,
+// otherwise the difference would be wrong and ErrConcurrentUpdate is returned.
+func (m *TransactionModel) Rebalance(account Account, newBalance Money) (int, error) {
+	stmt := `UPDATE accounts SET balance = ? WHERE id = ? AND balance = ?;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, account.StoredBalance.Minor, account.ID, account.StoredBalance.Minor)
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrConcurrentUpdate
+	}
+
+	id, err := insertTransaction(tx, NewRebalance(account, account.Balance.Sub(newBalance)))
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	return id, nil
+}
+
+func (m *TransactionModel) Get(userId, id int) (*Transaction, error) {
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
 	FROM transactions
-	WHERE transaction_id = ?;`
-
-	row := m.DB.QueryRow(stmt, id)
-	t := &Transaction{}
+	WHERE deleted_at IS NULL
+	AND ` + visibleTransaction + `
+	AND id = ?;`
 
-	err := row.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
+	t, err := scanTransaction(m.DB.QueryRow(stmt, userId, userId, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -201,93 +373,178 @@ func (m *TransactionModel) Get(id int) (*Transaction, error) {
 		}
 	}
 
+	_, err = m.withDetails([]*Transaction{t})
+	if err != nil {
+		return nil, err
+	}
+
 	return t, nil
 }
 
-func (m *TransactionModel) GetAll(userId int) ([]*Transaction, error) {
+// Update rewrites date, amount, account, category, description and tags of an
+// income or expense. The old amount is taken back from the old account and
+// the new one applied to the new account in the same database transaction,
+// so a correction never needs a manual rebalance.
+func (m *TransactionModel) Update(id int, tf TransactionCreateForm) error {
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
-	FROM transactions
-	WHERE user_id = ?;`
+	UPDATE transactions
+	SET account_id = ?, date = ?, amount = ?, currency = ?, category = ?, description = ?
+	WHERE id = ?
+	AND ` + editableTransaction + `
+	AND deleted_at IS NULL;`
 
-	rows, err := m.DB.Query(stmt, userId)
+	tx, err := m.DB.Begin()
 	if err != nil {
-		return nil, err
+		return mapWriteError(err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	transactions := []*Transaction{}
+	old, err := scanTransaction(tx.QueryRow(`
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
+	FROM transactions
+	WHERE deleted_at IS NULL
+	AND `+editableTransaction+`
+	AND id = ?;`, tf.UserId, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return mapWriteError(err)
+	}
 
-	for rows.Next() {
-		t := &Transaction{}
-		err := rows.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
-		if err != nil {
-			return nil, err
+	if !old.Editable() {
+		return ErrNotEditable
+	}
+
+	_, err = tx.Exec(stmt, tf.AccountId, tf.Date, tf.Amount.Minor, tf.Currency, tf.Category, tf.Description, id, tf.UserId)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	sign := old.TransactionType.Sign()
+	if old.AccountID == tf.AccountId {
+		err = applyDelta(tx, tf.AccountId, sign*(tf.Amount.Minor-old.Amount.Minor))
+	} else {
+		err = applyDelta(tx, old.AccountID, -sign*old.Amount.Minor)
+		if err == nil {
+			err = applyDelta(tx, tf.AccountId, sign*tf.Amount.Minor)
 		}
-		transactions = append(transactions, t)
+	}
+	if err != nil {
+		return err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	err = setTags(tx, tf.UserId, id, tf.Tags)
+	if err != nil {
+		return err
 	}
 
-	return transactions, nil
+	err = setSplits(tx, id, tf)
+	if err != nil {
+		return err
+	}
+
+	err = setPayee(tx, tf.UserId, id, tf.PayeeID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
 }
-func (m *TransactionModel) GetByDateAndType(userId int, tt TransactionType, startDate, endDate time.Time) ([]*Transaction, error) {
+
+// Delete moves an income or expense to the trash and takes its amount back
+// from the account balance.
+func (m *TransactionModel) Delete(userId, id int) error {
+	return m.setDeleted(userId, id, true)
+}
+
+// Restore takes a transaction out of the trash and applies its amount to the
+// account balance again.
+func (m *TransactionModel) Restore(userId, id int) error {
+	return m.setDeleted(userId, id, false)
+}
+
+func (m *TransactionModel) setDeleted(userId, id int, deleted bool) error {
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
 	FROM transactions
-	WHERE user_id = ?
-	AND date between ? and ?
-	AND transaction_type = ?
-	ORDER BY date DESC, id DESC;`
+	WHERE ` + editableTransaction + `
+	AND id = ?
+	AND (deleted_at IS NULL) = ?;`
 
-	rows, err := m.DB.Query(stmt, userId, startDate, endDate, tt)
+	tx, err := m.DB.Begin()
 	if err != nil {
-		return nil, err
+		return mapWriteError(err)
 	}
+	defer tx.Rollback()
 
-	defer rows.Close()
+	t, err := scanTransaction(tx.QueryRow(stmt, userId, id, deleted))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return mapWriteError(err)
+	}
 
-	transactions := []*Transaction{}
+	if !t.Editable() {
+		return ErrNotEditable
+	}
 
-	for rows.Next() {
-		t := &Transaction{}
-		err := rows.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
+	delta := t.TransactionType.Sign() * t.Amount.Minor
+	if deleted {
+		_, err = tx.Exec(`UPDATE transactions SET deleted_at = ? WHERE id = ?`, time.Now().UTC(), id)
+		delta = -delta
+	} else {
+		_, err = tx.Exec(`UPDATE transactions SET deleted_at = NULL WHERE id = ?`, id)
+	}
+	if err != nil {
+		return mapWriteError(err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	err = applyDelta(tx, t.AccountID, delta)
+	if err != nil {
+		return err
 	}
 
-	return transactions, nil
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
 }
-func (m *TransactionModel) GetByDate(userId int, startDate, endDate time.Time) ([]*Transaction, error) {
+
+// GetDeleted returns the transactions moved to the trash after since, most
+// recently deleted first. A deleted transfer is listed once, by its TIN leg.
+func (m *TransactionModel) GetDeleted(userId int, since time.Time) ([]*Transaction, error) {
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id, deleted_at
 	FROM transactions
-	WHERE user_id = ?
-	AND date between ? and ?
-	ORDER BY date DESC, id DESC;`
+	WHERE deleted_at IS NOT NULL
+	AND ` + visibleTransaction + `
+	AND deleted_at >= ?
+	AND (transfer_id IS NULL OR transaction_type = ?)
+	ORDER BY deleted_at DESC, id DESC;`
 
-	rows, err := m.DB.Query(stmt, userId, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	rows, err := m.DB.Query(stmt, userId, userId, since.UTC(), TransferIn)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	transactions := []*Transaction{}
-
 	for rows.Next() {
-		t := &Transaction{}
-		err := rows.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
+		var deletedAt time.Time
+		t, err := scanTransaction(rows, &deletedAt)
 		if err != nil {
 			return nil, err
 		}
+		t.DeletedAt = deletedAt
 		transactions = append(transactions, t)
 	}
 
@@ -295,118 +552,208 @@ func (m *TransactionModel) GetByDate(userId int, startDate, endDate time.Time) (
 		return nil, err
 	}
 
-	return transactions, nil
+	return m.withDetails(transactions)
 }
 
-func (m *TransactionModel) GetByType(userId int, tt TransactionType) ([]*Transaction, error) {
+// PurgeDeleted permanently removes transactions deleted before the given time,
+// and the transfers their legs belonged to. Their amount was already taken out
+// of the balance on Delete.
+func (m *TransactionModel) PurgeDeleted(before time.Time) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// NOTE: A transfer and its legs are deleted with the same time, so the
+	// legs are gone by now.
+	_, err = tx.Exec(`DELETE FROM transfers WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+func (m *TransactionModel) GetAll(userId int) ([]*Transaction, error) {
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
 	FROM transactions
-	WHERE user_id = ?
-	AND transaction_type = ?;`
+	WHERE deleted_at IS NULL
+	AND ` + visibleTransaction + `;`
 
-	rows, err := m.DB.Query(stmt, userId, tt)
+	rows, err := m.DB.Query(stmt, userId, userId)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	transactions := []*Transaction{}
-
-	for rows.Next() {
-		t := &Transaction{}
-		err := rows.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
-	}
-
-	if err := rows.Err(); err != nil {
+	transactions, err := scanTransactions(rows)
+	if err != nil {
 		return nil, err
 	}
 
-	return transactions, nil
+	return m.withDetails(transactions)
 }
 
-func (m *TransactionModel) GetLatest(userId, limit int, tt TransactionType) ([]*Transaction, error) {
+// GetByDateAndType returns the visible transactions of a type in the date
+// range. A tag narrows them down to the ones carrying it, ignoring case, an
+// empty tag leaves them open.
+func (m *TransactionModel) GetByDateAndType(userId int, tt TransactionType, tag string, startDate, endDate time.Time) ([]*Transaction, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tagged := ""
+	args := []any{userId, userId, startDate, endDate, tt}
+	if tag != "" {
+		// NOTE: Tags belong to the member who created the transaction, on
+		// shared accounts the same name can be a tag of several members.
+		tagged = `
+	AND id IN (
+		SELECT tt.transaction_id FROM transaction_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tg.name = ?
+	)`
+		args = append(args, tag)
+	}
+
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
 	FROM transactions
-	WHERE user_id = ?
-	AND transaction_type = ?
-	ORDER BY date DESC, id DESC
-	LIMIT ?;`
+	WHERE deleted_at IS NULL
+	AND ` + visibleTransaction + `
+	AND date between ? and ?
+	AND transaction_type = ?` + tagged + `
+	ORDER BY date DESC, id DESC;`
 
-	rows, err := m.DB.Query(stmt, userId, tt, limit)
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
+
 	defer rows.Close()
 
-	transactions := []*Transaction{}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		t := &Transaction{}
-		err := rows.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
+	return m.withDetails(transactions)
+}
+func (m *TransactionModel) GetByDate(userId int, startDate, endDate time.Time) ([]*Transaction, error) {
+	stmt := `
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
+	FROM transactions
+	WHERE deleted_at IS NULL
+	AND ` + visibleTransaction + `
+	AND date between ? and ?
+	ORDER BY date DESC, id DESC;`
+
+	rows, err := m.DB.Query(stmt, userId, userId, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if err := rows.Err(); err != nil {
+	transactions, err := scanTransactions(rows)
+	if err != nil {
 		return nil, err
 	}
 
-	return transactions, nil
+	return m.withDetails(transactions)
 }
 
-func (m *TransactionModel) GetTransfers(userId int, startDate, endDate time.Time) ([]*Transaction, error) {
+func (m *TransactionModel) GetByType(userId int, tt TransactionType) ([]*Transaction, error) {
 	stmt := `
-	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
 	FROM transactions
-	WHERE user_id = ?
-	AND date between ? and ?
-	AND transaction_type in (?, ?)
-	ORDER BY date DESC, id DESC;`
+	WHERE deleted_at IS NULL
+	AND ` + visibleTransaction + `
+	AND transaction_type = ?;`
 
-	rows, err := m.DB.Query(stmt, userId, startDate, endDate, TransferIn, TransferOut)
+	rows, err := m.DB.Query(stmt, userId, userId, tt)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	transactions := []*Transaction{}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		t := &Transaction{}
-		err := rows.Scan(&t.ID, &t.AccountID, &t.UserID, &t.Date, &t.Amount, &t.Currency, &t.Category, &t.Description, &t.TransactionType)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, t)
+	return m.withDetails(transactions)
+}
+
+func (m *TransactionModel) GetLatest(userId, limit int, tt TransactionType) ([]*Transaction, error) {
+	stmt := `
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
+	FROM transactions
+	WHERE deleted_at IS NULL
+	AND ` + visibleTransaction + `
+	AND transaction_type = ?
+	ORDER BY date DESC, id DESC
+	LIMIT ?;`
+
+	rows, err := m.DB.Query(stmt, userId, userId, tt, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if err := rows.Err(); err != nil {
+	transactions, err := scanTransactions(rows)
+	if err != nil {
 		return nil, err
 	}
 
-	return transactions, nil
+	return m.withDetails(transactions)
 }
 
+// GetGroupingByDate sums expenses per category, a split transaction counts
+// towards the category of every line. CategoryID is zero for names that are
+// not a user category, like transfer fees. Only the expenses the user
+// created count, categories are not shared.
 func (m *TransactionModel) GetGroupingByDate(userId int, startDate, endDate time.Time) ([]*GroupingReport, error) {
 	stmt := `
+		WITH lines AS (
+			SELECT
+				t.id,
+				t.user_id,
+				t.transaction_type,
+				t.currency,
+				COALESCE(s.category, t.category) AS category,
+				COALESCE(s.amount, t.amount) AS amount
+			FROM transactions t
+			LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+			WHERE t.deleted_at IS NULL
+				AND t.user_id = ?
+				AND t.date BETWEEN ? AND ?
+				AND t.transaction_type = ?
+		)
 		SELECT 
-			category,
-			COUNT(id) AS transaction_count,
-			SUM(amount) AS total_amount,
-			currency
-		FROM transactions
-		WHERE user_id = ?
-			AND date BETWEEN ? AND ?
-			AND transaction_type = ?
-		GROUP BY category, currency
+			l.category,
+			COALESCE(c.id, 0),
+			COUNT(DISTINCT l.id) AS transaction_count,
+			SUM(l.amount) AS total_amount,
+			l.currency
+		FROM lines l
+		LEFT JOIN categories c ON c.user_id = l.user_id
+			AND c.transaction_type = l.transaction_type
+			AND c.name = l.category
+		GROUP BY l.category, l.currency
 		ORDER BY total_amount DESC;
 	`
 
@@ -420,10 +767,11 @@ func (m *TransactionModel) GetGroupingByDate(userId int, startDate, endDate time
 
 	for rows.Next() {
 		t := &GroupingReport{}
-		err := rows.Scan(&t.Category, &t.Count, &t.Amount, &t.Currency)
+		err := rows.Scan(&t.Category, &t.CategoryID, &t.Count, &t.Amount.Minor, &t.Currency)
 		if err != nil {
 			return nil, err
 		}
+		t.Amount.Currency = t.Currency
 		reports = append(reports, t)
 	}
 
@@ -11,6 +11,8 @@ const (
 	TransferOut
 	RebalanceIn
 	RebalanceOut
+	LoanOut
+	LoanIn
 )
 
 var transactionTypeName = map[TransactionType]string{
@@ -20,6 +22,8 @@ var transactionTypeName = map[TransactionType]string{
 	TransferOut:  "TOUT",
 	RebalanceIn:  "RIN",
 	RebalanceOut: "ROUT",
+	LoanOut:      "LOUT",
+	LoanIn:       "LIN",
 }
 
 var stringToTransactionType = map[string]TransactionType{
@@ -29,6 +33,8 @@ var stringToTransactionType = map[string]TransactionType{
 	"TOUT": TransferOut,
 	"RIN":  RebalanceIn,
 	"ROUT": RebalanceOut,
+	"LOUT": LoanOut,
+	"LIN":  LoanIn,
 }
 
 func GetTransactionTypeFromString(s string) (TransactionType, bool) {
@@ -40,6 +46,17 @@ func (t TransactionType) String() string {
 	return transactionTypeName[t]
 }
 
+// Sign is +1 for types that add to the account balance and -1 for the ones
+// that take from it. It must agree with the account_balances view.
+func (t TransactionType) Sign() int64 {
+	switch t {
+	case Expense, TransferIn, RebalanceOut, LoanOut:
+		return -1
+	default:
+		return 1
+	}
+}
+
 func (t *TransactionType) Scan(value any) error {
 	*t = TransactionType(value.(int64))
 	return nil
@@ -0,0 +1,448 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+)
+
+func testTransaction(acc *Account, tt TransactionType, minor int64) TransactionCreateForm {
+	return TransactionCreateForm{
+		UserId:          acc.UserId,
+		AccountId:       acc.ID,
+		Date:            time.Now().UTC(),
+		Amount:          NewMoney(minor, acc.Currency),
+		Currency:        acc.Currency,
+		Category:        "test",
+		TransactionType: int(tt),
+	}
+}
+
+func TestTransactionModelConcurrentInserts(t *testing.T) {
+	db := newTestDB(t)
+	// NOTE: Hundreds of connections fighting for the sqlite write lock starve
+	// some of them past the busy timeout, a handful is plenty to race.
+	db.SetMaxOpenConns(8)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	_, err := transactions.Insert(testTransaction(acc, Income, 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: 300 deposits of 1 and 100 withdrawals of 100 race each other.
+	// Deposits always succeed, a withdrawal only while funds last.
+	var wg sync.WaitGroup
+	var withdrawn atomic.Int64
+	errs := make(chan error, 400)
+
+	for i := range 400 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			tf := testTransaction(acc, Income, 1)
+			if i%4 == 0 {
+				tf = testTransaction(acc, Expense, 100)
+			}
+
+			_, err := transactions.Insert(tf)
+			switch {
+			case err == nil && tf.TransactionType == int(Expense):
+				withdrawn.Add(100)
+			case errors.Is(err, ErrInsufficientFunds) && tf.TransactionType == int(Expense):
+			case err != nil:
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	acc, err = accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, acc.Balance.Minor, 5000+300-withdrawn.Load())
+	assert.Equal(t, acc.Consistent(), true)
+	assert.Equal(t, acc.Balance.IsNegative(), false)
+}
+
+func TestTransactionModelInsufficientFunds(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	id, err := accounts.Insert(acc.UserId, "Other", Euro, Checking, NewMoney(0, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := accounts.Get(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = transactions.Insert(testTransaction(acc, Expense, 1))
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+
+	_, err = transactions.Insert(testTransaction(acc, Income, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = transactions.InsertTransfer(TransferCreateForm{
+		UserId:     acc.UserId,
+		FromAcc:    *acc,
+		ToAcc:      *other,
+		Date:       time.Now().UTC(),
+		FromAmount: NewMoney(1001, Euro),
+		ToAmount:   NewMoney(1001, Euro),
+		Rate:       1,
+	})
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+
+	// NOTE: Nothing of the failed transfer may be left behind.
+	other, err = accounts.Get(acc.UserId, other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, other.Balance.Minor, int64(0))
+	assert.Equal(t, other.Consistent(), true)
+}
+
+func TestTransactionModelRebalance(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	_, err := transactions.Insert(testTransaction(acc, Income, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := acc
+	acc, err = accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = transactions.Rebalance(*stale, NewMoney(500, Euro))
+	assert.Equal(t, errors.Is(err, ErrConcurrentUpdate), true)
+
+	_, err = transactions.Rebalance(*acc, NewMoney(500, Euro))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc, err = accounts.Get(acc.UserId, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, acc.Balance.Minor, int64(500))
+	assert.Equal(t, acc.Consistent(), true)
+}
+
+func TestTransactionModelUpdate(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+	id, err := accounts.Insert(acc.UserId, "Other", SerbianDinar, Checking, NewMoney(0, SerbianDinar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := accounts.Get(acc.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = transactions.Insert(testTransaction(acc, Income, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenseId, err := transactions.Insert(testTransaction(acc, Expense, 2000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := func(a *Account) int64 {
+		t.Helper()
+		a, err := accounts.Get(a.UserId, a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, a.Consistent(), true)
+		return a.Balance.Minor
+	}
+
+	tf := testTransaction(acc, Expense, 3000)
+	tf.Category = "fixed"
+	err = transactions.Update(expenseId, tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(acc), int64(7000))
+
+	updated, err := transactions.Get(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, updated.Category, "fixed")
+
+	// NOTE: Moving the expense to an empty account must fail as a whole.
+	err = transactions.Update(expenseId, testTransaction(other, Expense, 3000))
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+	assert.Equal(t, balance(acc), int64(7000))
+
+	_, err = transactions.Insert(testTransaction(other, Income, 50000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transactions.Update(expenseId, testTransaction(other, Expense, 4000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(acc), int64(10000))
+	assert.Equal(t, balance(other), int64(46000))
+
+	err = transactions.Update(expenseId+100, tf)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	other, err = accounts.Get(other.UserId, other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebalanceId, err := transactions.Rebalance(*other, NewMoney(1, SerbianDinar))
+	if err == nil {
+		err = transactions.Update(rebalanceId, testTransaction(other, RebalanceIn, 1))
+	}
+	assert.Equal(t, errors.Is(err, ErrNotEditable), true)
+}
+
+func TestTransactionModelDeleteRestore(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	acc := newTestAccount(t, db, Euro)
+
+	incomeId, err := transactions.Insert(testTransaction(acc, Income, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenseId, err := transactions.Insert(testTransaction(acc, Expense, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := func() int64 {
+		t.Helper()
+		a, err := accounts.Get(acc.UserId, acc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, a.Consistent(), true)
+		return a.Balance.Minor
+	}
+
+	err = transactions.Delete(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(), int64(1000))
+
+	_, err = transactions.Get(acc.UserId, expenseId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	all, err := transactions.GetAll(acc.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(all), 1)
+
+	err = transactions.Delete(acc.UserId, expenseId)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	deleted, err := transactions.GetDeleted(acc.UserId, time.Now().Add(-TrashRetention))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(deleted), 1)
+	assert.Equal(t, deleted[0].ID, expenseId)
+
+	// NOTE: Spend the income, then its deletion would leave the account negative.
+	_, err = transactions.Insert(testTransaction(acc, Expense, 700))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transactions.Delete(acc.UserId, incomeId)
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+
+	err = transactions.Restore(acc.UserId, expenseId)
+	assert.Equal(t, errors.Is(err, ErrInsufficientFunds), true)
+
+	_, err = transactions.Insert(testTransaction(acc, Income, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transactions.Restore(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(), int64(0))
+
+	err = transactions.Delete(acc.UserId, expenseId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := transactions.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, n, 1)
+	assert.Equal(t, balance(), int64(400))
+}
+
+func TestTransactionModelTransfer(t *testing.T) {
+	db := newTestDB(t)
+	accounts := &AccountModel{DB: db}
+	transactions := &TransactionModel{DB: db}
+
+	from := newTestAccount(t, db, Euro)
+	toId, err := accounts.Insert(from.UserId, "Test RSD", SerbianDinar, Checking, NewMoney(0, SerbianDinar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := accounts.Get(from.UserId, toId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = transactions.Insert(testTransaction(from, Income, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := func(acc *Account) int64 {
+		t.Helper()
+		a, err := accounts.Get(acc.UserId, acc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, a.Consistent(), true)
+		return a.Balance.Minor
+	}
+
+	date := time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)
+	form := TransferCreateForm{
+		UserId:     from.UserId,
+		FromAcc:    *from,
+		FromAmount: NewMoney(1000, Euro),
+		ToAcc:      *to,
+		ToAmount:   NewMoney(117000, SerbianDinar),
+		Fee:        NewMoney(50, Euro),
+		Date:       date,
+		Rate:       117,
+		RateDate:   date,
+	}
+
+	id, err := transactions.InsertTransfer(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(from), int64(8950))
+	assert.Equal(t, balance(to), int64(117000))
+
+	transfer, err := transactions.GetTransfer(from.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, transfer.Rate, 117.0)
+	assert.Equal(t, transfer.RateDate.Equal(date), true)
+	assert.Equal(t, transfer.From.Amount.Minor, int64(1000))
+	assert.Equal(t, transfer.To.Amount.Minor, int64(117000))
+	assert.Equal(t, transfer.Fee.Amount.Minor, int64(50))
+	assert.Equal(t, transfer.Fee.Editable(), false)
+	assert.Equal(t, transfer.CrossCurrency(), true)
+
+	err = transactions.Update(transfer.Fee.ID, testTransaction(from, Expense, 10))
+	assert.Equal(t, errors.Is(err, ErrNotEditable), true)
+
+	form.FromAmount = NewMoney(2000, Euro)
+	form.ToAmount = NewMoney(234000, SerbianDinar)
+	form.Fee = NewMoney(0, Euro)
+	err = transactions.UpdateTransfer(id, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(from), int64(8000))
+	assert.Equal(t, balance(to), int64(234000))
+
+	transfer, err = transactions.GetTransfer(from.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, transfer.Fee == nil, true)
+
+	transfers, err := transactions.GetTransfers(from.UserId, date, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(transfers), 1)
+
+	err = transactions.DeleteTransfer(from.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(from), int64(10000))
+	assert.Equal(t, balance(to), int64(0))
+
+	_, err = transactions.GetTransfer(from.UserId, id)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	deleted, err := transactions.GetDeleted(from.UserId, time.Now().Add(-TrashRetention))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(deleted), 1)
+	assert.Equal(t, deleted[0].TransferID, id)
+
+	err = transactions.RestoreTransfer(from.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, balance(from), int64(8000))
+	assert.Equal(t, balance(to), int64(234000))
+
+	// NOTE: Purging the legs takes the transfer along.
+	err = transactions.DeleteTransfer(from.UserId, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := transactions.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, n, 2)
+	var left int
+	err = db.QueryRow(`SELECT COUNT(*) FROM transfers`).Scan(&left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, left, 0)
+}
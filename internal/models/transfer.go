@@ -0,0 +1,436 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Transfer groups the legs written for one transfer between two accounts.
+// From is the TIN leg on the source account, To the TOUT leg on the
+// destination account and Fee, when there is one, an expense on the source
+// account.
+type Transfer struct {
+	ID     int
+	UserID int
+	Date   time.Time
+	// Rate is the number of To currency units that were given for one From
+	// currency unit, RateDate the day of the stored rate it came from.
+	Rate     float64
+	RateDate time.Time
+	From     *Transaction
+	To       *Transaction
+	Fee      *Transaction
+	Tags     []string
+}
+
+const transferFeeCategory = "transfer fee"
+
+func (t Transfer) DisplayDate() string {
+	return t.Date.Format("02-01-2006")
+}
+
+// CrossCurrency reports whether the two sides are held in different
+// currencies, i.e. whether Rate means anything.
+func (t Transfer) CrossCurrency() bool {
+	return t.From.Currency != t.To.Currency
+}
+
+// NOTE: Like a transaction a transfer belongs to the member who made it, but
+// changing or even reading it takes edit rights on the accounts of all its
+// legs. Only the filtered id is named, the filter fits any alias.
+const editableTransfer = ` NOT IN (
+		SELECT transfer_id FROM transactions
+		WHERE transfer_id IS NOT NULL
+		AND account_id NOT IN (SELECT account_id FROM account_access WHERE user_id = ? AND role != 'viewer'))`
+
+func (m *TransactionModel) InsertTransfer(tf TransferCreateForm) (int, error) {
+	stmt := `INSERT INTO transfers (user_id, date, rate, rate_date) VALUES (?, ?, ?, ?);`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, tf.UserId, tf.Date, tf.Rate, nullDate(tf.RateDate))
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertTransferLegs(tx, int(id), tf)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, mapWriteError(err)
+	}
+
+	return int(id), nil
+}
+
+// insertTransferLegs writes the legs of a transfer for tf.UserId, who must be
+// able to edit both accounts.
+func insertTransferLegs(tx *sql.Tx, transferId int, tf TransferCreateForm) error {
+	var n int
+	err := tx.QueryRow(`
+	SELECT COUNT(*) FROM account_access
+	WHERE user_id = ? AND role != 'viewer'
+	AND account_id IN (?, ?)`, tf.UserId, tf.FromAcc.ID, tf.ToAcc.ID).Scan(&n)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	want := 2
+	if tf.FromAcc.ID == tf.ToAcc.ID {
+		want = 1
+	}
+	if n != want {
+		return ErrNoRecord
+	}
+
+	stmt := `
+	INSERT INTO transactions (account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	desc := fmt.Sprintf("[T] from %s to %s", tf.FromAcc.AccountName, tf.ToAcc.AccountName)
+
+	type leg struct {
+		account  Account
+		amount   Money
+		category string
+		txType   TransactionType
+	}
+
+	legs := []leg{
+		{tf.FromAcc, tf.FromAmount, "transfer", TransferIn},
+		{tf.ToAcc, tf.ToAmount, "transfer", TransferOut},
+	}
+	if tf.Fee.Minor > 0 {
+		legs = append(legs, leg{tf.FromAcc, tf.Fee, transferFeeCategory, Expense})
+	}
+
+	for _, leg := range legs {
+		result, err := tx.Exec(stmt, leg.account.ID, tf.UserId, tf.Date, leg.amount.Minor, leg.account.Currency, leg.category, desc, leg.txType, transferId)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		if leg.txType != Expense {
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+
+			err = setTags(tx, tf.UserId, int(id), tf.Tags)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = applyDelta(tx, leg.account.ID, leg.txType.Sign()*leg.amount.Minor)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateTransfer replaces the legs of a transfer with the ones described by
+// tf. The old legs are taken back from their accounts first, so moving a
+// transfer to other accounts or changing its amount, rate or fee keeps every
+// balance right.
+func (m *TransactionModel) UpdateTransfer(id int, tf TransferCreateForm) error {
+	stmt := `
+	UPDATE transfers SET date = ?, rate = ?, rate_date = ?
+	WHERE id = ?
+	AND id` + editableTransfer + `
+	AND deleted_at IS NULL;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(stmt, tf.Date, tf.Rate, nullDate(tf.RateDate), id, tf.UserId)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	legs, err := getTransferLegs(tx, id, false)
+	if err != nil {
+		return err
+	}
+
+	for _, leg := range legs {
+		err = applyDelta(tx, leg.AccountID, -leg.TransactionType.Sign()*leg.Amount.Minor)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM transaction_tags WHERE transaction_id IN (SELECT id FROM transactions WHERE transfer_id = ? AND deleted_at IS NULL)`, id)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM transactions WHERE transfer_id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	err = insertTransferLegs(tx, id, tf)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// DeleteTransfer moves all legs of a transfer to the trash.
+func (m *TransactionModel) DeleteTransfer(userId, id int) error {
+	return m.setTransferDeleted(userId, id, true)
+}
+
+// RestoreTransfer takes all legs of a transfer out of the trash.
+func (m *TransactionModel) RestoreTransfer(userId, id int) error {
+	return m.setTransferDeleted(userId, id, false)
+}
+
+func (m *TransactionModel) setTransferDeleted(userId, id int, deleted bool) error {
+	stmt := `
+	UPDATE transfers SET deleted_at = ?
+	WHERE id = ?
+	AND id` + editableTransfer + `
+	AND (deleted_at IS NULL) = ?;`
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return mapWriteError(err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt any
+	if deleted {
+		deletedAt = time.Now().UTC()
+	}
+
+	result, err := tx.Exec(stmt, deletedAt, id, userId, deleted)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	legs, err := getTransferLegs(tx, id, !deleted)
+	if err != nil {
+		return err
+	}
+
+	for _, leg := range legs {
+		delta := leg.TransactionType.Sign() * leg.Amount.Minor
+		if deleted {
+			delta = -delta
+		}
+
+		err = applyDelta(tx, leg.AccountID, delta)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE transactions SET deleted_at = ? WHERE transfer_id = ?`, deletedAt, id)
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+func getTransferLegs(tx *sql.Tx, id int, deleted bool) ([]*Transaction, error) {
+	stmt := `
+	SELECT id, account_id, user_id, date, amount, currency, category, description, transaction_type, transfer_id
+	FROM transactions
+	WHERE transfer_id = ?
+	AND (deleted_at IS NOT NULL) = ?;`
+
+	rows, err := tx.Query(stmt, id, deleted)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+const transferSelect = `
+	SELECT tr.id, tr.user_id, tr.date, tr.rate, tr.rate_date,
+		t.id, t.account_id, t.user_id, t.date, t.amount, t.currency, t.category, t.description, t.transaction_type, t.transfer_id
+	FROM transfers tr
+	JOIN transactions t ON t.transfer_id = tr.id AND t.deleted_at IS NULL
+	WHERE tr.deleted_at IS NULL`
+
+func (m *TransactionModel) GetTransfer(userId, id int) (*Transfer, error) {
+	stmt := transferSelect + `
+	AND tr.id` + editableTransfer + `
+	AND tr.id = ?
+	ORDER BY t.id;`
+
+	transfers, err := m.queryTransfers(stmt, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(transfers) == 0 {
+		return nil, ErrNoRecord
+	}
+
+	err = m.loadTransferTags(transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers[0], nil
+}
+
+func (m *TransactionModel) GetTransfers(userId int, startDate, endDate time.Time) ([]*Transfer, error) {
+	stmt := transferSelect + `
+	AND tr.id` + editableTransfer + `
+	AND tr.date BETWEEN ? AND ?
+	ORDER BY tr.date DESC, tr.id DESC, t.id;`
+
+	transfers, err := m.queryTransfers(stmt, userId, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.loadTransferTags(transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// loadTransferTags reads the tags of the transfers off their destination legs.
+func (m *TransactionModel) loadTransferTags(transfers []*Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	byId := make(map[int]*Transaction, len(transfers))
+	ids := make([]int, 0, len(transfers))
+	for _, tr := range transfers {
+		byId[tr.To.ID] = tr.To
+		ids = append(ids, tr.To.ID)
+	}
+
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	err = m.loadTags(byId, string(idsJSON))
+	if err != nil {
+		return err
+	}
+
+	for _, tr := range transfers {
+		tr.Tags = tr.To.Tags
+	}
+
+	return nil
+}
+
+// queryTransfers expects one row per leg, the legs of a transfer next to each
+// other.
+func (m *TransactionModel) queryTransfers(stmt string, args ...any) ([]*Transfer, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*Transfer{}
+	var current *Transfer
+
+	for rows.Next() {
+		tr := &Transfer{}
+		var rateDate sql.NullTime
+		leg, err := scanTransaction(&prefixedRow{rows, []any{&tr.ID, &tr.UserID, &tr.Date, &tr.Rate, &rateDate}})
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.ID != tr.ID {
+			tr.RateDate = rateDate.Time
+			current = tr
+			transfers = append(transfers, current)
+		}
+
+		switch leg.TransactionType {
+		case TransferIn:
+			current.From = leg
+		case TransferOut:
+			current.To = leg
+		default:
+			current.Fee = leg
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// prefixedRow scans the leading columns of a row into prefix and hands the
+// rest to the caller's destinations.
+type prefixedRow struct {
+	rows   *sql.Rows
+	prefix []any
+}
+
+func (p *prefixedRow) Scan(dest ...any) error {
+	return p.rows.Scan(append(p.prefix, dest...)...)
+}
+
+func nullDate(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
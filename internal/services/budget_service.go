@@ -0,0 +1,123 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// BudgetProgress compares a budget with what was spent in its month.
+type BudgetProgress struct {
+	Budget   *models.Budget
+	Category string
+	// Rollover is what was left of the previous month, Available the budget
+	// amount plus Rollover.
+	Rollover  models.Money
+	Available models.Money
+	Spent     models.Money
+	Percent   int
+}
+
+func (p BudgetProgress) Remaining() models.Money {
+	return p.Available.Sub(p.Spent)
+}
+
+func (p BudgetProgress) Over() bool {
+	return p.Spent.Minor > p.Available.Minor
+}
+
+// Spending is the expense of one month per category id, sub-categories
+// included, with one Money per currency.
+type Spending map[int][]models.Money
+
+// SpendingOf collects the totals of a rolled up groupings report.
+func SpendingOf(rollups []*CategoryRollup) Spending {
+	spending := Spending{}
+	var walk func(nodes []*CategoryRollup)
+	walk = func(nodes []*CategoryRollup) {
+		for _, node := range nodes {
+			if node.ID != 0 {
+				spending[node.ID] = node.Total
+			}
+			walk(node.Children)
+		}
+	}
+	walk(rollups)
+	return spending
+}
+
+func (s Spending) of(categoryId int, currency models.Currency) models.Money {
+	for _, m := range s[categoryId] {
+		if m.Currency == currency {
+			return m
+		}
+	}
+	return models.NewMoney(0, currency)
+}
+
+// GetBudgetProgress returns the progress of the budgets of month. budgets may
+// reach back before month, a budget with rollover adds what was left of the
+// budget of the month before, as long as the months follow each other.
+// spending holds the spending of every month in budgets.
+func GetBudgetProgress(budgets []*models.Budget, categories []*models.Category, month time.Time, spending map[time.Time]Spending) []*BudgetProgress {
+	type key struct {
+		categoryId int
+		currency   models.Currency
+	}
+
+	paths := map[int]string{}
+	for _, c := range categories {
+		paths[c.ID] = c.Path
+	}
+
+	month = models.MonthOf(month)
+	previous := map[key]*BudgetProgress{}
+	progress := []*BudgetProgress{}
+
+	sorted := append([]*models.Budget{}, budgets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Month.Before(sorted[j].Month)
+	})
+
+	for _, b := range sorted {
+		if b.Month.After(month) {
+			continue
+		}
+
+		k := key{b.CategoryID, b.Amount.Currency}
+		p := &BudgetProgress{
+			Budget:   b,
+			Category: paths[b.CategoryID],
+			Rollover: models.NewMoney(0, b.Amount.Currency),
+			Spent:    spending[b.Month].of(b.CategoryID, b.Amount.Currency),
+		}
+
+		prev, ok := previous[k]
+		if b.Rollover && ok && prev.Budget.Month.Equal(b.Month.AddDate(0, -1, 0)) {
+			if left := prev.Remaining(); !left.IsNegative() {
+				p.Rollover = left
+			}
+		}
+		p.Available = b.Amount.Add(p.Rollover)
+		if p.Available.Minor > 0 {
+			p.Percent = int(p.Spent.Minor * 100 / p.Available.Minor)
+		} else if p.Spent.Minor > 0 {
+			p.Percent = 100
+		}
+		previous[k] = p
+
+		if b.Month.Equal(month) {
+			progress = append(progress, p)
+		}
+	}
+
+	sort.SliceStable(progress, func(i, j int) bool {
+		if progress[i].Category != progress[j].Category {
+			return progress[i].Category < progress[j].Category
+		}
+		return progress[i].Budget.Amount.Currency < progress[j].Budget.Amount.Currency
+	})
+
+	return progress
+}
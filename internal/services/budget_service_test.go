@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestGetBudgetProgress(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := jan.AddDate(0, 1, 0)
+	mar := jan.AddDate(0, 2, 0)
+	eur := func(minor int64) models.Money { return models.NewMoney(minor, models.Euro) }
+
+	categories := []*models.Category{
+		{ID: 1, Name: "food", Path: "food"},
+		{ID: 2, Name: "fun", Path: "fun"},
+	}
+	budgets := []*models.Budget{
+		{CategoryID: 1, Month: jan, Amount: eur(10000)},
+		{CategoryID: 1, Month: feb, Amount: eur(10000), Rollover: true},
+		{CategoryID: 1, Month: mar, Amount: eur(10000), Rollover: true},
+		// NOTE: No February budget, nothing to roll over into March.
+		{CategoryID: 2, Month: jan, Amount: eur(5000)},
+		{CategoryID: 2, Month: mar, Amount: eur(5000), Rollover: true},
+	}
+	spending := map[time.Time]Spending{
+		jan: {1: {eur(7000)}, 2: {eur(1000)}},
+		feb: {1: {eur(14000)}},
+		mar: {1: {eur(2000)}, 2: {eur(6000), models.NewMoney(100, models.SerbianDinar)}},
+	}
+
+	feb2 := GetBudgetProgress(budgets, categories, feb, spending)
+	assert.Equal(t, len(feb2), 1)
+	assert.Equal(t, feb2[0].Rollover, eur(3000))
+	assert.Equal(t, feb2[0].Available, eur(13000))
+	assert.Equal(t, feb2[0].Over(), true)
+	assert.Equal(t, feb2[0].Percent, 107)
+
+	march := GetBudgetProgress(budgets, categories, mar, spending)
+	assert.Equal(t, len(march), 2)
+	assert.Equal(t, march[0].Category, "food")
+	// NOTE: February was overspent, only unspent amounts roll over.
+	assert.Equal(t, march[0].Rollover, eur(0))
+	assert.Equal(t, march[0].Remaining(), eur(8000))
+	assert.Equal(t, march[1].Category, "fun")
+	assert.Equal(t, march[1].Rollover, eur(0))
+	assert.Equal(t, march[1].Spent, eur(6000))
+	assert.Equal(t, march[1].Over(), true)
+}
+
+func TestSpendingOf(t *testing.T) {
+	rollups := []*CategoryRollup{
+		{ID: 1, Total: []models.Money{models.NewMoney(300, models.Euro)}, Children: []*CategoryRollup{
+			{ID: 2, Total: []models.Money{models.NewMoney(100, models.Euro)}},
+		}},
+		{Name: "transfer fee", Total: []models.Money{models.NewMoney(5, models.Euro)}},
+	}
+
+	spending := SpendingOf(rollups)
+	assert.Equal(t, len(spending), 2)
+	assert.Equal(t, spending.of(1, models.Euro).Minor, int64(300))
+	assert.Equal(t, spending.of(2, models.SerbianDinar).Minor, int64(0))
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"slices"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// CategoryRollup is one category of the groupings report. Own holds the
+// amounts booked on the category itself, Total adds those of every
+// sub-category, both with one Money per currency.
+type CategoryRollup struct {
+	ID    int
+	Name  string
+	Path  string
+	Depth int
+	// Count includes the transactions of sub-categories.
+	Count    int
+	Own      []models.Money
+	Total    []models.Money
+	Children []*CategoryRollup
+}
+
+// RollUpGroupings arranges the per category groupings into the category tree
+// and adds the totals of sub-categories to their parents. Categories without
+// any transaction in the groupings are left out, names that are not a
+// category become top level rows of their own.
+func RollUpGroupings(categories []*models.Category, groupings []*models.GroupingReport) []*CategoryRollup {
+	nodes := map[int]*CategoryRollup{}
+	byName := map[string]*CategoryRollup{}
+	roots := []*CategoryRollup{}
+
+	for _, c := range categories {
+		node := &CategoryRollup{ID: c.ID, Name: c.Name, Path: c.Path, Depth: c.Depth}
+		nodes[c.ID] = node
+		if c.TransactionType == models.Expense {
+			byName[c.Name] = node
+		}
+	}
+
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if parent, ok := nodes[c.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else if c.TransactionType == models.Expense {
+			roots = append(roots, node)
+		}
+	}
+
+	for _, g := range groupings {
+		node, ok := nodes[g.CategoryID]
+		if !ok {
+			node, ok = byName[g.Category]
+		}
+		if !ok {
+			node = &CategoryRollup{Name: g.Category, Path: g.Category}
+			byName[g.Category] = node
+			roots = append(roots, node)
+		}
+		node.Count += g.Count
+		node.Own = addMoney(node.Own, g.Amount)
+	}
+
+	return pruneRollups(roots)
+}
+
+// pruneRollups computes Total bottom up and drops the categories that have
+// nothing booked on them or on any of their sub-categories.
+func pruneRollups(nodes []*CategoryRollup) []*CategoryRollup {
+	kept := []*CategoryRollup{}
+	for _, node := range nodes {
+		node.Children = pruneRollups(node.Children)
+		node.Total = slices.Clone(node.Own)
+		for _, child := range node.Children {
+			node.Count += child.Count
+			for _, m := range child.Total {
+				node.Total = addMoney(node.Total, m)
+			}
+		}
+		if len(node.Total) > 0 {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
+
+// addMoney adds m to the entry of the same currency, keeping the list sorted
+// by currency.
+func addMoney(list []models.Money, m models.Money) []models.Money {
+	i, found := slices.BinarySearchFunc(list, m.Currency, func(e models.Money, c models.Currency) int {
+		switch {
+		case e.Currency < c:
+			return -1
+		case e.Currency > c:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if found {
+		list[i] = list[i].Add(m)
+		return list
+	}
+	return slices.Insert(list, i, m)
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestRollUpGroupings(t *testing.T) {
+	categories := []*models.Category{
+		{ID: 1, Name: "home", Path: "home", TransactionType: models.Expense},
+		{ID: 2, Name: "utilities", Path: "home > utilities", Depth: 1, ParentID: 1, TransactionType: models.Expense},
+		{ID: 3, Name: "electricity", Path: "home > utilities > electricity", Depth: 2, ParentID: 2, TransactionType: models.Expense},
+		{ID: 4, Name: "furniture", Path: "home > furniture", Depth: 1, ParentID: 1, TransactionType: models.Expense},
+		{ID: 5, Name: "gym", Path: "gym", TransactionType: models.Expense},
+		{ID: 6, Name: "salary", Path: "salary", TransactionType: models.Income},
+	}
+	groupings := []*models.GroupingReport{
+		{Category: "electricity", CategoryID: 3, Count: 2, Amount: models.NewMoney(5000, models.SerbianDinar)},
+		{Category: "electricity", CategoryID: 3, Count: 1, Amount: models.NewMoney(100, models.Euro)},
+		{Category: "home", CategoryID: 1, Count: 1, Amount: models.NewMoney(1000, models.SerbianDinar)},
+		{Category: "transfer fee", Count: 1, Amount: models.NewMoney(50, models.Euro)},
+	}
+
+	roots := RollUpGroupings(categories, groupings)
+	assert.Equal(t, len(roots), 2)
+
+	home := roots[0]
+	assert.Equal(t, home.Name, "home")
+	assert.Equal(t, home.Count, 4)
+	assert.Equal(t, len(home.Own), 1)
+	assert.Equal(t, home.Own[0].Minor, int64(1000))
+	assert.Equal(t, len(home.Total), 2)
+	assert.Equal(t, home.Total[0], models.NewMoney(100, models.Euro))
+	assert.Equal(t, home.Total[1], models.NewMoney(6000, models.SerbianDinar))
+
+	// NOTE: furniture has nothing booked and is left out.
+	assert.Equal(t, len(home.Children), 1)
+	utilities := home.Children[0]
+	assert.Equal(t, len(utilities.Own), 0)
+	assert.Equal(t, utilities.Count, 3)
+	assert.Equal(t, utilities.Children[0].Path, "home > utilities > electricity")
+
+	assert.Equal(t, roots[1].Name, "transfer fee")
+	assert.Equal(t, roots[1].ID, 0)
+}
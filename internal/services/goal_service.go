@@ -0,0 +1,83 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// daysPerMonth is the length of an average month.
+const daysPerMonth = 365.25 / 12
+
+// GoalProgress is how far a goal got and what it takes to reach it.
+type GoalProgress struct {
+	Goal      *models.Goal
+	Percent   int
+	Remaining models.Money
+	// Monthly is what has to be put aside every month to make the deadline,
+	// zero for goals without one.
+	Monthly models.Money
+	// Projected is when the goal is reached at the pace of the recent months,
+	// zero when nothing came in lately.
+	Projected time.Time
+}
+
+func (p GoalProgress) Reached() bool {
+	return p.Remaining.IsZero()
+}
+
+// Late reports whether a goal with a deadline will not make it at the
+// current pace.
+func (p GoalProgress) Late() bool {
+	if p.Goal.Deadline.IsZero() || p.Reached() {
+		return false
+	}
+	return p.Projected.IsZero() || p.Projected.After(p.Goal.Deadline)
+}
+
+// GetGoalProgress works out the progress of goals on day now. The pace is
+// what came in over the last paceMonths months, see models.Goal.Recent.
+func GetGoalProgress(goals []*models.Goal, now time.Time, paceMonths int) []*GoalProgress {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	progress := make([]*GoalProgress, 0, len(goals))
+
+	for _, g := range goals {
+		currency := g.Target.Currency
+		p := &GoalProgress{
+			Goal:      g,
+			Remaining: models.NewMoney(0, currency),
+			Monthly:   models.NewMoney(0, currency),
+		}
+
+		if g.Saved.Minor > 0 {
+			p.Percent = int(min(g.Saved.Minor*100/g.Target.Minor, 100))
+		}
+		if left := g.Target.Sub(g.Saved); left.Minor > 0 {
+			p.Remaining = left
+		}
+
+		if p.Reached() {
+			p.Projected = today
+			progress = append(progress, p)
+			continue
+		}
+
+		if !g.Deadline.IsZero() {
+			// NOTE: A deadline that passed leaves everything due this month.
+			months := math.Ceil(g.Deadline.Sub(today).Hours() / 24 / daysPerMonth)
+			months = max(months, 1)
+			p.Monthly = models.NewMoney(int64(math.Ceil(float64(p.Remaining.Minor)/months)), currency)
+		}
+
+		if paceMonths > 0 && g.Recent.Minor > 0 {
+			pace := float64(g.Recent.Minor) / float64(paceMonths)
+			days := math.Ceil(float64(p.Remaining.Minor) / pace * daysPerMonth)
+			p.Projected = today.AddDate(0, 0, int(days))
+		}
+
+		progress = append(progress, p)
+	}
+
+	return progress
+}
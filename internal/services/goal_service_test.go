@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestGetGoalProgress(t *testing.T) {
+	now := time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC)
+	eur := func(minor int64) models.Money { return models.NewMoney(minor, models.Euro) }
+
+	goals := []*models.Goal{
+		{Name: "car", Target: eur(120000), Saved: eur(60000), Recent: eur(30000), Deadline: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "bike", Target: eur(120000), Saved: eur(130000), Recent: eur(10000)},
+		// NOTE: The deadline passed and nothing comes in anymore.
+		{Name: "trip", Target: eur(50000), Saved: eur(20000), Recent: eur(-500), Deadline: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	progress := GetGoalProgress(goals, now, 3)
+	assert.Equal(t, len(progress), 3)
+
+	car := progress[0]
+	assert.Equal(t, car.Percent, 50)
+	assert.Equal(t, car.Remaining, eur(60000))
+	assert.Equal(t, car.Monthly, eur(10000))
+	assert.Equal(t, car.Projected, time.Date(2026, 7, 3, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, car.Late(), true)
+
+	bike := progress[1]
+	assert.Equal(t, bike.Percent, 100)
+	assert.Equal(t, bike.Reached(), true)
+	assert.Equal(t, bike.Monthly, eur(0))
+	assert.Equal(t, bike.Late(), false)
+
+	trip := progress[2]
+	assert.Equal(t, trip.Percent, 40)
+	assert.Equal(t, trip.Monthly, eur(30000))
+	assert.Equal(t, trip.Projected.IsZero(), true)
+	assert.Equal(t, trip.Late(), true)
+}
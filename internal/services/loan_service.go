@@ -0,0 +1,74 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// LoanBalance is what is outstanding between the user and one counterparty in
+// one currency. Owed is what the counterparty owes the user, Owing what the
+// user owes them.
+type LoanBalance struct {
+	CounterpartyID int
+	Counterparty   string
+	Currency       models.Currency
+	Owed           models.Money
+	Owing          models.Money
+	// Overdue is set when one of the loans is past its due date.
+	Overdue bool
+}
+
+func (b LoanBalance) Net() models.Money {
+	return b.Owed.Sub(b.Owing)
+}
+
+// GetLoanBalances returns the outstanding balances of the loans, sorted by
+// counterparty and currency. Loans paid back in full are left out.
+func GetLoanBalances(loans []*models.PersonalLoan, now time.Time) []*LoanBalance {
+	type key struct {
+		counterpartyId int
+		currency       models.Currency
+	}
+
+	byKey := map[key]*LoanBalance{}
+	balances := []*LoanBalance{}
+
+	for _, l := range loans {
+		outstanding := l.Outstanding()
+		if outstanding.Minor <= 0 {
+			continue
+		}
+
+		k := key{l.CounterpartyID, l.Principal.Currency}
+		b, ok := byKey[k]
+		if !ok {
+			b = &LoanBalance{
+				CounterpartyID: l.CounterpartyID,
+				Counterparty:   l.Counterparty,
+				Currency:       l.Principal.Currency,
+				Owed:           models.NewMoney(0, l.Principal.Currency),
+				Owing:          models.NewMoney(0, l.Principal.Currency),
+			}
+			byKey[k] = b
+			balances = append(balances, b)
+		}
+
+		if l.Direction == models.Lent {
+			b.Owed = b.Owed.Add(outstanding)
+		} else {
+			b.Owing = b.Owing.Add(outstanding)
+		}
+		b.Overdue = b.Overdue || l.Overdue(now)
+	}
+
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Counterparty != balances[j].Counterparty {
+			return balances[i].Counterparty < balances[j].Counterparty
+		}
+		return balances[i].Currency < balances[j].Currency
+	})
+
+	return balances
+}
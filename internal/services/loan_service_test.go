@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestGetLoanBalances(t *testing.T) {
+	eur := func(minor int64) models.Money { return models.NewMoney(minor, models.Euro) }
+	rsd := func(minor int64) models.Money { return models.NewMoney(minor, models.SerbianDinar) }
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	loans := []*models.PersonalLoan{
+		{CounterpartyID: 2, Counterparty: "Marko", Direction: models.Lent, Principal: eur(10000), Repaid: eur(4000),
+			DueDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{CounterpartyID: 2, Counterparty: "Marko", Direction: models.Borrowed, Principal: eur(1500), Repaid: eur(0)},
+		{CounterpartyID: 1, Counterparty: "Ana", Direction: models.Borrowed, Principal: rsd(500000), Repaid: rsd(100000),
+			DueDate: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// NOTE: Paid back in full, late.
+		{CounterpartyID: 1, Counterparty: "Ana", Direction: models.Lent, Principal: eur(500), Repaid: eur(500),
+			DueDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	balances := GetLoanBalances(loans, now)
+	assert.Equal(t, len(balances), 2)
+
+	assert.Equal(t, balances[0].Counterparty, "Ana")
+	assert.Equal(t, balances[0].Owing, rsd(400000))
+	assert.Equal(t, balances[0].Net(), rsd(-400000))
+	assert.Equal(t, balances[0].Overdue, false)
+
+	assert.Equal(t, balances[1].Counterparty, "Marko")
+	assert.Equal(t, balances[1].Owed, eur(6000))
+	assert.Equal(t, balances[1].Owing, eur(1500))
+	assert.Equal(t, balances[1].Net(), eur(4500))
+	assert.Equal(t, balances[1].Overdue, true)
+}
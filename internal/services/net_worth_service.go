@@ -0,0 +1,51 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// NetWorth sums the accounts held in one currency. A negative balance is
+// money owed, whatever the type of the account, a credit card or loan paid
+// beyond zero is money owned.
+type NetWorth struct {
+	Currency    models.Currency
+	Assets      models.Money
+	Liabilities models.Money
+}
+
+func (n NetWorth) Net() models.Money {
+	return n.Assets.Sub(n.Liabilities)
+}
+
+// GetNetWorth returns the net worth per currency, sorted by currency.
+func GetNetWorth(accounts []*models.Account) []*NetWorth {
+	byCurrency := map[models.Currency]*NetWorth{}
+	worth := []*NetWorth{}
+
+	for _, a := range accounts {
+		n, ok := byCurrency[a.Currency]
+		if !ok {
+			n = &NetWorth{
+				Currency:    a.Currency,
+				Assets:      models.NewMoney(0, a.Currency),
+				Liabilities: models.NewMoney(0, a.Currency),
+			}
+			byCurrency[a.Currency] = n
+			worth = append(worth, n)
+		}
+
+		if a.Balance.IsNegative() {
+			n.Liabilities = n.Liabilities.Add(a.Balance.Abs())
+		} else {
+			n.Assets = n.Assets.Add(a.Balance)
+		}
+	}
+
+	sort.Slice(worth, func(i, j int) bool {
+		return worth[i].Currency < worth[j].Currency
+	})
+
+	return worth
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestGetNetWorth(t *testing.T) {
+	eur := func(minor int64) models.Money { return models.NewMoney(minor, models.Euro) }
+	rsd := func(minor int64) models.Money { return models.NewMoney(minor, models.SerbianDinar) }
+
+	accounts := []*models.Account{
+		{Currency: models.SerbianDinar, Type: models.Cash, Balance: rsd(500000)},
+		{Currency: models.Euro, Type: models.Checking, Balance: eur(-2000)},
+		{Currency: models.Euro, Type: models.Savings, Balance: eur(100000)},
+		{Currency: models.Euro, Type: models.CreditCard, Balance: eur(-30000)},
+		// NOTE: Paid more than was owed.
+		{Currency: models.Euro, Type: models.CreditCard, Balance: eur(500)},
+		{Currency: models.Euro, Type: models.Loan, Balance: eur(-50000)},
+	}
+
+	worth := GetNetWorth(accounts)
+	assert.Equal(t, len(worth), 2)
+	assert.Equal(t, worth[0].Currency, models.Euro)
+	assert.Equal(t, worth[0].Assets, eur(100500))
+	assert.Equal(t, worth[0].Liabilities, eur(82000))
+	assert.Equal(t, worth[0].Net(), eur(18500))
+	assert.Equal(t, worth[1].Net(), rsd(500000))
+}
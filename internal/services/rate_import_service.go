@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markaya/meinappf/internal/models"
+)
+
+// Supported exchange rate file formats.
+const (
+	RateFormatAuto   = "auto"
+	RateFormatECBXML = "ecb-xml"
+	RateFormatECBCSV = "ecb-csv"
+	RateFormatNBSCSV = "nbs-csv"
+)
+
+var RateFormats = []string{RateFormatAuto, RateFormatECBXML, RateFormatECBCSV, RateFormatNBSCSV}
+
+const (
+	rateSourceECB = "ecb"
+	rateSourceNBS = "nbs"
+)
+
+// ParseRates reads a whole rate file in the given format. With
+// RateFormatAuto the format is guessed from the first bytes of the file.
+func ParseRates(format string, r io.Reader) ([]models.ExchangeRate, error) {
+	br := bufio.NewReader(r)
+
+	if format == RateFormatAuto {
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		format = DetectRateFormat(head)
+	}
+
+	switch format {
+	case RateFormatECBXML:
+		return ParseECBXML(br)
+	case RateFormatECBCSV:
+		return ParseECBCSV(br)
+	case RateFormatNBSCSV:
+		return ParseNBSCSV(br)
+	default:
+		return nil, fmt.Errorf("rates: unsupported format %q", format)
+	}
+}
+
+func DetectRateFormat(head []byte) string {
+	head = bytes.TrimPrefix(bytes.TrimSpace(head), []byte("\xef\xbb\xbf"))
+	switch {
+	case bytes.HasPrefix(head, []byte("<")):
+		return RateFormatECBXML
+	case bytes.HasPrefix(bytes.ToLower(head), []byte("date,")):
+		return RateFormatECBCSV
+	default:
+		return RateFormatNBSCSV
+	}
+}
+
+// ecbEnvelope mirrors eurofxref-hist.xml / eurofxref-daily.xml:
+//
+//	<Cube><Cube time="2024-03-28"><Cube currency="USD" rate="1.0811"/>...
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECBXML parses the ECB euro foreign exchange reference rates XML. All
+// rates are quoted as units of currency per 1 EUR.
+func ParseECBXML(r io.Reader) ([]models.ExchangeRate, error) {
+	envelope := ecbEnvelope{}
+	err := xml.NewDecoder(r).Decode(&envelope)
+	if err != nil {
+		return nil, fmt.Errorf("rates: ecb xml: %w", err)
+	}
+
+	rates := []models.ExchangeRate{}
+	for _, day := range envelope.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("rates: ecb xml: %w", err)
+		}
+
+		for _, cube := range day.Rates {
+			rate, err := strconv.ParseFloat(cube.Rate, 64)
+			if err != nil {
+				return nil, fmt.Errorf("rates: ecb xml %s %s: %w", day.Time, cube.Currency, err)
+			}
+			rates = append(rates, models.ExchangeRate{
+				From:   models.Euro,
+				To:     models.Currency(cube.Currency),
+				Date:   date,
+				Rate:   rate,
+				Source: rateSourceECB,
+			})
+		}
+	}
+
+	return rates, nil
+}
+
+// ParseECBCSV parses eurofxref-hist.csv, a header row of currency codes
+// ("Date,USD,JPY,...") followed by one row per day. Currencies that did not
+// exist yet or were retired are "N/A" or empty.
+func ParseECBCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("rates: ecb csv: %w", err)
+	}
+	if len(header) == 0 || !strings.EqualFold(strings.TrimPrefix(header[0], "\ufeff"), "date") {
+		return nil, fmt.Errorf("rates: ecb csv: first column must be Date")
+	}
+
+	rates := []models.ExchangeRate{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rates: ecb csv: %w", err)
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("rates: ecb csv: %w", err)
+		}
+
+		for i := 1; i < len(record) && i < len(header); i++ {
+			code := strings.TrimSpace(header[i])
+			value := strings.TrimSpace(record[i])
+			if code == "" || value == "" || value == "N/A" {
+				continue
+			}
+
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("rates: ecb csv %s %s: %w", record[0], code, err)
+			}
+			rates = append(rates, models.ExchangeRate{
+				From:   models.Euro,
+				To:     models.Currency(code),
+				Date:   date,
+				Rate:   rate,
+				Source: rateSourceECB,
+			})
+		}
+	}
+
+	return rates, nil
+}
+
+// ParseNBSCSV parses the National Bank of Serbia exchange rate list export.
+// Columns are found by their (Serbian or English) header so that the column
+// order and the ';' or ',' separator do not matter:
+//
+//	Datum;Oznaka valute;Važi za;Srednji kurs
+//	28.03.2024.;EUR;1;117,1797
+//
+// Middle rates are RSD for "Važi za" units of the foreign currency.
+func ParseNBSCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	br := bufio.NewReader(r)
+	firstLine, err := br.Peek(4096)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	line, _, _ := bytes.Cut(firstLine, []byte("\n"))
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("rates: nbs csv: %w", err)
+	}
+
+	dateCol, codeCol, unitCol, middleCol := -1, -1, -1, -1
+	for i, name := range header {
+		switch normalizeHeader(name) {
+		case "datum", "date", "datum primene", "vazi od":
+			dateCol = i
+		case "oznaka valute", "oznaka", "valuta", "currency", "currency code":
+			codeCol = i
+		case "vazi za", "paritet", "jedinica", "unit":
+			unitCol = i
+		case "srednji kurs", "srednji", "middle rate", "middle":
+			middleCol = i
+		}
+	}
+	if dateCol < 0 || codeCol < 0 || middleCol < 0 {
+		return nil, fmt.Errorf("rates: nbs csv: header must have date, currency code and middle rate columns")
+	}
+
+	rates := []models.ExchangeRate{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rates: nbs csv: %w", err)
+		}
+		if len(record) <= max(dateCol, codeCol, unitCol, middleCol) {
+			continue
+		}
+
+		date, err := parseNBSDate(record[dateCol])
+		if err != nil {
+			return nil, fmt.Errorf("rates: nbs csv: %w", err)
+		}
+
+		middle, err := parseDecimal(record[middleCol])
+		if err != nil {
+			return nil, fmt.Errorf("rates: nbs csv %s %s: %w", record[dateCol], record[codeCol], err)
+		}
+
+		unit := 1.0
+		if unitCol >= 0 && strings.TrimSpace(record[unitCol]) != "" {
+			unit, err = parseDecimal(record[unitCol])
+			if err != nil || unit <= 0 {
+				return nil, fmt.Errorf("rates: nbs csv %s %s: invalid unit %q", record[dateCol], record[codeCol], record[unitCol])
+			}
+		}
+
+		rates = append(rates, models.ExchangeRate{
+			From:   models.Currency(strings.ToUpper(strings.TrimSpace(record[codeCol]))),
+			To:     models.SerbianDinar,
+			Date:   date,
+			Rate:   middle / unit,
+			Source: rateSourceNBS,
+		})
+	}
+
+	return rates, nil
+}
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(s, "\ufeff")))
+	return strings.NewReplacer("ž", "z", "š", "s", "č", "c", "ć", "c", "đ", "dj").Replace(s)
+}
+
+func parseNBSDate(s string) (time.Time, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), ".")
+	for _, layout := range []string{"02.01.2006", "2.1.2006", "2006-01-02", "02/01/2006"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
+
+// parseDecimal accepts both "117,1797" and "117.1797", thousands separators
+// are not used by either bank.
+func parseDecimal(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+}
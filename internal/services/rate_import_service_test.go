@@ -0,0 +1,126 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+const ecbXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2024-03-28">
+			<Cube currency="USD" rate="1.0811"/>
+			<Cube currency="JPY" rate="163.45"/>
+		</Cube>
+		<Cube time="2024-03-27">
+			<Cube currency="USD" rate="1.0816"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+const ecbCSV = `Date,USD,JPY,CYP,
+2024-03-28,1.0811,163.45,N/A,
+2024-03-27,1.0816,164.07,N/A,
+`
+
+const nbsCSV = "Datum;Oznaka valute;Važi za;Srednji kurs\n" +
+	"28.03.2024.;EUR;1;117,1797\n" +
+	"28.03.2024.;JPY;100;71,6701\n"
+
+func TestParseRates(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []models.ExchangeRate
+	}{
+		{
+			name:   "ECB XML",
+			format: RateFormatECBXML,
+			input:  ecbXML,
+			want: []models.ExchangeRate{
+				{From: "EUR", To: "USD", Date: date(2024, 3, 28), Rate: 1.0811, Source: "ecb"},
+				{From: "EUR", To: "JPY", Date: date(2024, 3, 28), Rate: 163.45, Source: "ecb"},
+				{From: "EUR", To: "USD", Date: date(2024, 3, 27), Rate: 1.0816, Source: "ecb"},
+			},
+		},
+		{
+			name:   "ECB CSV",
+			format: RateFormatECBCSV,
+			input:  ecbCSV,
+			want: []models.ExchangeRate{
+				{From: "EUR", To: "USD", Date: date(2024, 3, 28), Rate: 1.0811, Source: "ecb"},
+				{From: "EUR", To: "JPY", Date: date(2024, 3, 28), Rate: 163.45, Source: "ecb"},
+				{From: "EUR", To: "USD", Date: date(2024, 3, 27), Rate: 1.0816, Source: "ecb"},
+				{From: "EUR", To: "JPY", Date: date(2024, 3, 27), Rate: 164.07, Source: "ecb"},
+			},
+		},
+		{
+			name:   "NBS CSV",
+			format: RateFormatNBSCSV,
+			input:  nbsCSV,
+			want: []models.ExchangeRate{
+				{From: "EUR", To: "RSD", Date: date(2024, 3, 28), Rate: 117.1797, Source: "nbs"},
+				{From: "JPY", To: "RSD", Date: date(2024, 3, 28), Rate: 0.716701, Source: "nbs"},
+			},
+		},
+		{
+			name:   "Detect ECB XML",
+			format: RateFormatAuto,
+			input:  ecbXML,
+			want: []models.ExchangeRate{
+				{From: "EUR", To: "USD", Date: date(2024, 3, 28), Rate: 1.0811, Source: "ecb"},
+				{From: "EUR", To: "JPY", Date: date(2024, 3, 28), Rate: 163.45, Source: "ecb"},
+				{From: "EUR", To: "USD", Date: date(2024, 3, 27), Rate: 1.0816, Source: "ecb"},
+			},
+		},
+		{
+			name:   "Detect NBS CSV",
+			format: RateFormatAuto,
+			input:  nbsCSV,
+			want: []models.ExchangeRate{
+				{From: "EUR", To: "RSD", Date: date(2024, 3, 28), Rate: 117.1797, Source: "nbs"},
+				{From: "JPY", To: "RSD", Date: date(2024, 3, 28), Rate: 0.716701, Source: "nbs"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ParseRates(tt.format, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, len(rates), len(tt.want))
+			for i := range min(len(rates), len(tt.want)) {
+				assert.Equal(t, rates[i].From, tt.want[i].From)
+				assert.Equal(t, rates[i].To, tt.want[i].To)
+				assert.Equal(t, rates[i].Date.Equal(tt.want[i].Date), true)
+				assert.Equal(t, rates[i].Rate, tt.want[i].Rate)
+				assert.Equal(t, rates[i].Source, tt.want[i].Source)
+			}
+		})
+	}
+}
+
+func TestParseRatesInvalid(t *testing.T) {
+	_, err := ParseRates(RateFormatNBSCSV, strings.NewReader("foo;bar\n1;2\n"))
+	if err == nil {
+		t.Error("expected error for NBS file without known columns")
+	}
+
+	_, err = ParseRates(RateFormatECBCSV, strings.NewReader("Date,USD\n28.03.2024,1.08\n"))
+	if err == nil {
+		t.Error("expected error for ECB file with a malformed date")
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
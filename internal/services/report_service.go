@@ -2,20 +2,34 @@ package services
 
 import (
 	"math"
+	"sort"
 	"time"
 
 	"github.com/markaya/meinappf/internal/models"
 )
 
+// CurrencyReport holds income and expense totals for a single currency,
+// amounts in different currencies are never added up.
+type CurrencyReport struct {
+	Currency models.Currency
+	Income   models.Money
+	Expense  models.Money
+	Progress int
+}
+
+// CategoryTotal is the expense of one category in one currency, split
+// transactions count towards the category of each line.
+type CategoryTotal struct {
+	Category string
+	Amount   models.Money
+}
+
 type TotalReport struct {
-	StartDate           time.Time
-	EndDate             time.Time
-	IncomeEur           float64
-	ExpenseEur          float64
-	ProgressEur         int
-	IncomeRsd           float64
-	ExpenseRsd          float64
-	ProgressRsd         int
+	StartDate  time.Time
+	EndDate    time.Time
+	Currencies []*CurrencyReport
+	// ExpenseCategories is sorted by currency, largest amount first.
+	ExpenseCategories   []*CategoryTotal
 	IncomeTransactions  []models.Transaction
 	ExpenseTransactions []models.Transaction
 }
@@ -23,55 +37,76 @@ type TotalReport struct {
 func GetTotalReport(transactions []*models.Transaction, startDate, endDate time.Time) TotalReport {
 	incomeTransactions := make([]models.Transaction, 100)
 	expenseTransactions := make([]models.Transaction, 100)
-	incomeEur := 0.0
-	incomeRsd := 0.0
-	expenseEur := 0.0
-	expenseRsd := 0.0
+	byCurrency := map[models.Currency]*CurrencyReport{}
+	type categoryKey struct {
+		category string
+		currency models.Currency
+	}
+	byCategory := map[categoryKey]*CategoryTotal{}
+	expenseCategories := []*CategoryTotal{}
+
+	reportFor := func(c models.Currency) *CurrencyReport {
+		r, ok := byCurrency[c]
+		if !ok {
+			r = &CurrencyReport{
+				Currency: c,
+				Income:   models.NewMoney(0, c),
+				Expense:  models.NewMoney(0, c),
+			}
+			byCurrency[c] = r
+		}
+		return r
+	}
 
 	for _, v := range transactions {
 		// NOTE: Ignore transfer
 		switch v.TransactionType {
 		case models.Income:
-			switch v.Currency {
-			case models.Euro:
-				incomeEur += v.Amount
-			case models.SerbianDinar:
-				incomeRsd += v.Amount
-			default:
-				panic("unsupported currency")
-			}
+			r := reportFor(v.Currency)
+			r.Income = r.Income.Add(v.Amount)
 			incomeTransactions = append(incomeTransactions, *v)
 		case models.Expense:
-			switch v.Currency {
-			case models.Euro:
-				expenseEur += v.Amount
-			case models.SerbianDinar:
-				expenseRsd += v.Amount
-			default:
-				panic("unsupported currency")
+			r := reportFor(v.Currency)
+			for _, line := range v.Lines() {
+				r.Expense = r.Expense.Add(line.Amount)
+
+				key := categoryKey{line.Category, v.Currency}
+				c, ok := byCategory[key]
+				if !ok {
+					c = &CategoryTotal{Category: line.Category, Amount: models.NewMoney(0, v.Currency)}
+					byCategory[key] = c
+					expenseCategories = append(expenseCategories, c)
+				}
+				c.Amount = c.Amount.Add(line.Amount)
 			}
 			expenseTransactions = append(expenseTransactions, *v)
 		}
 	}
 
-	progressEur := 0
-	if incomeEur > 0 {
-		progressEur = int(math.Round((expenseEur / incomeEur) * 100))
-	}
-	progressRsd := 0
-	if incomeRsd > 0 {
-		progressRsd = int(math.Round((expenseRsd / incomeRsd) * 100))
+	currencies := make([]*CurrencyReport, 0, len(byCurrency))
+	for _, r := range byCurrency {
+		if r.Income.Minor > 0 {
+			r.Progress = int(math.Round((float64(r.Expense.Minor) / float64(r.Income.Minor)) * 100))
+		}
+		currencies = append(currencies, r)
 	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Currency < currencies[j].Currency
+	})
+
+	sort.SliceStable(expenseCategories, func(i, j int) bool {
+		a, b := expenseCategories[i].Amount, expenseCategories[j].Amount
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Minor > b.Minor
+	})
 
 	return TotalReport{
 		StartDate:           startDate,
 		EndDate:             endDate,
-		IncomeEur:           incomeEur,
-		ExpenseEur:          expenseEur,
-		ProgressEur:         progressEur,
-		IncomeRsd:           incomeRsd,
-		ExpenseRsd:          expenseRsd,
-		ProgressRsd:         progressRsd,
+		Currencies:          currencies,
+		ExpenseCategories:   expenseCategories,
 		IncomeTransactions:  incomeTransactions,
 		ExpenseTransactions: expenseTransactions,
 	}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/markaya/meinappf/internal/assert"
+	"github.com/markaya/meinappf/internal/models"
+)
+
+func TestGetTotalReportSplits(t *testing.T) {
+	transactions := []*models.Transaction{
+		{TransactionType: models.Income, Currency: models.Euro, Category: "salary", Amount: models.NewMoney(10000, models.Euro)},
+		{TransactionType: models.Expense, Currency: models.Euro, Category: "food", Amount: models.NewMoney(500, models.Euro)},
+		{
+			TransactionType: models.Expense,
+			Currency:        models.Euro,
+			Category:        "food",
+			Amount:          models.NewMoney(3000, models.Euro),
+			Splits: []models.Split{
+				{Category: "food", Amount: models.NewMoney(1000, models.Euro)},
+				{Category: "household", Amount: models.NewMoney(2000, models.Euro)},
+			},
+		},
+		{TransactionType: models.Expense, Currency: models.SerbianDinar, Category: "food", Amount: models.NewMoney(100, models.SerbianDinar)},
+		{TransactionType: models.TransferIn, Currency: models.Euro, Category: "transfer", Amount: models.NewMoney(700, models.Euro)},
+	}
+
+	report := GetTotalReport(transactions, time.Now(), time.Now())
+
+	assert.Equal(t, len(report.Currencies), 2)
+	assert.Equal(t, report.Currencies[0].Expense, models.NewMoney(3500, models.Euro))
+
+	assert.Equal(t, len(report.ExpenseCategories), 3)
+	assert.Equal(t, report.ExpenseCategories[0].Category, "household")
+	assert.Equal(t, report.ExpenseCategories[0].Amount, models.NewMoney(2000, models.Euro))
+	assert.Equal(t, report.ExpenseCategories[1].Category, "food")
+	assert.Equal(t, report.ExpenseCategories[1].Amount, models.NewMoney(1500, models.Euro))
+	assert.Equal(t, report.ExpenseCategories[2].Amount, models.NewMoney(100, models.SerbianDinar))
+}
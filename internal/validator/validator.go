@@ -44,7 +44,7 @@ func (v *Validator) CheckField(ok bool, key, message string) {
 	}
 }
 
-func GreaterThanZero(value float64) bool {
+func GreaterThanZero[T int | int64 | float64](value T) bool {
 	return value >= 0
 }
 
@@ -60,6 +60,10 @@ func PermittedInt(value int, permittedValues ...int) bool {
 	return slices.Contains(permittedValues, value)
 }
 
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	return slices.Contains(permittedValues, value)
+}
+
 func MinChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) >= n
 }